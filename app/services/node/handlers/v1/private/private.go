@@ -3,15 +3,24 @@ package private
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 
+	v1 "github.com/PhyoYazar/blockchain/business/web/v1"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/peer"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/state"
 	"github.com/PhyoYazar/blockchain/foundation/web"
 	"go.uber.org/zap"
 )
 
 // Handlers manages the set of bar ledger endpoints.
 type Handlers struct {
-	Log *zap.SugaredLogger
+	Log   *zap.SugaredLogger
+	State *state.State
 }
 
 // Sample just provides a starting point for the class.
@@ -24,3 +33,149 @@ func (h Handlers) Sample(ctx context.Context, w http.ResponseWriter, r *http.Req
 
 	return web.Respond(ctx, w, resp, http.StatusOK)
 }
+
+// Status returns the current status of the node.
+func (h Handlers) Status(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	header := h.State.RetrieveLatestHeader()
+
+	status := peer.PeerStatus{
+		LatestBlockHash:   header.Hash(),
+		LatestBlockNumber: header.Number,
+		KnownPeers:        h.State.RetrieveKnownPeers(),
+	}
+
+	return web.Respond(ctx, w, status, http.StatusOK)
+}
+
+// BlockHeaders returns the block headers for the specified range of block
+// numbers. The to value can be "latest".
+func (h Handlers) BlockHeaders(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	from, to, err := blockRange(r)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	headers, err := h.State.RetrieveHeaders(from, to)
+	if err != nil {
+		return err
+	}
+
+	if headers == nil {
+		headers = []database.BlockHeader{}
+	}
+
+	return web.Respond(ctx, w, headers, http.StatusOK)
+}
+
+// BlockList returns the blocks for the specified range of block numbers. The
+// to value can be "latest". A pruned node can't return blocks whose
+// transactions have been deleted.
+func (h Handlers) BlockList(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	from, to, err := blockRange(r)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	blocks, err := h.State.RetrieveBlocks(from, to)
+	if err != nil {
+		if errors.Is(err, database.ErrPruned) {
+			return v1.NewRequestError(err, http.StatusGone)
+		}
+		return err
+	}
+
+	if blocks == nil {
+		blocks = []database.BlockData{}
+	}
+
+	return web.Respond(ctx, w, blocks, http.StatusOK)
+}
+
+// StateSnapshot returns a chunk of the snapshot of the account state the
+// specified block header commits to, so a new node can start from it. A
+// download starts at the latest block, and once its snapshot is no longer
+// kept the request is gone. The number can be "latest".
+func (h Handlers) StateSnapshot(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	number := h.State.RetrieveLatestHeader().Number
+	if numberStr := web.Param(r, "number"); numberStr != "latest" {
+		var err error
+		if number, err = strconv.ParseUint(numberStr, 10, 64); err != nil {
+			return v1.NewRequestError(fmt.Errorf("invalid block number: %w", err), http.StatusBadRequest)
+		}
+	}
+
+	chunk, err := strconv.Atoi(web.Param(r, "chunk"))
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("invalid chunk: %w", err), http.StatusBadRequest)
+	}
+
+	sc, err := h.State.RetrieveSnapshotChunk(number, chunk)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrNotFound):
+			return v1.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, database.ErrSnapshotMoved):
+			return v1.NewRequestError(err, http.StatusGone)
+		}
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	return web.Respond(ctx, w, sc, http.StatusOK)
+}
+
+// TxProof returns a mined transaction along with the merkle proof of its
+// inclusion so a node running in light mode can verify it.
+func (h Handlers) TxProof(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	tp, err := h.State.RetrieveTxProof(web.Param(r, "hash"))
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrNotFound):
+			return v1.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, database.ErrPruned):
+			return v1.NewRequestError(err, http.StatusGone)
+		}
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	return web.Respond(ctx, w, tp, http.StatusOK)
+}
+
+// AccountProof returns an account along with the proof of its state against
+// the latest block header so a node running in light mode can verify it.
+func (h Handlers) AccountProof(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	accountID, err := database.ToAccountID(web.Param(r, "account"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	ap, err := h.State.RetrieveAccountProof(accountID)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	return web.Respond(ctx, w, ap, http.StatusOK)
+}
+
+// =============================================================================
+
+// blockRange parses the from and to block numbers from the request. The to
+// value can be "latest".
+func blockRange(r *http.Request) (uint64, uint64, error) {
+	from, err := strconv.ParseUint(web.Param(r, "from"), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid from block number: %w", err)
+	}
+
+	to := uint64(math.MaxUint64)
+	if toStr := web.Param(r, "to"); toStr != "latest" {
+		if to, err = strconv.ParseUint(toStr, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid to block number: %w", err)
+		}
+	}
+
+	if from > to {
+		return 0, 0, errors.New("from block number is greater than to block number")
+	}
+
+	return from, to, nil
+}
@@ -1,14 +1,24 @@
 package public
 
 import (
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/amount"
 	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
 type act struct {
-	Account database.AccountID `json:"account"`
-	Name    string             `json:"name"`
-	Balance uint64             `json:"balance"`
-	Nonce   uint64             `json:"nonce"`
+	Account     database.AccountID       `json:"account"`
+	Name        string                   `json:"name"`
+	Balance     uint64                   `json:"balance"`
+	Locked      uint64                   `json:"locked"`
+	Locks       []database.TimeLock      `json:"locks,omitempty"`
+	Nonce       uint64                   `json:"nonce"`
+	CodeHash    string                   `json:"code_hash,omitempty"`
+	StorageRoot string                   `json:"storage_root,omitempty"`
+	Multisig    *database.MultisigConfig `json:"multisig,omitempty"`
+	Token       *token                   `json:"token,omitempty"`
+	Tokens      []tokenBalance           `json:"tokens,omitempty"`
+	HTLC        *database.HTLC           `json:"htlc,omitempty"`
 }
 
 type actInfo struct {
@@ -18,19 +28,111 @@ type actInfo struct {
 }
 
 type tx struct {
-	FromAccount database.AccountID `json:"from"`
-	FromName    string             `json:"from_name"`
-	To          database.AccountID `json:"to"`
-	ToName      string             `json:"to_name"`
-	ChainID     uint16             `json:"chain_id"`
+	Hash              string             `json:"hash"`
+	FromAccount       database.AccountID `json:"from"`
+	FromName          string             `json:"from_name"`
+	To                database.AccountID `json:"to"`
+	ToName            string             `json:"to_name"`
+	FeePayer          database.AccountID `json:"fee_payer,omitempty"`
+	PaidBy            database.AccountID `json:"paid_by"`
+	PaidByName        string             `json:"paid_by_name"`
+	ChainID           uint16             `json:"chain_id"`
+	Nonce             uint64             `json:"nonce"`
+	Value             uint64             `json:"value"`
+	Tip               uint64             `json:"tip"`
+	Data              []byte             `json:"data"`
+	GasLimit          uint64             `json:"gas_limit,omitempty"`
+	MaxFee            uint64             `json:"max_fee,omitempty"`
+	Type              database.TxType    `json:"type,omitempty"`
+	ValidAfter        uint64             `json:"valid_after,omitempty"`
+	ValidUntil        uint64             `json:"valid_until,omitempty"`
+	TimeStamp         uint64             `json:"timestamp"`
+	GasPrice          uint64             `json:"gas_price"`
+	GasUnits          uint64             `json:"gas_units"`
+	EffectiveGasPrice uint64             `json:"effective_gas_price,omitempty"`
+	Sig               string             `json:"sig"`
+	Proof             []string           `json:"proof,omitempty"`
+	ProofOrder        []int64            `json:"proof_order,omitempty"`
+}
+
+type txProof struct {
+	Tx        tx                   `json:"tx"`
+	BlockHash string               `json:"block_hash"`
+	Block     database.BlockHeader `json:"block"`
+	Root      string               `json:"root"`
+	Hardened  bool                 `json:"hardened"`
+}
+
+type txProofBatch struct {
+	Hashes []string `json:"hashes"`
+}
+
+type txMultiProof struct {
+	Trans     []tx                 `json:"trans"`
+	BlockHash string               `json:"block_hash"`
+	Block     database.BlockHeader `json:"block"`
+	Root      string               `json:"root"`
+	LeafCount int                  `json:"leaf_count"`
+	Indices   []int                `json:"indices"`
+	Proof     []string             `json:"proof"`
+	Hardened  bool                 `json:"hardened"`
+}
+
+type actProof struct {
+	Account     database.AccountID `json:"account"`
+	Name        string             `json:"name"`
+	Balance     uint64             `json:"balance"`
 	Nonce       uint64             `json:"nonce"`
-	Value       uint64             `json:"value"`
-	Tip         uint64             `json:"tip"`
-	Data        []byte             `json:"data"`
-	TimeStamp   uint64             `json:"timestamp"`
-	GasPrice    uint64             `json:"gas_price"`
-	GasUnits    uint64             `json:"gas_units"`
-	Sig         string             `json:"sig"`
-	Proof       []string           `json:"proof"`
-	ProofOrder  []int64            `json:"proof_order"`
+	BlockNumber uint64             `json:"block_number"`
+	StateRoot   string             `json:"state_root"`
+	Key         string             `json:"key"`
+	Value       string             `json:"value"`
+	Bitmap      string             `json:"bitmap"`
+	Siblings    []string           `json:"siblings"`
+}
+
+type actBlock struct {
+	Number    uint64 `json:"number"`
+	Hash      string `json:"hash"`
+	TimeStamp uint64 `json:"timestamp"`
+	Trans     []tx   `json:"trans"`
+}
+
+type blockBloom struct {
+	Number uint64         `json:"number"`
+	Hash   string         `json:"hash"`
+	Bloom  database.Bloom `json:"bloom,omitempty"`
+}
+
+type feeEstimate struct {
+	BaseFee  uint64 `json:"base_fee"`
+	MaxFee   uint64 `json:"max_fee"`
+	Tip      uint64 `json:"tip"`
+	GasPrice uint64 `json:"gas_price"`
+	GasUnits uint64 `json:"gas_units"`
+}
+
+type token struct {
+	Token           database.AccountID `json:"token"`
+	Issuer          database.AccountID `json:"issuer"`
+	IssuerName      string             `json:"issuer_name"`
+	Symbol          string             `json:"symbol"`
+	Decimals        uint8              `json:"decimals"`
+	Supply          amount.Amount      `json:"supply"`
+	SupplyFormatted string             `json:"supply_formatted"`
+}
+
+type tokenBalance struct {
+	Token            database.AccountID `json:"token"`
+	Symbol           string             `json:"symbol,omitempty"`
+	Balance          amount.Amount      `json:"balance"`
+	BalanceFormatted string             `json:"balance_formatted,omitempty"`
+}
+
+type contract struct {
+	Account     database.AccountID     `json:"account"`
+	CodeHash    string                 `json:"code_hash"`
+	StorageRoot string                 `json:"storage_root"`
+	Code        hexutil.Bytes          `json:"code"`
+	Storage     []database.StorageSlot `json:"storage"`
 }
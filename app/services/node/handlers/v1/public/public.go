@@ -3,14 +3,19 @@ package public
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"sort"
+	"strconv"
 
 	v1 "github.com/PhyoYazar/blockchain/business/web/v1"
 	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
 	"github.com/PhyoYazar/blockchain/foundation/blockchain/state"
 	"github.com/PhyoYazar/blockchain/foundation/nameservice"
 	"github.com/PhyoYazar/blockchain/foundation/web"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"go.uber.org/zap"
 )
 
@@ -98,21 +103,12 @@ func (h Handlers) Mempool(ctx context.Context, w http.ResponseWriter, r *http.Re
 			continue
 		}
 
-		trans = append(trans, tx{
-			FromAccount: tran.FromID,
-			FromName:    h.NS.Lookup(tran.FromID),
-			To:          tran.ToID,
-			ToName:      h.NS.Lookup(tran.ToID),
-			ChainID:     tran.ChainID,
-			Nonce:       tran.Nonce,
-			Value:       tran.Value,
-			Tip:         tran.Tip,
-			Data:        tran.Data,
-			TimeStamp:   tran.TimeStamp,
-			GasPrice:    tran.GasPrice,
-			GasUnits:    tran.GasUnits,
-			Sig:         tran.SignatureString(),
-		})
+		hash, err := tran.Hash()
+		if err != nil {
+			return err
+		}
+
+		trans = append(trans, h.toTx(hash, tran))
 	}
 
 	return web.Respond(ctx, w, trans, http.StatusOK)
@@ -125,6 +121,9 @@ func (h Handlers) Accounts(ctx context.Context, w http.ResponseWriter, r *http.R
 	var accounts map[database.AccountID]database.Account
 	switch accountStr {
 	case "":
+		if h.State.IsLight() {
+			return v1.NewRequestError(fmt.Errorf("listing all accounts is %w", state.ErrLightMode), http.StatusBadRequest)
+		}
 		accounts = h.State.RetrieveAccounts()
 
 	default:
@@ -142,19 +141,426 @@ func (h Handlers) Accounts(ctx context.Context, w http.ResponseWriter, r *http.R
 	resp := make([]act, 0, len(accounts))
 	for account, info := range accounts {
 		act := act{
-			Account: account,
-			Name:    h.NS.Lookup(account),
-			Balance: info.Balance,
-			Nonce:   info.Nonce,
+			Account:     account,
+			Name:        h.NS.Lookup(account),
+			Balance:     info.Balance,
+			Nonce:       info.Nonce,
+			CodeHash:    info.CodeHash,
+			StorageRoot: info.StorageRoot,
+		}
+		if mc, isMultisig := info.MultisigConfig(); isMultisig {
+			act.Multisig = &mc
+		}
+		if t, isToken := info.TokenInfo(); isToken {
+			tkn := h.token(account, t)
+			act.Token = &tkn
+		}
+		if htlc, isLock := info.HTLCInfo(); isLock {
+			act.HTLC = &htlc
+		}
+		tbs, err := h.tokenBalances(info)
+		if err != nil {
+			return err
+		}
+		act.Tokens = tbs
+		if act.Locks, err = info.TimeLocks(); err != nil {
+			return err
+		}
+		if act.Locked, err = info.LockedBalance(); err != nil {
+			return err
 		}
 		resp = append(resp, act)
 	}
 
 	ai := actInfo{
-		LastestBlock: h.State.RetrieveLatestBlock().Hash(),
+		LastestBlock: h.State.RetrieveLatestHeader().Hash(),
 		Uncommitted:  len(h.State.RetrieveMempool()),
 		Accounts:     resp,
 	}
 
 	return web.Respond(ctx, w, ai, http.StatusOK)
 }
+
+// AccountProof returns the account along with a proof it's part of the state
+// trie so a light wallet can verify the balance.
+func (h Handlers) AccountProof(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	accountID, err := database.ToAccountID(web.Param(r, "account"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	ap, err := h.State.QueryAccountProof(accountID)
+	if err != nil {
+		return err
+	}
+
+	siblings := make([]string, len(ap.Proof.Siblings))
+	for i, sibling := range ap.Proof.Siblings {
+		siblings[i] = hexutil.Encode(sibling)
+	}
+
+	var value string
+	if ap.Value != nil {
+		value = hexutil.Encode(ap.Value)
+	}
+
+	resp := actProof{
+		Account:     accountID,
+		Name:        h.NS.Lookup(accountID),
+		Balance:     ap.Account.Balance,
+		Nonce:       ap.Account.Nonce,
+		BlockNumber: ap.BlockNumber,
+		StateRoot:   ap.StateRoot,
+		Key:         hexutil.Encode(ap.Key[:]),
+		Value:       value,
+		Bitmap:      hexutil.Encode(ap.Proof.Bitmap[:]),
+		Siblings:    siblings,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// TxProof returns a mined transaction along with the merkle proof that it's
+// included in the block, so it can be verified against the block header.
+func (h Handlers) TxProof(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	tp, err := h.State.QueryTxProof(web.Param(r, "hash"))
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrNotFound):
+			return v1.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, database.ErrPruned):
+			return v1.NewRequestError(err, http.StatusGone)
+		}
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	proof := make([]string, len(tp.Proof))
+	for i, hash := range tp.Proof {
+		proof[i] = hexutil.Encode(hash)
+	}
+
+	txp := h.toTx(tp.LeafHash, tp.Tx)
+	txp.Proof = proof
+	txp.ProofOrder = tp.ProofOrder
+
+	resp := txProof{
+		Tx:        txp,
+		BlockHash: tp.BlockHash,
+		Block:     tp.Header,
+		Root:      hexutil.Encode(tp.Root),
+		Hardened:  tp.Hardened,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// TxProofBatch returns a set of mined transactions from the same block along
+// with a single merkle proof that they are all included in the block.
+func (h Handlers) TxProofBatch(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req txProofBatch
+	if err := web.Decode(r, &req); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	tmp, err := h.State.QueryTxMultiProof(req.Hashes)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrNotFound):
+			return v1.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, database.ErrPruned):
+			return v1.NewRequestError(err, http.StatusGone)
+		}
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	trans := make([]tx, len(tmp.Trans))
+	for i, tran := range tmp.Trans {
+		trans[i] = h.toTx(tmp.LeafHashes[i], tran)
+	}
+
+	proof := make([]string, len(tmp.Proof.Hashes))
+	for i, hash := range tmp.Proof.Hashes {
+		proof[i] = hexutil.Encode(hash)
+	}
+
+	resp := txMultiProof{
+		Trans:     trans,
+		BlockHash: tmp.BlockHash,
+		Block:     tmp.Header,
+		Root:      hexutil.Encode(tmp.Root),
+		LeafCount: tmp.Proof.LeafCount,
+		Indices:   tmp.Proof.Indices,
+		Proof:     proof,
+		Hardened:  tmp.Hardened,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// AccountBlocks returns the transactions from or to the account in the
+// blocks of the specified range. The block header blooms are used to skip
+// the blocks that don't have the account.
+func (h Handlers) AccountBlocks(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	accountID, err := database.ToAccountID(web.Param(r, "account"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	from, to, err := blockRange(r)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	blocks, err := h.State.QueryAccountBlocks(accountID, from, to)
+	if err != nil {
+		if errors.Is(err, database.ErrPruned) {
+			return v1.NewRequestError(err, http.StatusGone)
+		}
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	resp := make([]actBlock, 0, len(blocks))
+	for _, block := range blocks {
+		trans := []tx{}
+		for _, tran := range block.Trans {
+			if !tran.HasAccount(accountID) {
+				continue
+			}
+
+			hash, err := tran.Hash()
+			if err != nil {
+				return err
+			}
+
+			trans = append(trans, h.toTx(hash, tran))
+		}
+
+		resp = append(resp, actBlock{
+			Number:    block.Header.Number,
+			Hash:      block.Hash,
+			TimeStamp: block.Header.TimeStamp,
+			Trans:     trans,
+		})
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// BlockBlooms returns the account blooms of the block headers in the
+// specified range, so a light wallet can find the blocks it needs without
+// downloading them all.
+func (h Handlers) BlockBlooms(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	from, to, err := blockRange(r)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	headers, err := h.State.RetrieveHeaders(from, to)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	resp := make([]blockBloom, len(headers))
+	for i, header := range headers {
+		resp[i] = blockBloom{
+			Number: header.Number,
+			Hash:   header.Hash(),
+			Bloom:  header.Bloom,
+		}
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// FeeEstimate returns the suggested fees for a transaction to be mined into
+// one of the next blocks.
+func (h Handlers) FeeEstimate(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	fe, err := h.State.QueryFeeEstimate()
+	if err != nil {
+		return err
+	}
+
+	resp := feeEstimate{
+		BaseFee:  fe.BaseFee,
+		MaxFee:   fe.MaxFee,
+		Tip:      fe.Tip,
+		GasPrice: fe.GasPrice,
+		GasUnits: fe.GasUnits,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Contract returns the code and storage of a contract account.
+func (h Handlers) Contract(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	accountID, err := database.ToAccountID(web.Param(r, "account"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	c, err := h.State.QueryContract(accountID)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrNotFound):
+			return v1.NewRequestError(fmt.Errorf("contract %s: %w", accountID, err), http.StatusNotFound)
+		case errors.Is(err, state.ErrLightMode):
+			return v1.NewRequestError(err, http.StatusBadRequest)
+		}
+		return err
+	}
+
+	resp := contract{
+		Account:     accountID,
+		CodeHash:    c.CodeHash(),
+		StorageRoot: c.StorageRoot(),
+		Code:        c.Code,
+		Storage:     c.Slots(),
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Tokens returns the tokens issued on the chain, or the specified token.
+func (h Handlers) Tokens(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	tokenStr := web.Param(r, "token")
+
+	var tokens map[database.AccountID]database.Token
+	switch tokenStr {
+	case "":
+		var err error
+		if tokens, err = h.State.QueryTokens(); err != nil {
+			if errors.Is(err, state.ErrLightMode) {
+				return v1.NewRequestError(fmt.Errorf("listing all tokens is %w", err), http.StatusBadRequest)
+			}
+			return err
+		}
+
+	default:
+		tokenID, err := database.ToAccountID(tokenStr)
+		if err != nil {
+			return v1.NewRequestError(err, http.StatusBadRequest)
+		}
+		t, err := h.State.QueryToken(tokenID)
+		if err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				return v1.NewRequestError(fmt.Errorf("token %s: %w", tokenID, err), http.StatusNotFound)
+			}
+			return err
+		}
+		tokens = map[database.AccountID]database.Token{tokenID: t}
+	}
+
+	resp := make([]token, 0, len(tokens))
+	for tokenID, t := range tokens {
+		resp = append(resp, h.token(tokenID, t))
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].Token < resp[j].Token
+	})
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Supply returns the issued, circulating and locked supply.
+func (h Handlers) Supply(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	supply, err := h.State.QuerySupply()
+	if err != nil {
+		if errors.Is(err, state.ErrLightMode) {
+			return v1.NewRequestError(fmt.Errorf("reporting the supply is %w", err), http.StatusBadRequest)
+		}
+		return err
+	}
+
+	return web.Respond(ctx, w, supply, http.StatusOK)
+}
+
+// =============================================================================
+
+// toTx converts the transaction with the specified hash into its response
+// model.
+func (h Handlers) toTx(hash []byte, tran database.BlockTx) tx {
+	return tx{
+		Hash:              hexutil.Encode(hash),
+		FromAccount:       tran.FromID,
+		FromName:          h.NS.Lookup(tran.FromID),
+		To:                tran.ToID,
+		ToName:            h.NS.Lookup(tran.ToID),
+		FeePayer:          tran.FeePayerID,
+		PaidBy:            tran.PayerID(),
+		PaidByName:        h.NS.Lookup(tran.PayerID()),
+		ChainID:           tran.ChainID,
+		Nonce:             tran.Nonce,
+		Value:             tran.Value,
+		Tip:               tran.Tip,
+		Data:              tran.Data,
+		GasLimit:          tran.GasLimit,
+		MaxFee:            tran.MaxFee,
+		Type:              tran.Type,
+		ValidAfter:        tran.ValidAfter,
+		ValidUntil:        tran.ValidUntil,
+		TimeStamp:         tran.TimeStamp,
+		GasPrice:          tran.GasPrice,
+		GasUnits:          tran.GasUnits,
+		EffectiveGasPrice: tran.EffectiveGasPrice,
+		Sig:               tran.SignatureString(),
+	}
+}
+
+// token converts the token into its response model.
+func (h Handlers) token(tokenID database.AccountID, t database.Token) token {
+	return token{
+		Token:           tokenID,
+		Issuer:          t.Issuer,
+		IssuerName:      h.NS.Lookup(t.Issuer),
+		Symbol:          t.Symbol,
+		Decimals:        t.Decimals,
+		Supply:          t.Supply,
+		SupplyFormatted: t.Denomination().Format(t.Supply),
+	}
+}
+
+// tokenBalances converts the token balances of the account into their
+// response model. Balances of tokens that can't be looked up are returned
+// without the symbol and formatted balance.
+func (h Handlers) tokenBalances(account database.Account) ([]tokenBalance, error) {
+	tbs, err := account.TokenBalances()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]tokenBalance, len(tbs))
+	for i, tb := range tbs {
+		resp[i] = tokenBalance{
+			Token:   tb.Token,
+			Balance: tb.Balance,
+		}
+		if t, err := h.State.QueryToken(tb.Token); err == nil {
+			resp[i].Symbol = t.Symbol
+			resp[i].BalanceFormatted = t.Denomination().Format(tb.Balance)
+		}
+	}
+
+	return resp, nil
+}
+
+// blockRange parses the from and to block numbers from the request. The to
+// value can be "latest".
+func blockRange(r *http.Request) (uint64, uint64, error) {
+	from, err := strconv.ParseUint(web.Param(r, "from"), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid from block number: %w", err)
+	}
+
+	to := uint64(math.MaxUint64)
+	if toStr := web.Param(r, "to"); toStr != "latest" {
+		if to, err = strconv.ParseUint(toStr, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid to block number: %w", err)
+		}
+	}
+
+	if from > to {
+		return 0, 0, errors.New("from block number is greater than to block number")
+	}
+
+	return from, to, nil
+}
@@ -35,15 +35,32 @@ func PublicRoutes(app *web.App, cfg Config) {
 	app.Handle(http.MethodGet, version, "/start/mining", pbl.StartMining)
 	app.Handle(http.MethodPost, version, "/tx/submit", pbl.SubmitWalletTransaction)
 	app.Handle(http.MethodGet, version, "/tx/uncommitted/list", pbl.Mempool)
+	app.Handle(http.MethodGet, version, "/tx/proof/:hash", pbl.TxProof)
+	app.Handle(http.MethodPost, version, "/tx/proof/batch", pbl.TxProofBatch)
 	app.Handle(http.MethodGet, version, "/accounts/list", pbl.Accounts)
 	app.Handle(http.MethodGet, version, "/accounts/list/:account", pbl.Accounts)
+	app.Handle(http.MethodGet, version, "/accounts/proof/:account", pbl.AccountProof)
+	app.Handle(http.MethodGet, version, "/accounts/blocks/:account/:from/:to", pbl.AccountBlocks)
+	app.Handle(http.MethodGet, version, "/block/blooms/:from/:to", pbl.BlockBlooms)
+	app.Handle(http.MethodGet, version, "/fees/estimate", pbl.FeeEstimate)
+	app.Handle(http.MethodGet, version, "/contracts/:account", pbl.Contract)
+	app.Handle(http.MethodGet, version, "/tokens", pbl.Tokens)
+	app.Handle(http.MethodGet, version, "/tokens/:token", pbl.Tokens)
+	app.Handle(http.MethodGet, version, "/supply", pbl.Supply)
 }
 
 // PrivateRoutes binds all the version 1 private routes.
 func PrivateRoutes(app *web.App, cfg Config) {
 	prv := private.Handlers{
-		Log: cfg.Log,
+		Log:   cfg.Log,
+		State: cfg.State,
 	}
 
 	app.Handle(http.MethodGet, version, "/node/sample", prv.Sample)
+	app.Handle(http.MethodGet, version, "/node/status", prv.Status)
+	app.Handle(http.MethodGet, version, "/node/block/list/:from/:to", prv.BlockList)
+	app.Handle(http.MethodGet, version, "/node/block/headers/:from/:to", prv.BlockHeaders)
+	app.Handle(http.MethodGet, version, "/node/state/snapshot/:number/:chunk", prv.StateSnapshot)
+	app.Handle(http.MethodGet, version, "/node/tx/proof/:hash", prv.TxProof)
+	app.Handle(http.MethodGet, version, "/node/accounts/proof/:account", prv.AccountProof)
 }
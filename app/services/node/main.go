@@ -64,8 +64,12 @@ func run(log *zap.SugaredLogger) error {
 		}
 		State struct {
 			Beneficiary string   `conf:"default:miner1"`
+			GenesisPath string   `conf:"default:zblock/genesis.json"`
 			DBPath      string   `conf:"default:zblock/miner1/"`
 			OriginPeers []string `conf:"default:0.0.0.0:9080"`
+			Light       bool     `conf:"default:false"`
+			PruneDepth  uint64   `conf:"default:0"`
+			SnapSync    bool     `conf:"default:false"`
 		}
 		NameService struct {
 			Folder string `conf:"default:zblock/accounts/"`
@@ -157,8 +161,12 @@ func run(log *zap.SugaredLogger) error {
 	state, err := state.New(state.Config{
 		BeneficiaryID: database.PublicKeyToAccountID(privateKey.PublicKey),
 		Host:          cfg.Web.PrivateHost,
+		GenesisPath:   cfg.State.GenesisPath,
 		DBPath:        cfg.State.DBPath,
 		KnownPeers:    peerSet,
+		Light:         cfg.State.Light,
+		PruneDepth:    cfg.State.PruneDepth,
+		SnapSync:      cfg.State.SnapSync,
 		EvHandler:     ev,
 	})
 
@@ -242,6 +250,7 @@ func run(log *zap.SugaredLogger) error {
 	privateMux := handlers.PrivateMux(handlers.MuxConfig{
 		Shutdown: shutdown,
 		Log:      log,
+		State:    state,
 	})
 
 	// Construct a server to service the requests against the mux.
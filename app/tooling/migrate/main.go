@@ -0,0 +1,53 @@
+// This program upgrades the blocks stored on disk for a node to the
+// current block format version.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/storage/disk"
+	"github.com/ardanlabs/conf/v3"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Println("ERROR:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	cfg := struct {
+		State struct {
+			DBPath string `conf:"default:zblock/miner1/"`
+		}
+	}{}
+
+	// The same prefix as the node is used so the environment used to run a
+	// node can be used to migrate its database.
+	const prefix = "NODE"
+	help, err := conf.Parse(prefix, &cfg)
+	if err != nil {
+		if errors.Is(err, conf.ErrHelpWanted) {
+			fmt.Println(help)
+			return nil
+		}
+		return fmt.Errorf("parsing config: %w", err)
+	}
+
+	ev := func(v string, args ...any) {
+		fmt.Printf(v+"\n", args...)
+	}
+
+	backup, err := disk.Migrate(cfg.State.DBPath, ev)
+	if err != nil {
+		if backup != "" {
+			return fmt.Errorf("%w: restore the blocks from %s", err, backup)
+		}
+		return err
+	}
+
+	return nil
+}
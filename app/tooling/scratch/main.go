@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
 	"github.com/PhyoYazar/blockchain/foundation/blockchain/merkle"
 	"github.com/PhyoYazar/blockchain/foundation/blockchain/signature"
 	"github.com/PhyoYazar/blockchain/foundation/blockchain/storage/disk"
@@ -40,7 +41,12 @@ func readBlock() error {
 
 	fmt.Println(blockData)
 
-	block, err := database.ToBlock(blockData)
+	gen, err := genesis.Load("zblock/genesis.json")
+	if err != nil {
+		return err
+	}
+
+	block, err := database.ToBlock(gen, blockData)
 	if err != nil {
 		return nil
 	}
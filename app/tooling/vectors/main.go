@@ -0,0 +1,291 @@
+// This program generates the published test vectors for the canonical
+// binary encoding used to hash and sign transactions and block headers.
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/signature"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// testKey is the private key of the pavel account found in zblock/accounts.
+// It's used so anyone can reproduce the signatures in the vectors.
+const testKey = "fae85851bdf5c9f49923722ce38f3c1defcfd3619ef5453230a58ad805499959"
+
+var output string
+
+func init() {
+	flag.StringVar(&output, "output", "foundation/blockchain/database/testdata/canonical_vectors.json", "file to write the vectors to")
+}
+
+type txVector struct {
+	Description   string           `json:"description"`
+	Tx            database.Tx      `json:"tx"`
+	Encoded       string           `json:"encoded"`
+	Signature     string           `json:"signature"`
+	BlockTx       database.BlockTx `json:"block_tx"`
+	BlockTxEncode string           `json:"block_tx_encoded"`
+	LeafHash      string           `json:"leaf_hash"`
+}
+
+type headerVector struct {
+	Description string               `json:"description"`
+	Header      database.BlockHeader `json:"header"`
+	Encoded     string               `json:"encoded"`
+	Hash        string               `json:"hash"`
+}
+
+// rejectedVector is a signed transaction that encodes and recovers its
+// signer like a valid one but has to be rejected.
+type rejectedVector struct {
+	Description string            `json:"description"`
+	SignedTx    database.SignedTx `json:"signed_tx"`
+	Encoded     string            `json:"encoded"`
+	Reason      string            `json:"reason"`
+}
+
+type vectors struct {
+	PrivateKey string           `json:"private_key"`
+	Hash       string           `json:"hash"`
+	Stamp      string           `json:"stamp"`
+	Txs        []txVector       `json:"txs"`
+	Rejected   []rejectedVector `json:"rejected"`
+	Headers    []headerVector   `json:"headers"`
+}
+
+func main() {
+	flag.Parse()
+
+	if err := run(); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func run() error {
+	privateKey, err := crypto.HexToECDSA(testKey)
+	if err != nil {
+		return err
+	}
+	fromID := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	vs := vectors{
+		PrivateKey: testKey,
+		Hash:       "sha256(encoded)",
+		Stamp:      `signature over keccak256("\x19Ardan Signed Message:\n" + len(encoded) + encoded), v = recovery id + 29`,
+	}
+
+	txs := []struct {
+		description string
+		tx          database.Tx
+	}{
+		{
+			description: "plain transfer without data",
+			tx: database.Tx{
+				ChainID:  1,
+				Nonce:    1,
+				FromID:   fromID,
+				ToID:     "0xbEE6ACE826eC3DE1B6349888B9151B92522F7F76",
+				Value:    100,
+				Tip:      0,
+				Encoding: database.EncodingCanonical,
+			},
+		},
+		{
+			description: "transfer with a tip and data",
+			tx: database.Tx{
+				ChainID:  1,
+				Nonce:    2,
+				FromID:   fromID,
+				ToID:     "0x6Fe6CF3c8fF57c58d24BfC869668F48BCbDb3BD9",
+				Value:    1_000_000,
+				Tip:      25,
+				Data:     []byte("payroll"),
+				Encoding: database.EncodingCanonical,
+			},
+		},
+		{
+			description: "transfer with a gas limit",
+			tx: database.Tx{
+				ChainID:  1,
+				Nonce:    3,
+				FromID:   fromID,
+				ToID:     "0xbEE6ACE826eC3DE1B6349888B9151B92522F7F76",
+				Value:    250,
+				Tip:      5,
+				Data:     []byte("invoice 42"),
+				Encoding: database.EncodingCanonical,
+				GasLimit: 21_000,
+			},
+		},
+		{
+			description: "transfer with a gas limit and max fee",
+			tx: database.Tx{
+				ChainID:  1,
+				Nonce:    4,
+				FromID:   fromID,
+				ToID:     "0xbEE6ACE826eC3DE1B6349888B9151B92522F7F76",
+				Value:    250,
+				Tip:      5,
+				Encoding: database.EncodingCanonical,
+				GasLimit: 21_000,
+				MaxFee:   30,
+			},
+		},
+		{
+			description: "contract call",
+			tx: database.Tx{
+				ChainID:  1,
+				Nonce:    5,
+				FromID:   fromID,
+				ToID:     "0x2111e4Fae69AbE53B2Df482a11a8A1D7C82062AC",
+				Value:    10,
+				Tip:      5,
+				Data:     []byte{0x01},
+				Encoding: database.EncodingCanonical,
+				GasLimit: 50_000,
+				MaxFee:   30,
+				Type:     database.TxTypeCall,
+			},
+		},
+		{
+			description: "sponsored transfer with a validity window",
+			tx: database.Tx{
+				ChainID:    1,
+				Nonce:      6,
+				FromID:     fromID,
+				ToID:       "0xbEE6ACE826eC3DE1B6349888B9151B92522F7F76",
+				Value:      75,
+				Tip:        5,
+				Encoding:   database.EncodingCanonical,
+				GasLimit:   21_000,
+				MaxFee:     30,
+				FeePayerID: "0xF01813E4B85e178A83e29B8E7bF26BD830a25f32",
+				ValidAfter: 100,
+				ValidUntil: 200,
+			},
+		},
+	}
+
+	for _, v := range txs {
+		encoded, err := v.tx.Encode()
+		if err != nil {
+			return err
+		}
+
+		signedTx, err := v.tx.Sign(privateKey)
+		if err != nil {
+			return err
+		}
+
+		blockTx := database.BlockTx{
+			SignedTx:  signedTx,
+			TimeStamp: 1639699200000,
+			GasPrice:  15,
+			GasUnits:  1,
+		}
+
+		blockTxEncoded, err := blockTx.Encode()
+		if err != nil {
+			return err
+		}
+
+		leafHash, err := blockTx.Hash()
+		if err != nil {
+			return err
+		}
+
+		vs.Txs = append(vs.Txs, txVector{
+			Description:   v.description,
+			Tx:            v.tx,
+			Encoded:       "0x" + hex.EncodeToString(encoded),
+			Signature:     signedTx.SignatureString(),
+			BlockTx:       blockTx,
+			BlockTxEncode: "0x" + hex.EncodeToString(blockTxEncoded),
+			LeafHash:      "0x" + hex.EncodeToString(leafHash),
+		})
+	}
+
+	// The canonical encoding only covers the bytes of an account, so the
+	// recipient can be recased without breaking the signature.
+	recased, err := txs[0].tx.Sign(privateKey)
+	if err != nil {
+		return err
+	}
+	recased.ToID = database.AccountID(strings.ToLower(string(recased.ToID)))
+
+	recasedEncoded, err := recased.Tx.Encode()
+	if err != nil {
+		return err
+	}
+
+	vs.Rejected = append(vs.Rejected, rejectedVector{
+		Description: "signed transfer with a recased recipient",
+		SignedTx:    recased,
+		Encoded:     "0x" + hex.EncodeToString(recasedEncoded),
+		Reason:      "the to account is not in checksum form",
+	})
+
+	header := database.BlockHeader{
+		Number:        3,
+		PrevBlockHash: "0x0000005494ee76627a369643f64558cb270dd8e6e8a69cf6a470360221b69f55",
+		TimeStamp:     1639699200000,
+		BeneficiaryID: "0xFef311483Cc040e1A89fb9bb469eeB8A70935EF8",
+		Difficulty:    6,
+		MiningReward:  700,
+		StateRoot:     "0x5d702670c2d85eead5eab783364785f3075d48e4078182338303585a8e7248cb",
+		TransRoot:     vs.Txs[0].LeafHash,
+		Nonce:         8910563041127464146,
+		Encoding:      database.EncodingCanonical,
+	}
+
+	bloomHeader := header
+	bloomHeader.Bloom = database.NewBloom(vs.Txs[0].Tx.FromID, vs.Txs[0].Tx.ToID)
+
+	feeHeader := bloomHeader
+	feeHeader.GasUsed = 21_000
+	feeHeader.BaseFee = 12
+
+	headers := []struct {
+		description string
+		header      database.BlockHeader
+	}{
+		{description: "block header", header: header},
+		{description: "block header with an account bloom", header: bloomHeader},
+		{description: "block header with gas used and a base fee", header: feeHeader},
+	}
+
+	for _, v := range headers {
+		encoded, err := v.header.Encode()
+		if err != nil {
+			return err
+		}
+
+		vs.Headers = append(vs.Headers, headerVector{
+			Description: v.description,
+			Header:      v.header,
+			Encoded:     "0x" + hex.EncodeToString(encoded),
+			Hash:        signature.HashBytes(encoded),
+		})
+	}
+
+	data, err := json.MarshalIndent(vs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(output, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+
+	fmt.Println("vectors written to", output)
+
+	return nil
+}
@@ -14,12 +14,13 @@ import (
 type balance struct {
 	Account string `json:"account"`
 	Balance uint   `json:"balance"`
+	Locked  uint   `json:"locked"`
 }
 
 type balances struct {
 	LastestBlock string    `json:"lastest_block"`
 	Uncommitted  int       `json:"uncommitted"`
-	Balances     []balance `json:"balances"`
+	Balances     []balance `json:"accounts"`
 }
 
 var balanceCmd = &cobra.Command{
@@ -55,6 +56,7 @@ func balanceRun(cmd *cobra.Command, args []string) {
 	}
 
 	if len(balances.Balances) > 0 {
-		fmt.Println(balances.Balances[0].Balance)
+		fmt.Println("Spendable:", balances.Balances[0].Balance)
+		fmt.Println("Locked:", balances.Balances[0].Locked)
 	}
 }
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/amount"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+)
+
+var batchFile string
+
+var batchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "Pay a list of accounts from a CSV file in one transaction",
+	Run:   batchRun,
+}
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+	batchCmd.Flags().StringVarP(&url, "url", "u", "http://localhost:8080", "Url of the node.")
+	batchCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "id for the transaction.")
+	batchCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
+	batchCmd.Flags().Uint64Var(&maxFee, "max-fee", 0, "Maximum base fee per unit of gas to pay, required once the base fee is active.")
+	batchCmd.Flags().Uint64Var(&gasLimit, "gas-limit", 0, "Maximum units of gas to pay for, required once gas metering is active.")
+	batchCmd.Flags().BoolVar(&canonical, "canonical", false, "Sign using the canonical binary encoding.")
+	batchCmd.Flags().Uint64Var(&validAfter, "valid-after", 0, "Block number, not a timestamp, the transaction can only be mined after.")
+	batchCmd.Flags().Uint64Var(&validUntil, "valid-until", 0, "Number of the last block, not a timestamp, the transaction can be mined in.")
+	batchCmd.Flags().StringVar(&batchFile, "file", "batch.csv", "CSV file with a to account and a value on every line.")
+}
+
+func batchRun(cmd *cobra.Command, args []string) {
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	entries, err := readBatchFile(batchFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var total uint64
+	for _, entry := range entries {
+		if total, err = amount.Add(total, entry.Value); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	batch, err := database.EncodeBatch(entries)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	const chainID = 1
+	tx := database.Tx{
+		ChainID: chainID,
+		Nonce:   nonce,
+		FromID:  database.PublicKeyToAccountID(privateKey.PublicKey),
+		Value:   total,
+		Tip:     tip,
+		Data:    batch,
+		Type:    database.TxTypeBatchTransfer,
+	}
+
+	submitTx(privateKey, tx)
+
+	fmt.Println("Entries:", len(entries))
+	fmt.Println("Total:", total)
+}
+
+// readBatchFile reads the entries of a batch transfer from a CSV file. Every
+// line holds the account to pay and the value to pay it. A first line that
+// doesn't start with an account is taken as a header and skipped.
+func readBatchFile(path string) ([]database.BatchEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 2
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+
+	var entries []database.BatchEntry
+	for first := true; ; first = false {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+
+		to, err := database.ToAccountID(strings.TrimSpace(record[0]))
+		if err != nil {
+			if first {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		value, err := strconv.ParseUint(strings.TrimSpace(record[1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: value: %w", line, err)
+		}
+
+		entries = append(entries, database.BatchEntry{To: to, Value: value})
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s has no entries", path)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/vm"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+)
+
+var codePath string
+
+var deployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "Deploy a contract",
+	Run:   deployRun,
+}
+
+var callCmd = &cobra.Command{
+	Use:   "call",
+	Short: "Call a contract",
+	Run:   callRun,
+}
+
+func init() {
+	rootCmd.AddCommand(deployCmd)
+	deployCmd.Flags().StringVarP(&url, "url", "u", "http://localhost:8080", "Url of the node.")
+	deployCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "id for the transaction.")
+	deployCmd.Flags().StringVar(&codePath, "code", "", "Path to the contract code, assembly when the file ends in .asm, hex otherwise.")
+	deployCmd.Flags().Uint64VarP(&value, "value", "v", 0, "Value to send to the contract.")
+	deployCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
+	deployCmd.Flags().Uint64Var(&maxFee, "max-fee", 0, "Maximum base fee per unit of gas to pay, required once the base fee is active.")
+	deployCmd.Flags().Uint64Var(&gasLimit, "gas-limit", 0, "Most units of gas to pay for, the gas the contract doesn't use is refunded.")
+	deployCmd.Flags().BoolVar(&canonical, "canonical", false, "Sign using the canonical binary encoding.")
+
+	rootCmd.AddCommand(callCmd)
+	callCmd.Flags().StringVarP(&url, "url", "u", "http://localhost:8080", "Url of the node.")
+	callCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "id for the transaction.")
+	callCmd.Flags().StringVarP(&to, "to", "t", "", "Contract to call.")
+	callCmd.Flags().BytesHexVarP(&data, "data", "d", nil, "Call data to send.")
+	callCmd.Flags().Uint64VarP(&value, "value", "v", 0, "Value to send to the contract.")
+	callCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
+	callCmd.Flags().Uint64Var(&maxFee, "max-fee", 0, "Maximum base fee per unit of gas to pay, required once the base fee is active.")
+	callCmd.Flags().Uint64Var(&gasLimit, "gas-limit", 0, "Most units of gas to pay for, the gas the contract doesn't use is refunded.")
+	callCmd.Flags().BoolVar(&canonical, "canonical", false, "Sign using the canonical binary encoding.")
+}
+
+func deployRun(cmd *cobra.Command, args []string) {
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	code, err := readCode(codePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fromAccount := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	const chainID = 1
+	tx := database.Tx{
+		ChainID: chainID,
+		Nonce:   nonce,
+		FromID:  fromAccount,
+		Value:   value,
+		Tip:     tip,
+		Data:    code,
+		Type:    database.TxTypeCreate,
+	}
+
+	submitTx(privateKey, tx)
+
+	fmt.Println("Contract:", database.ContractAccountID(fromAccount, nonce))
+}
+
+func callRun(cmd *cobra.Command, args []string) {
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	toAccount, err := database.ToAccountID(to)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	const chainID = 1
+	tx := database.Tx{
+		ChainID: chainID,
+		Nonce:   nonce,
+		FromID:  database.PublicKeyToAccountID(privateKey.PublicKey),
+		ToID:    toAccount,
+		Value:   value,
+		Tip:     tip,
+		Data:    data,
+		Type:    database.TxTypeCall,
+	}
+
+	submitTx(privateKey, tx)
+}
+
+// readCode reads the contract code from the file, assembling it when the
+// file holds assembly.
+func readCode(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading code: %w", err)
+	}
+
+	if filepath.Ext(path) == ".asm" {
+		return vm.Assemble(string(content))
+	}
+
+	return hexutil.Decode(strings.TrimSpace(string(content)))
+}
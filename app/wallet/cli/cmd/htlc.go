@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lockID   string
+	hashLock string
+	preimage string
+	expiry   uint64
+)
+
+var htlcCmd = &cobra.Command{
+	Use:   "htlc",
+	Short: "Lock, claim and refund value with hash time-locks",
+}
+
+var htlcLockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Lock value for an account until the preimage of a hash is presented",
+	Run:   htlcLockRun,
+}
+
+var htlcClaimCmd = &cobra.Command{
+	Use:   "claim",
+	Short: "Claim a lock for its recipient with the preimage",
+	Run:   htlcClaimRun,
+}
+
+var htlcRefundCmd = &cobra.Command{
+	Use:   "refund",
+	Short: "Refund an expired lock to its sender",
+	Run:   htlcRefundRun,
+}
+
+func init() {
+	rootCmd.AddCommand(htlcCmd)
+	htlcCmd.AddCommand(htlcLockCmd, htlcClaimCmd, htlcRefundCmd)
+
+	for _, cmd := range []*cobra.Command{htlcLockCmd, htlcClaimCmd, htlcRefundCmd} {
+		cmd.Flags().StringVarP(&url, "url", "u", "http://localhost:8080", "Url of the node.")
+		cmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "id for the transaction.")
+		cmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
+		cmd.Flags().Uint64Var(&maxFee, "max-fee", 0, "Maximum base fee per unit of gas to pay, required once the base fee is active.")
+		cmd.Flags().Uint64Var(&gasLimit, "gas-limit", 0, "Maximum units of gas to pay for, required once gas metering is active.")
+		cmd.Flags().BoolVar(&canonical, "canonical", false, "Sign using the canonical binary encoding.")
+	}
+
+	htlcLockCmd.Flags().StringVarP(&to, "to", "t", "", "Who can claim the lock.")
+	htlcLockCmd.Flags().Uint64VarP(&value, "value", "v", 0, "Value to lock.")
+	htlcLockCmd.Flags().StringVar(&hashLock, "hash", "", "Hash to lock with, a new secret is generated when not set.")
+	htlcLockCmd.Flags().Uint64Var(&expiry, "expiry", 0, "Block the lock expires at and can be refunded.")
+
+	htlcClaimCmd.Flags().StringVar(&lockID, "lock", "", "Lock to claim.")
+	htlcClaimCmd.Flags().StringVar(&preimage, "preimage", "", "Preimage of the hash of the lock.")
+
+	htlcRefundCmd.Flags().StringVar(&lockID, "lock", "", "Lock to refund.")
+}
+
+func htlcLockRun(cmd *cobra.Command, args []string) {
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	toAccount, err := database.ToAccountID(to)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	hl := database.HTLCLock{
+		Expiry: expiry,
+	}
+
+	switch hashLock {
+	case "":
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatal(err)
+		}
+		hl.HashLock = database.HashPreimage(secret)
+		fmt.Println("Preimage:", hexutil.Encode(secret))
+
+	default:
+		hash, err := hexutil.Decode(hashLock)
+		if err != nil || len(hash) != common.HashLength {
+			log.Fatalf("hash must be %d hex encoded bytes", common.HashLength)
+		}
+		hl.HashLock = common.BytesToHash(hash)
+	}
+
+	lock, err := hl.Encode()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fromAccount := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	const chainID = 1
+	tx := database.Tx{
+		ChainID: chainID,
+		Nonce:   nonce,
+		FromID:  fromAccount,
+		ToID:    toAccount,
+		Value:   value,
+		Tip:     tip,
+		Data:    lock,
+		Type:    database.TxTypeHTLCLock,
+	}
+
+	submitTx(privateKey, tx)
+
+	fmt.Println("Hash:", hl.HashLock.Hex())
+	fmt.Println("Lock:", database.HTLCAccountID(fromAccount, nonce))
+}
+
+func htlcClaimRun(cmd *cobra.Command, args []string) {
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	lockAccount, err := database.ToAccountID(lockID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	secret, err := hexutil.Decode(preimage)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	const chainID = 1
+	tx := database.Tx{
+		ChainID: chainID,
+		Nonce:   nonce,
+		FromID:  database.PublicKeyToAccountID(privateKey.PublicKey),
+		ToID:    lockAccount,
+		Tip:     tip,
+		Data:    secret,
+		Type:    database.TxTypeHTLCClaim,
+	}
+
+	submitTx(privateKey, tx)
+}
+
+func htlcRefundRun(cmd *cobra.Command, args []string) {
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	lockAccount, err := database.ToAccountID(lockID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	const chainID = 1
+	tx := database.Tx{
+		ChainID: chainID,
+		Nonce:   nonce,
+		FromID:  database.PublicKeyToAccountID(privateKey.PublicKey),
+		ToID:    lockAccount,
+		Tip:     tip,
+		Type:    database.TxTypeHTLCRefund,
+	}
+
+	submitTx(privateKey, tx)
+}
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+)
+
+var (
+	txFile    string
+	signers   []string
+	threshold uint16
+)
+
+var multisigCmd = &cobra.Command{
+	Use:   "multisig",
+	Short: "Register and spend from multisig accounts",
+}
+
+var multisigRegisterCmd = &cobra.Command{
+	Use:   "register",
+	Short: "Register a new multisig account",
+	Run:   multisigRegisterRun,
+}
+
+var multisigBuildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Build and sign a transaction from a multisig account into a file",
+	Run:   multisigBuildRun,
+}
+
+var multisigSignCmd = &cobra.Command{
+	Use:   "sign",
+	Short: "Add your signature to the transaction in a file",
+	Run:   multisigSignRun,
+}
+
+var multisigSubmitCmd = &cobra.Command{
+	Use:   "submit",
+	Short: "Submit the transaction in a file",
+	Run:   multisigSubmitRun,
+}
+
+func init() {
+	rootCmd.AddCommand(multisigCmd)
+	multisigCmd.AddCommand(multisigRegisterCmd, multisigBuildCmd, multisigSignCmd, multisigSubmitCmd)
+
+	multisigRegisterCmd.Flags().StringVarP(&url, "url", "u", "http://localhost:8080", "Url of the node.")
+	multisigRegisterCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "id for the transaction.")
+	multisigRegisterCmd.Flags().StringSliceVar(&signers, "signer", nil, "Account of a signer, repeat for each signer.")
+	multisigRegisterCmd.Flags().Uint16Var(&threshold, "threshold", 0, "Number of signers needed to spend.")
+	multisigRegisterCmd.Flags().Uint64VarP(&value, "value", "v", 0, "Value to fund the multisig account with.")
+	multisigRegisterCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
+	multisigRegisterCmd.Flags().Uint64Var(&maxFee, "max-fee", 0, "Maximum base fee per unit of gas to pay, required once the base fee is active.")
+	multisigRegisterCmd.Flags().Uint64Var(&gasLimit, "gas-limit", 0, "Maximum units of gas to pay for, required once gas metering is active.")
+	multisigRegisterCmd.Flags().BoolVar(&canonical, "canonical", false, "Sign using the canonical binary encoding.")
+
+	multisigBuildCmd.Flags().StringVar(&txFile, "file", "multisig-tx.json", "File to write the transaction to.")
+	multisigBuildCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "id for the transaction.")
+	multisigBuildCmd.Flags().StringVarP(&from, "from", "f", "", "Multisig account sending the transaction.")
+	multisigBuildCmd.Flags().StringVarP(&to, "to", "t", "", "Who is receiving the transaction.")
+	multisigBuildCmd.Flags().Uint64VarP(&value, "value", "v", 0, "Value to send.")
+	multisigBuildCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
+	multisigBuildCmd.Flags().BytesHexVarP(&data, "data", "d", nil, "Data to send.")
+	multisigBuildCmd.Flags().Uint64Var(&maxFee, "max-fee", 0, "Maximum base fee per unit of gas to pay, required once the base fee is active.")
+	multisigBuildCmd.Flags().Uint64Var(&gasLimit, "gas-limit", 0, "Maximum units of gas to pay for, required once gas metering is active.")
+	multisigBuildCmd.Flags().BoolVar(&canonical, "canonical", false, "Sign using the canonical binary encoding.")
+
+	multisigSignCmd.Flags().StringVar(&txFile, "file", "multisig-tx.json", "File holding the transaction.")
+
+	multisigSubmitCmd.Flags().StringVarP(&url, "url", "u", "http://localhost:8080", "Url of the node.")
+	multisigSubmitCmd.Flags().StringVar(&txFile, "file", "multisig-tx.json", "File holding the transaction.")
+}
+
+func multisigRegisterRun(cmd *cobra.Command, args []string) {
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mc := database.MultisigConfig{
+		Threshold: threshold,
+		Signers:   make([]database.AccountID, len(signers)),
+	}
+	for i, signer := range signers {
+		if mc.Signers[i], err = database.ToAccountID(signer); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := mc.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	config, err := mc.Encode()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fromAccount := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	const chainID = 1
+	tx := database.Tx{
+		ChainID: chainID,
+		Nonce:   nonce,
+		FromID:  fromAccount,
+		Value:   value,
+		Tip:     tip,
+		Data:    config,
+		Type:    database.TxTypeMultisig,
+	}
+
+	submitTx(privateKey, tx)
+
+	fmt.Println("Multisig:", database.MultisigAccountID(fromAccount, nonce))
+}
+
+func multisigBuildRun(cmd *cobra.Command, args []string) {
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fromAccount, err := database.ToAccountID(from)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	toAccount, err := database.ToAccountID(to)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	const chainID = 1
+	tx, err := database.NewTx(chainID, nonce, fromAccount, toAccount, value, tip, data)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tx.GasLimit = gasLimit
+	tx.MaxFee = maxFee
+
+	if canonical {
+		tx.Encoding = database.EncodingCanonical
+	}
+
+	signedTx, err := tx.Sign(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	writeTxFile(txFile, signedTx)
+}
+
+func multisigSignRun(cmd *cobra.Command, args []string) {
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	signedTx, err := readTxFile(txFile).CoSign(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := signedTx.Signers(); err != nil {
+		log.Fatal(err)
+	}
+
+	writeTxFile(txFile, signedTx)
+}
+
+func multisigSubmitRun(cmd *cobra.Command, args []string) {
+	postTx(readTxFile(txFile))
+}
+
+// readTxFile reads the partially signed transaction from the file.
+func readTxFile(path string) database.SignedTx {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var signedTx database.SignedTx
+	if err := json.Unmarshal(content, &signedTx); err != nil {
+		log.Fatal(err)
+	}
+
+	return signedTx
+}
+
+// writeTxFile writes the partially signed transaction to the file and shows
+// who signed it so far.
+func writeTxFile(path string, signedTx database.SignedTx) {
+	content, err := json.MarshalIndent(signedTx, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		log.Fatal(err)
+	}
+
+	signers, err := signedTx.Signers()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Signed by:", signers)
+}
@@ -21,6 +21,15 @@ var (
 	value uint64
 	tip   uint64
 	data  []byte
+
+	gasLimit  uint64
+	maxFee    uint64
+	canonical bool
+	feePayer  string
+	feeFile   string
+
+	validAfter uint64
+	validUntil uint64
 )
 
 var sendCmd = &cobra.Command{
@@ -38,6 +47,13 @@ func init() {
 	sendCmd.Flags().Uint64VarP(&value, "value", "v", 0, "Value to send.")
 	sendCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
 	sendCmd.Flags().BytesHexVarP(&data, "data", "d", nil, "Data to send.")
+	sendCmd.Flags().Uint64Var(&maxFee, "max-fee", 0, "Maximum base fee per unit of gas to pay, required once the base fee is active.")
+	sendCmd.Flags().Uint64Var(&gasLimit, "gas-limit", 0, "Maximum units of gas to pay for, required once gas metering is active.")
+	sendCmd.Flags().BoolVar(&canonical, "canonical", false, "Sign using the canonical binary encoding.")
+	sendCmd.Flags().Uint64Var(&validAfter, "valid-after", 0, "Block number, not a timestamp, the transaction can only be mined after.")
+	sendCmd.Flags().Uint64Var(&validUntil, "valid-until", 0, "Number of the last block, not a timestamp, the transaction can be mined in.")
+	sendCmd.Flags().StringVar(&feePayer, "fee-payer", "", "Account paying the gas and tip, which signs the transaction written to the file next.")
+	sendCmd.Flags().StringVar(&feeFile, "file", "sponsored-tx.json", "File to write the transaction the fee payer signs to.")
 }
 
 func sendRun(cmd *cobra.Command, args []string) {
@@ -66,11 +82,46 @@ func sendWithDetails(privateKey *ecdsa.PrivateKey) {
 		log.Fatal(err)
 	}
 
+	submitTx(privateKey, tx)
+}
+
+// submitTx adds the fee and encoding flags to the transaction, then signs and
+// submits it to the node. A transaction with a fee payer is written to the
+// file instead, so the fee payer can sign and submit it.
+func submitTx(privateKey *ecdsa.PrivateKey, tx database.Tx) {
+	tx.GasLimit = gasLimit
+	tx.MaxFee = maxFee
+	tx.ValidAfter = validAfter
+	tx.ValidUntil = validUntil
+
+	if canonical {
+		tx.Encoding = database.EncodingCanonical
+	}
+
+	if feePayer != "" {
+		feePayerAccount, err := database.ToAccountID(feePayer)
+		if err != nil {
+			log.Fatal(err)
+		}
+		tx.FeePayerID = feePayerAccount
+	}
+
 	signedTx, err := tx.Sign(privateKey)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	if tx.FeePayerID != "" {
+		writeTxFile(feeFile, signedTx)
+		fmt.Println("Fee payer:", tx.FeePayerID)
+		return
+	}
+
+	postTx(signedTx)
+}
+
+// postTx submits the signed transaction to the node.
+func postTx(signedTx database.SignedTx) {
 	data, err := json.Marshal(signedTx)
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+)
+
+var sponsorCmd = &cobra.Command{
+	Use:   "sponsor",
+	Short: "Sign and submit the transaction in a file as its fee payer",
+	Run:   sponsorRun,
+}
+
+func init() {
+	rootCmd.AddCommand(sponsorCmd)
+	sponsorCmd.Flags().StringVarP(&url, "url", "u", "http://localhost:8080", "Url of the node.")
+	sponsorCmd.Flags().StringVar(&feeFile, "file", "sponsored-tx.json", "File holding the transaction to pay for.")
+}
+
+func sponsorRun(cmd *cobra.Command, args []string) {
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	signedTx, err := readTxFile(feeFile).SignFeePayer(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	postTx(signedTx)
+
+	fmt.Println("Paid by:", signedTx.FeePayerID)
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/amount"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tokenID     string
+	tokenAmount string
+	symbol      string
+	decimals    uint8
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Issue, transfer and burn tokens",
+}
+
+var tokenIssueCmd = &cobra.Command{
+	Use:   "issue",
+	Short: "Issue a new token",
+	Run:   tokenIssueRun,
+}
+
+var tokenTransferCmd = &cobra.Command{
+	Use:   "transfer",
+	Short: "Transfer tokens",
+	Run:   tokenTransferRun,
+}
+
+var tokenBurnCmd = &cobra.Command{
+	Use:   "burn",
+	Short: "Burn tokens",
+	Run:   tokenBurnRun,
+}
+
+func init() {
+	rootCmd.AddCommand(tokenCmd)
+	tokenCmd.AddCommand(tokenIssueCmd, tokenTransferCmd, tokenBurnCmd)
+
+	for _, cmd := range []*cobra.Command{tokenIssueCmd, tokenTransferCmd, tokenBurnCmd} {
+		cmd.Flags().StringVarP(&url, "url", "u", "http://localhost:8080", "Url of the node.")
+		cmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "id for the transaction.")
+		cmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
+		cmd.Flags().Uint64Var(&maxFee, "max-fee", 0, "Maximum base fee per unit of gas to pay, required once the base fee is active.")
+		cmd.Flags().Uint64Var(&gasLimit, "gas-limit", 0, "Maximum units of gas to pay for, required once gas metering is active.")
+		cmd.Flags().BoolVar(&canonical, "canonical", false, "Sign using the canonical binary encoding.")
+	}
+
+	tokenIssueCmd.Flags().StringVar(&symbol, "symbol", "", "Symbol of the token.")
+	tokenIssueCmd.Flags().Uint8Var(&decimals, "decimals", 0, "Number of decimals of the token.")
+	tokenIssueCmd.Flags().StringVar(&tokenAmount, "supply", "", "Supply of the token, like 1000.50.")
+
+	tokenTransferCmd.Flags().StringVar(&tokenID, "token", "", "Token to transfer.")
+	tokenTransferCmd.Flags().StringVarP(&to, "to", "t", "", "Who is receiving the tokens.")
+	tokenTransferCmd.Flags().StringVar(&tokenAmount, "amount", "", "Amount of the token to transfer, like 10.25.")
+
+	tokenBurnCmd.Flags().StringVar(&tokenID, "token", "", "Token to burn.")
+	tokenBurnCmd.Flags().StringVar(&tokenAmount, "amount", "", "Amount of the token to burn, like 10.25.")
+}
+
+func tokenIssueRun(cmd *cobra.Command, args []string) {
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	denom := amount.Denomination{Symbol: symbol, Decimals: decimals}
+	supply, err := denom.Parse(tokenAmount)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ti := database.TokenIssue{
+		Symbol:   symbol,
+		Decimals: decimals,
+		Supply:   supply,
+	}
+
+	if err := ti.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	issue, err := ti.Encode()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fromAccount := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	const chainID = 1
+	tx := database.Tx{
+		ChainID: chainID,
+		Nonce:   nonce,
+		FromID:  fromAccount,
+		Tip:     tip,
+		Data:    issue,
+		Type:    database.TxTypeTokenIssue,
+	}
+
+	submitTx(privateKey, tx)
+
+	fmt.Println("Token:", database.TokenAccountID(fromAccount, nonce))
+}
+
+func tokenTransferRun(cmd *cobra.Command, args []string) {
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	toAccount, err := database.ToAccountID(to)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	const chainID = 1
+	tx := database.Tx{
+		ChainID: chainID,
+		Nonce:   nonce,
+		FromID:  database.PublicKeyToAccountID(privateKey.PublicKey),
+		ToID:    toAccount,
+		Tip:     tip,
+		Data:    encodeTokenAmount(),
+		Type:    database.TxTypeTokenTransfer,
+	}
+
+	submitTx(privateKey, tx)
+}
+
+func tokenBurnRun(cmd *cobra.Command, args []string) {
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	const chainID = 1
+	tx := database.Tx{
+		ChainID: chainID,
+		Nonce:   nonce,
+		FromID:  database.PublicKeyToAccountID(privateKey.PublicKey),
+		Tip:     tip,
+		Data:    encodeTokenAmount(),
+		Type:    database.TxTypeTokenBurn,
+	}
+
+	submitTx(privateKey, tx)
+}
+
+// encodeTokenAmount asks the node for the decimals of the token so the
+// amount can be converted into the smallest unit, and returns the data of
+// the transfer or burn transaction.
+func encodeTokenAmount() []byte {
+	token, err := database.ToAccountID(tokenID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("%s/v1/tokens/%s", url, token))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Fatalf("token %s: %s", token, resp.Status)
+	}
+
+	var tokens []struct {
+		Symbol   string `json:"symbol"`
+		Decimals uint8  `json:"decimals"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		log.Fatal(err)
+	}
+
+	if len(tokens) == 0 {
+		log.Fatalf("token %s not found", token)
+	}
+
+	denom := amount.Denomination{Symbol: tokens[0].Symbol, Decimals: tokens[0].Decimals}
+	amt, err := denom.Parse(tokenAmount)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ta := database.TokenAmount{
+		Token:  token,
+		Amount: amt,
+	}
+
+	data, err := ta.Encode()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return data
+}
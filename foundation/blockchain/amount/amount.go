@@ -0,0 +1,207 @@
+// Package amount provides checked arithmetic for the values that move between
+// accounts and an arbitrary precision amount with denominations for assets
+// that need more than 64 bits.
+package amount
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"strings"
+)
+
+// ErrOverflow is returned when the result of an operation doesn't fit.
+var ErrOverflow = errors.New("amount overflow")
+
+// ErrUnderflow is returned when an operation would produce a negative amount.
+var ErrUnderflow = errors.New("amount underflow")
+
+// =============================================================================
+
+// Add returns the sum of the values or ErrOverflow if it doesn't fit.
+func Add(a uint64, b uint64) (uint64, error) {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return 0, ErrOverflow
+	}
+
+	return sum, nil
+}
+
+// Sub returns the difference of the values or ErrUnderflow if b is larger
+// than a.
+func Sub(a uint64, b uint64) (uint64, error) {
+	diff, borrow := bits.Sub64(a, b, 0)
+	if borrow != 0 {
+		return 0, ErrUnderflow
+	}
+
+	return diff, nil
+}
+
+// Mul returns the product of the values or ErrOverflow if it doesn't fit.
+func Mul(a uint64, b uint64) (uint64, error) {
+	hi, lo := bits.Mul64(a, b)
+	if hi != 0 {
+		return 0, ErrOverflow
+	}
+
+	return lo, nil
+}
+
+// =============================================================================
+
+// Amount represents a non-negative amount of any size in the smallest unit of
+// an asset. The value is immutable and the zero value is an amount of zero.
+type Amount struct {
+	v *big.Int
+}
+
+// New constructs an amount from a uint64 value.
+func New(v uint64) Amount {
+	return Amount{v: new(big.Int).SetUint64(v)}
+}
+
+// FromBig constructs an amount from a copy of the big integer.
+func FromBig(v *big.Int) (Amount, error) {
+	if v == nil {
+		return Amount{}, nil
+	}
+
+	if v.Sign() < 0 {
+		return Amount{}, ErrUnderflow
+	}
+
+	return Amount{v: new(big.Int).Set(v)}, nil
+}
+
+// Big returns a copy of the amount as a big integer.
+func (a Amount) Big() *big.Int {
+	if a.v == nil {
+		return new(big.Int)
+	}
+
+	return new(big.Int).Set(a.v)
+}
+
+// Uint64 returns the amount as a uint64 or ErrOverflow if it doesn't fit.
+func (a Amount) Uint64() (uint64, error) {
+	v := a.Big()
+	if !v.IsUint64() {
+		return 0, ErrOverflow
+	}
+
+	return v.Uint64(), nil
+}
+
+// IsZero reports whether the amount is zero.
+func (a Amount) IsZero() bool {
+	return a.v == nil || a.v.Sign() == 0
+}
+
+// Cmp compares the amounts and returns -1, 0 or +1.
+func (a Amount) Cmp(b Amount) int {
+	return a.Big().Cmp(b.Big())
+}
+
+// Add returns the sum of the amounts.
+func (a Amount) Add(b Amount) Amount {
+	return Amount{v: new(big.Int).Add(a.Big(), b.Big())}
+}
+
+// Sub returns the difference of the amounts or ErrUnderflow if b is larger
+// than a.
+func (a Amount) Sub(b Amount) (Amount, error) {
+	if a.Cmp(b) < 0 {
+		return Amount{}, ErrUnderflow
+	}
+
+	return Amount{v: new(big.Int).Sub(a.Big(), b.Big())}, nil
+}
+
+// Mul returns the amount multiplied by the value.
+func (a Amount) Mul(v uint64) Amount {
+	return Amount{v: new(big.Int).Mul(a.Big(), new(big.Int).SetUint64(v))}
+}
+
+// String returns the amount in the smallest unit as a decimal string.
+func (a Amount) String() string {
+	return a.Big().String()
+}
+
+// MarshalText implements the encoding.TextMarshaler interface so the amount
+// is written as a decimal string that doesn't lose precision in JSON.
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (a *Amount) UnmarshalText(input []byte) error {
+	v, ok := new(big.Int).SetString(string(input), 10)
+	if !ok {
+		return fmt.Errorf("invalid amount %q", input)
+	}
+
+	amt, err := FromBig(v)
+	if err != nil {
+		return err
+	}
+
+	*a = amt
+	return nil
+}
+
+// =============================================================================
+
+// Denomination describes how the smallest unit of an asset is presented to
+// people. An asset with 2 decimals presents 12345 units as "123.45".
+type Denomination struct {
+	Symbol   string
+	Decimals uint8
+}
+
+// Parse converts a decimal string like "123.45" into an amount of the
+// smallest unit. More fractional digits than the denomination has decimals
+// is an error since the amount can't be represented.
+func (d Denomination) Parse(s string) (Amount, error) {
+	whole, frac, _ := strings.Cut(strings.TrimSpace(s), ".")
+	if whole == "" && frac == "" {
+		return Amount{}, fmt.Errorf("invalid amount %q", s)
+	}
+
+	if len(frac) > int(d.Decimals) {
+		return Amount{}, fmt.Errorf("amount %q has more than %d decimals", s, d.Decimals)
+	}
+
+	digits := whole + frac + strings.Repeat("0", int(d.Decimals)-len(frac))
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return Amount{}, fmt.Errorf("invalid amount %q", s)
+		}
+	}
+
+	v, _ := new(big.Int).SetString(digits, 10)
+	return Amount{v: v}, nil
+}
+
+// Format converts the amount of the smallest unit into a decimal string.
+// Trailing zeros of the fractional part are dropped.
+func (d Denomination) Format(a Amount) string {
+	digits := a.String()
+	if d.Decimals == 0 {
+		return digits
+	}
+
+	if pad := int(d.Decimals) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+
+	split := len(digits) - int(d.Decimals)
+	whole, frac := digits[:split], strings.TrimRight(digits[split:], "0")
+	if frac == "" {
+		return whole
+	}
+
+	return whole + "." + frac
+}
@@ -1,17 +1,31 @@
 package database
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"errors"
+	"fmt"
 
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/amount"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/smt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
 // Account represents information stored in the database for an individual account.
 type Account struct {
-	AccountID AccountID
-	Nonce     uint64
-	Balance   uint64
+	AccountID   AccountID
+	Nonce       uint64
+	Balance     uint64
+	CodeHash    string `json:",omitempty"` // Hash of the code of a contract account.
+	StorageRoot string `json:",omitempty"` // Root of the storage of a contract account.
+	Multisig    string `json:",omitempty"` // Encoded signers and threshold of a multisig account.
+	Token       string `json:",omitempty"` // Encoded issuer, symbol, decimals and supply of a token account.
+	Tokens      string `json:",omitempty"` // Encoded balances of the tokens held by the account.
+	HTLC        string `json:",omitempty"` // Encoded hash time-lock of a lock account.
+	Locked      string `json:",omitempty"` // Encoded time locks holding balance that can't be spent yet.
 }
 
 // newAccount constructs a new account value for use.
@@ -22,6 +36,100 @@ func newAccount(accountID AccountID, balance uint64) Account {
 	}
 }
 
+// registered reports whether the account was created by a transaction to
+// hold a contract, multisig, token or lock. These accounts get their address
+// from the sender and nonce of the transaction, so they can't be created
+// twice.
+func (a Account) registered() bool {
+	return a.CodeHash != "" || a.Multisig != "" || a.Token != "" || a.HTLC != ""
+}
+
+// AccountProof represents an account and the proof it's part of the state
+// trie with the specified root. The root is the state after the specified
+// block has been applied, which is the state root the next block commits to.
+// When the account doesn't exist, Value is nil and the proof shows the key
+// has no value.
+type AccountProof struct {
+	Account     Account
+	BlockNumber uint64
+	StateRoot   string
+	Key         smt.Key
+	Value       []byte
+	Proof       smt.Proof
+}
+
+// Verify checks the proof shows the account holds the proven values in the
+// state the specified header commits to. The header must be the block after
+// the proof's block number since each block commits to the state before its
+// transactions are applied. Only the values in the proof and the header are
+// used, so this can be run by a light client.
+func (ap AccountProof) Verify(accountID AccountID, header BlockHeader) error {
+	if header.Number != ap.BlockNumber+1 {
+		return fmt.Errorf("proof is for the state after block %d, header is for block %d", ap.BlockNumber, header.Number)
+	}
+
+	if ap.StateRoot != header.StateRoot {
+		return errors.New("state root doesn't match the block header")
+	}
+
+	var value []byte
+	switch {
+	case ap.Value == nil:
+		if ap.Account != (Account{}) {
+			return errors.New("proof shows the account doesn't exist but has account values")
+		}
+
+	default:
+		if ap.Account.AccountID != accountID {
+			return fmt.Errorf("proof is for account %s", ap.Account.AccountID)
+		}
+
+		var err error
+		if value, err = ap.Account.Encode(); err != nil {
+			return err
+		}
+
+		if !bytes.Equal(value, ap.Value) {
+			return errors.New("account doesn't match the proven value")
+		}
+	}
+
+	root, err := hexutil.Decode(header.StateRoot)
+	if err != nil {
+		return fmt.Errorf("invalid state root: %w", err)
+	}
+
+	return smt.VerifyProof(root, accountKey(accountID), value, ap.Proof)
+}
+
+// accountKey returns the key of the account in the state trie.
+func accountKey(accountID AccountID) smt.Key {
+	return smt.NewKey(accountBytes(accountID))
+}
+
+// =============================================================================
+
+// addBalance adds the values with checked arithmetic once the checkedBalances
+// fork is active. Blocks before the fork keep wrapping around on overflow so
+// they apply the same way they always have.
+func addBalance(rules genesis.Rules, a uint64, b uint64) (uint64, error) {
+	if !rules.IsActive(genesis.ForkCheckedBalances) {
+		return a + b, nil
+	}
+
+	return amount.Add(a, b)
+}
+
+// mulBalance multiplies the values with checked arithmetic once the
+// checkedBalances fork is active.
+func mulBalance(rules genesis.Rules, a uint64, b uint64) (uint64, error) {
+	if !rules.IsActive(genesis.ForkCheckedBalances) {
+		return a * b, nil
+	}
+
+	return amount.Mul(a, b)
+}
+
 // =============================================================================
 
 // AccountID represents an account id that is used to sign transactions and is
@@ -30,13 +138,31 @@ func newAccount(accountID AccountID, balance uint64) Account {
 type AccountID string
 
 // ToAccountID converts a hex-encoded string to an account and validates the
-// hex-encoded string is formatted correctly.
+// hex-encoded string is formatted correctly. The account is returned in its
+// EIP-55 checksum form, so every casing of an address names the same account.
 func ToAccountID(hex string) (AccountID, error) {
 	a := AccountID(hex)
 	if !a.IsAccountID() {
 		return "", errors.New("invalid account format")
 	}
 
+	return AccountID(common.HexToAddress(hex).Hex()), nil
+}
+
+// genesisAccountID converts an account from the genesis file. Once the
+// stateTrie fork is scheduled the genesis only holds accounts in checksum
+// form. Chains without it keep the accounts as written, since their state
+// hash covers the account strings.
+func genesisAccountID(gen genesis.Genesis, hex string) (AccountID, error) {
+	if _, exists := gen.Forks[genesis.ForkStateTrie]; exists {
+		return ToAccountID(hex)
+	}
+
+	a := AccountID(hex)
+	if !a.IsAccountID() {
+		return "", errors.New("invalid account format")
+	}
+
 	return a, nil
 }
 
@@ -57,6 +183,13 @@ func (a AccountID) IsAccountID() bool {
 	return len(a) == 2*addressLength && isHex(a)
 }
 
+// IsChecksummed reports whether the account is in its EIP-55 checksum form.
+// The state trie keys accounts by their 20 bytes, so once it's active only
+// this form is allowed and two casings can't name two accounts.
+func (a AccountID) IsChecksummed() bool {
+	return a.IsAccountID() && common.HexToAddress(string(a)).Hex() == string(a)
+}
+
 // =============================================================================
 
 // has0xPrefix validates the account starts with a 0x.
@@ -0,0 +1,131 @@
+package database_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/storage/disk"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Accounts used by the tests. The private key belongs to the pavel account.
+const (
+	testPrivateKey = "fae85851bdf5c9f49923722ce38f3c1defcfd3619ef5453230a58ad805499959"
+	kennedy        = "0xF01813E4B85e178A83e29B8E7bF26BD830a25f32"
+	pavel          = "0xdd6B972ffcc631a62CAE1BB9d80b7ff429c8ebA4"
+)
+
+// newTestDatabase constructs a database for the genesis backed by a
+// temporary directory.
+func newTestDatabase(t *testing.T, gen genesis.Genesis) *database.Database {
+	storage, err := disk.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to construct the storage : %s", failed, err)
+	}
+
+	db, err := database.New(gen, storage, func(v string, args ...any) {})
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to construct the database : %s", failed, err)
+	}
+
+	return db
+}
+
+// stateTrieGenesis returns a genesis with the stateTrie fork active from the
+// first block.
+func stateTrieGenesis(balances map[string]uint64) genesis.Genesis {
+	return genesis.Genesis{
+		Date:          time.Date(2021, 12, 17, 0, 0, 0, 0, time.UTC),
+		ChainID:       1,
+		TransPerBlock: 10,
+		Difficulty:    1,
+		MiningReward:  700,
+		GasPrice:      15,
+		Balances:      balances,
+		Forks:         map[string]uint64{genesis.ForkStateTrie: 0},
+	}
+}
+
+func TestAccountCasing(t *testing.T) {
+	lower := strings.ToLower(kennedy)
+
+	t.Log("Given the need for every casing of an address to name one account.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen converting two casings of one address.", testID)
+		{
+			lowerID, err := database.ToAccountID(lower)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to convert the lower case address : %s", failed, testID, err)
+			}
+			checksumID, err := database.ToAccountID(kennedy)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to convert the checksum address : %s", failed, testID, err)
+			}
+			if lowerID != checksumID || string(lowerID) != kennedy {
+				t.Fatalf("\t%s\tTest %d:\tShould convert both casings to the checksum form : got %s and %s, exp %s", failed, testID, lowerID, checksumID, kennedy)
+			}
+			t.Logf("\t%s\tTest %d:\tShould convert both casings to the checksum form.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen a genesis with the stateTrie fork funds a lower case account.", testID)
+		{
+			gen := stateTrieGenesis(map[string]uint64{lower: 100})
+			if err := gen.Validate(); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould reject the genesis.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject the genesis.", success, testID)
+
+			db := newTestDatabase(t, gen)
+			accounts := db.CopyAccounts()
+			if len(accounts) != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould hold one account : got %d", failed, testID, len(accounts))
+			}
+			if _, exists := accounts[database.AccountID(kennedy)]; !exists {
+				t.Fatalf("\t%s\tTest %d:\tShould hold the account in checksum form : got %v", failed, testID, accounts)
+			}
+			t.Logf("\t%s\tTest %d:\tShould hold one account in checksum form.", success, testID)
+
+			checksumDB := newTestDatabase(t, stateTrieGenesis(map[string]uint64{kennedy: 100}))
+			if got, exp := db.HashState(1), checksumDB.HashState(1); got != exp {
+				t.Fatalf("\t%s\tTest %d:\tShould produce the state root of the checksum account : got %s, exp %s", failed, testID, got, exp)
+			}
+			t.Logf("\t%s\tTest %d:\tShould produce the state root of the checksum account.", success, testID)
+		}
+
+		testID = 2
+		t.Logf("\tTest %d:\tWhen a transaction sends to a lower case account once the stateTrie fork is active.", testID)
+		{
+			privateKey, err := crypto.HexToECDSA(testPrivateKey)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to load the private key : %s", failed, testID, err)
+			}
+
+			tx := database.Tx{ChainID: 1, Nonce: 1, FromID: pavel, ToID: database.AccountID(lower), Value: 10}
+			signedTx, err := tx.Sign(privateKey)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to sign the transaction : %s", failed, testID, err)
+			}
+
+			rules := stateTrieGenesis(nil).Rules(1)
+			if err := signedTx.Validate(rules); err == nil || !strings.Contains(err.Error(), "checksum") {
+				t.Fatalf("\t%s\tTest %d:\tShould reject the transaction for its casing : %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject the transaction for its casing.", success, testID)
+
+			tx.ToID = kennedy
+			signedTx, err = tx.Sign(privateKey)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to sign the transaction : %s", failed, testID, err)
+			}
+			if err := signedTx.Validate(rules); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould accept the checksum account : %s", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept the checksum account.", success, testID)
+		}
+	}
+}
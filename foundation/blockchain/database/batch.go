@@ -0,0 +1,201 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/amount"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// CORE NOTE: Once the batchTransfers fork is active a batch transfer pays a
+// list of accounts with one transaction, so a payroll needs one signature,
+// one nonce and one tip instead of one for every payment. The entries are the
+// data of the transaction and the value of the transaction is their total,
+// so the sender is checked to hold enough for all of them before anything
+// moves. The per type gas of a batch transfer is charged for every entry.
+// Either every entry is paid or the transaction fails without paying any.
+
+// TxTypeBatchTransfer is the type of transaction that pays a list of accounts.
+const TxTypeBatchTransfer TxType = 10
+
+// MaxBatchEntries is the largest number of entries a batch transfer can have.
+const MaxBatchEntries = 1000
+
+// BatchEntry represents one payment of a batch transfer.
+type BatchEntry struct {
+	To    AccountID `json:"to"`
+	Value uint64    `json:"value"`
+}
+
+// rlpBatchEntry is the canonical layout of an entry of a batch transfer.
+type rlpBatchEntry struct {
+	To    []byte
+	Value uint64
+}
+
+// EncodeBatch returns the data of a batch transfer paying the entries.
+func EncodeBatch(entries []BatchEntry) ([]byte, error) {
+	rbes := make([]rlpBatchEntry, len(entries))
+	for i, entry := range entries {
+		rbes[i] = rlpBatchEntry{
+			To:    accountBytes(entry.To),
+			Value: entry.Value,
+		}
+	}
+
+	return rlp.EncodeToBytes(rbes)
+}
+
+// DecodeBatch converts the data of a batch transfer into its entries.
+func DecodeBatch(data []byte) ([]BatchEntry, error) {
+	var rbes []rlpBatchEntry
+	if err := rlp.DecodeBytes(data, &rbes); err != nil {
+		return nil, fmt.Errorf("decoding batch: %w", err)
+	}
+
+	entries := make([]BatchEntry, len(rbes))
+	for i, rbe := range rbes {
+		if len(rbe.To) != common.AddressLength {
+			return nil, fmt.Errorf("batch entry %d: to account is not properly formatted", i)
+		}
+
+		entries[i] = BatchEntry{
+			To:    AccountID(common.BytesToAddress(rbe.To).Hex()),
+			Value: rbe.Value,
+		}
+	}
+
+	return entries, nil
+}
+
+// batchRecipients returns the accounts paid by a batch transfer.
+func (tx Tx) batchRecipients() []AccountID {
+	if tx.Type != TxTypeBatchTransfer {
+		return nil
+	}
+
+	entries, err := DecodeBatch(tx.Data)
+	if err != nil {
+		return nil
+	}
+
+	recipients := make([]AccountID, len(entries))
+	for i, entry := range entries {
+		recipients[i] = entry.To
+	}
+
+	return recipients
+}
+
+// =============================================================================
+
+// txBatcher is implemented by the types of transaction whose per type gas is
+// charged for each entry of their payload.
+type txBatcher interface {
+	entries(tx Tx) (uint64, error)
+}
+
+// batchTransferHandler moves value from the sender to every account in the
+// entries of the transaction.
+type batchTransferHandler struct{}
+
+// Name implements the TxHandler interface.
+func (batchTransferHandler) Name() string {
+	return "batchTransfer"
+}
+
+// Fork implements the TxHandler interface.
+func (batchTransferHandler) Fork() string {
+	return genesis.ForkBatchTransfers
+}
+
+// entries implements the txBatcher interface.
+func (batchTransferHandler) entries(tx Tx) (uint64, error) {
+	entries, err := DecodeBatch(tx.Data)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(len(entries)), nil
+}
+
+// Validate implements the TxHandler interface.
+func (batchTransferHandler) Validate(rules genesis.Rules, tx Tx) error {
+	if tx.ToID != "" {
+		return errors.New("batch transfers pay the accounts in their entries and can't have a to account")
+	}
+
+	entries, err := DecodeBatch(tx.Data)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 || len(entries) > MaxBatchEntries {
+		return fmt.Errorf("batch transfers need between 1 and %d entries, got %d", MaxBatchEntries, len(entries))
+	}
+
+	var total uint64
+	for i, entry := range entries {
+		if entry.To == tx.FromID {
+			return fmt.Errorf("batch entry %d: sending money to yourself, from %s, to %s", i, tx.FromID, entry.To)
+		}
+
+		if entry.Value == 0 {
+			return fmt.Errorf("batch entry %d: value must be greater than 0", i)
+		}
+
+		if total, err = amount.Add(total, entry.Value); err != nil {
+			return fmt.Errorf("batch total: %w", err)
+		}
+	}
+
+	if tx.Value != total {
+		return fmt.Errorf("batch transfer value %d doesn't match the total of its entries %d", tx.Value, total)
+	}
+
+	return nil
+}
+
+// Apply implements the TxHandler interface.
+func (batchTransferHandler) Apply(tc *TxContext) error {
+	entries, err := DecodeBatch(tc.Tx.Data)
+	if err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+
+	// Every entry is checked before any account is changed, so either every
+	// entry is paid or none are. An account can be paid by more than one
+	// entry, so the accounts are kept in the order they are first paid.
+	var order []AccountID
+	accounts := make(map[AccountID]Account)
+	for i, entry := range entries {
+		to, exists := accounts[entry.To]
+		if !exists {
+			to = tc.Account(entry.To)
+			order = append(order, entry.To)
+		}
+
+		if to.CodeHash != "" {
+			return fmt.Errorf("transaction invalid, batch entry %d: account %s is a contract and needs a call transaction", i, to.AccountID)
+		}
+
+		balance, err := addBalance(tc.Rules, to.Balance, entry.Value)
+		if err != nil {
+			return fmt.Errorf("transaction invalid, batch entry %d: to balance: %w", i, err)
+		}
+		to.Balance = balance
+
+		accounts[entry.To] = to
+	}
+
+	tc.From.Balance -= tc.Tx.Value
+
+	for _, accountID := range order {
+		tc.SetAccount(accounts[accountID])
+	}
+
+	return nil
+}
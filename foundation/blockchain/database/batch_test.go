@@ -0,0 +1,120 @@
+package database_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+)
+
+func TestBatchTransfer(t *testing.T) {
+	const miner = "0xFef311483Cc040e1A89fb9bb469eeB8A70935EF8"
+
+	type balances struct {
+		pavel   uint64
+		kennedy uint64
+		miner   uint64
+	}
+
+	tt := []struct {
+		name    string
+		entries []database.BatchEntry
+		value   uint64
+		valid   bool
+		exp     balances
+	}{
+		{
+			name:    "paying two accounts",
+			entries: []database.BatchEntry{{To: kennedy, Value: 100}, {To: miner, Value: 50}},
+			value:   150,
+			valid:   true,
+			exp:     balances{pavel: 850, kennedy: 100, miner: 50},
+		},
+		{
+			name:    "paying the same account twice",
+			entries: []database.BatchEntry{{To: kennedy, Value: 100}, {To: miner, Value: 50}, {To: kennedy, Value: 25}},
+			value:   175,
+			valid:   true,
+			exp:     balances{pavel: 825, kennedy: 125, miner: 50},
+		},
+		{
+			name:    "entries whose total overflows",
+			entries: []database.BatchEntry{{To: kennedy, Value: math.MaxUint64}, {To: miner, Value: 1}},
+			value:   0,
+			exp:     balances{pavel: 1000},
+		},
+		{
+			name:    "a value that doesn't match the total of the entries",
+			entries: []database.BatchEntry{{To: kennedy, Value: 100}, {To: miner, Value: 50}},
+			value:   100,
+			exp:     balances{pavel: 1000},
+		},
+		{
+			name:    "a total more than the sender holds",
+			entries: []database.BatchEntry{{To: kennedy, Value: 600}, {To: miner, Value: 500}},
+			value:   1100,
+			exp:     balances{pavel: 1000},
+		},
+		{
+			name:    "an entry paying the sender",
+			entries: []database.BatchEntry{{To: kennedy, Value: 100}, {To: pavel, Value: 50}},
+			value:   150,
+			exp:     balances{pavel: 1000},
+		},
+		{
+			name:    "an entry without a value",
+			entries: []database.BatchEntry{{To: kennedy, Value: 100}, {To: miner, Value: 0}},
+			value:   100,
+			exp:     balances{pavel: 1000},
+		},
+	}
+
+	t.Log("Given the need to pay every entry of a batch transfer or none of them.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen applying %s.", testID, test.name)
+			{
+				gen := genesis.Genesis{
+					Date:          time.Date(2021, 12, 17, 0, 0, 0, 0, time.UTC),
+					ChainID:       1,
+					TransPerBlock: 10,
+					Difficulty:    1,
+					MiningReward:  700,
+					Balances:      map[string]uint64{pavel: 1000},
+					Forks:         map[string]uint64{genesis.ForkBatchTransfers: 0},
+				}
+				db := newTestDatabase(t, gen)
+
+				data, err := database.EncodeBatch(test.entries)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to encode the batch : %s", failed, testID, err)
+				}
+
+				tx := database.Tx{ChainID: 1, Nonce: 1, FromID: pavel, Type: database.TxTypeBatchTransfer, Value: test.value, Data: data}
+				block := database.Block{Header: database.BlockHeader{Number: 1}}
+
+				err = db.ApplyTransaction(block, database.NewBlockTx(signTx(t, tx), 0, 0))
+				switch {
+				case test.valid && err != nil:
+					t.Fatalf("\t%s\tTest %d:\tShould be able to apply the batch : %s", failed, testID, err)
+				case !test.valid && err == nil:
+					t.Fatalf("\t%s\tTest %d:\tShould fail the batch.", failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould apply the batch is %v.", success, testID, test.valid)
+
+				accounts := db.CopyAccounts()
+				got := balances{
+					pavel:   accounts[pavel].Balance,
+					kennedy: accounts[kennedy].Balance,
+					miner:   accounts[miner].Balance,
+				}
+				if got != test.exp {
+					t.Fatalf("\t%s\tTest %d:\tShould leave the expected balances : got %+v, exp %+v", failed, testID, got, test.exp)
+				}
+				t.Logf("\t%s\tTest %d:\tShould leave the expected balances.", success, testID)
+			}
+		}
+	}
+}
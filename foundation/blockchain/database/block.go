@@ -1,6 +1,7 @@
 package database
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"errors"
@@ -9,6 +10,7 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
 	"github.com/PhyoYazar/blockchain/foundation/blockchain/merkle"
 	"github.com/PhyoYazar/blockchain/foundation/blockchain/signature"
 )
@@ -17,29 +19,48 @@ import (
 // is two or more blocks ahead of ours.
 var ErrChainForked = errors.New("blockchain forked, start resync")
 
+// BlockDataVersion represents the current format version of block data. A
+// breaking change to BlockData, BlockHeader or BlockTx, one that changes how
+// a block already on disk is read like renaming, removing or changing the
+// meaning of a field, requires this version to be bumped and a storage
+// migration to be provided. Adding an optional field that is left out when
+// empty and only set once the fork adding it is active isn't a breaking
+// change, since every stored block still reads the same.
+const BlockDataVersion uint16 = 1
+
 // =============================================================================
 
 // BlockData represents what can be serialized to disk and over the network.
 type BlockData struct {
-	Hash   string      `json:"hash"`
-	Header BlockHeader `json:"block"`
-	Trans  []BlockTx   `json:"trans"`
+	Version uint16      `json:"version"`
+	Hash    string      `json:"hash"`
+	Header  BlockHeader `json:"block"`
+	Trans   []BlockTx   `json:"trans"`
 }
 
 // NewBlockData constructs block data from a block.
 func NewBlockData(block Block) BlockData {
 	blockData := BlockData{
-		Hash:   block.Hash(),
-		Header: block.Header,
-		Trans:  block.MerkleTree.Values(),
+		Version: BlockDataVersion,
+		Hash:    block.Hash(),
+		Header:  block.Header,
+		Trans:   block.MerkleTree.Values(),
 	}
 
 	return blockData
 }
 
-// ToBlock converts a storage block into a database block.
-func ToBlock(blockData BlockData) (Block, error) {
-	tree, err := merkle.NewTree(blockData.Trans)
+// ToBlock converts a storage block into a database block. The genesis
+// information is needed to know which rules apply to the block.
+func ToBlock(genesis genesis.Genesis, blockData BlockData) (Block, error) {
+	if blockData.Version > BlockDataVersion {
+		return Block{}, fmt.Errorf("block %d has format version %d, this node only supports up to version %d", blockData.Header.Number, blockData.Version, BlockDataVersion)
+	}
+
+	// The effective gas price isn't part of the block hash, so it's always recorded
+	// from the header and never taken from the stored block.
+	rules := genesis.Rules(blockData.Header.Number)
+	tree, err := newTransTree(rules, withEffectiveGasPrice(rules, blockData.Header.BaseFee, blockData.Trans))
 	if err != nil {
 		return Block{}, err
 	}
@@ -56,15 +77,19 @@ func ToBlock(blockData BlockData) (Block, error) {
 
 // BlockHeader represents common information required for each block.
 type BlockHeader struct {
-	Number        uint64    `json:"number"`          // Ethereum: Block number in the chain.
-	PrevBlockHash string    `json:"prev_block_hash"` // Bitcoin: Hash of the previous block in the chain.
-	TimeStamp     uint64    `json:"timestamp"`       // Bitcoin: Time the block was mined.
-	BeneficiaryID AccountID `json:"beneficiary"`     // Ethereum: The account who is receiving fees and tips.
-	Difficulty    uint16    `json:"difficulty"`      // Ethereum: Number of 0's needed to solve the hash solution.
-	MiningReward  uint64    `json:"mining_reward"`   // Ethereum: The reward for mining this block.
-	StateRoot     string    `json:"state_root"`      // Ethereum: Represents a hash of the accounts and their balances.
-	TransRoot     string    `json:"trans_root"`      // Both: Represents the merkle tree root hash for the transactions in this block.
-	Nonce         uint64    `json:"nonce"`           // Both: Value identified to solve the hash solution.
+	Number        uint64    `json:"number"`             // Ethereum: Block number in the chain.
+	PrevBlockHash string    `json:"prev_block_hash"`    // Bitcoin: Hash of the previous block in the chain.
+	TimeStamp     uint64    `json:"timestamp"`          // Bitcoin: Time the block was mined.
+	BeneficiaryID AccountID `json:"beneficiary"`        // Ethereum: The account who is receiving fees and tips.
+	Difficulty    uint16    `json:"difficulty"`         // Ethereum: Number of 0's needed to solve the hash solution.
+	MiningReward  uint64    `json:"mining_reward"`      // Ethereum: The reward for mining this block.
+	StateRoot     string    `json:"state_root"`         // Ethereum: Represents a hash of the accounts and their balances.
+	TransRoot     string    `json:"trans_root"`         // Both: Represents the merkle tree root hash for the transactions in this block.
+	Nonce         uint64    `json:"nonce"`              // Both: Value identified to solve the hash solution.
+	Encoding      Encoding  `json:"encoding,omitempty"` // Ardan: How the header is encoded for hashing.
+	Bloom         Bloom     `json:"bloom,omitempty"`    // Ethereum: Bloom filter of the accounts used by the transactions in this block.
+	GasUsed       uint64    `json:"gas_used,omitempty"` // Ethereum: Units of gas used by the transactions in this block.
+	BaseFee       uint64    `json:"base_fee,omitempty"` // Ethereum: Fee per unit of gas every transaction in this block pays.
 }
 
 // Block represents a group of transactions batched together.
@@ -75,9 +100,11 @@ type Block struct {
 
 // POWArgs represents the set of arguments required to run POW.
 type POWArgs struct {
+	GenesisHash   string
+	Rules         genesis.Rules
 	BeneficiaryID AccountID
 	Difficulty    uint16
-	MiningReward  uint64
+	BaseFee       uint64
 	PrevBlock     Block
 	StateRoot     string
 	Trans         []BlockTx
@@ -88,15 +115,13 @@ type POWArgs struct {
 // solves the cryptographic POW puzzel.
 func POW(ctx context.Context, args POWArgs) (Block, error) {
 
-	// When mining the first block, the previous block's hash will be zero.
-	prevBlockHash := signature.ZeroHash
-	if args.PrevBlock.Header.Number > 0 {
-		prevBlockHash = args.PrevBlock.Hash()
-	}
+	// When mining the first block, the previous block's hash will be zero
+	// or the hash of the genesis file.
+	prevBlockHash := parentHash(args.Rules, args.GenesisHash, args.PrevBlock.Header)
 
 	// Construct a merkle tree from the transaction for this block. The root
 	// of this tree will be part of the block to be mined.
-	tree, err := merkle.NewTree(args.Trans)
+	tree, err := newTransTree(args.Rules, withEffectiveGasPrice(args.Rules, args.BaseFee, args.Trans))
 	if err != nil {
 		return Block{}, err
 	}
@@ -109,10 +134,14 @@ func POW(ctx context.Context, args POWArgs) (Block, error) {
 			TimeStamp:     uint64(time.Now().UTC().UnixMilli()),
 			BeneficiaryID: args.BeneficiaryID,
 			Difficulty:    args.Difficulty,
-			MiningReward:  args.MiningReward,
+			MiningReward:  args.Rules.MiningReward,
 			StateRoot:     args.StateRoot,
 			TransRoot:     tree.RootHex(), //
 			Nonce:         0,              // Will be identified by the POW algorithm.
+			Encoding:      HeaderEncoding(args.Rules),
+			Bloom:         blockBloom(args.Rules, args.Trans),
+			GasUsed:       blockGasUsed(args.Rules, args.Trans),
+			BaseFee:       args.BaseFee,
 		},
 		MerkleTree: tree,
 	}
@@ -176,9 +205,6 @@ func (b *Block) performPOW(ctx context.Context, ev func(v string, args ...any))
 
 // Hash returns the unique hash for the Block.
 func (b Block) Hash() string {
-	if b.Header.Number == 0 {
-		return signature.ZeroHash
-	}
 
 	// CORE NOTE: Hashing the block header and not the whole block so the blockchain
 	// can be cryptographically checked by only needing block headers and not full
@@ -191,11 +217,11 @@ func (b Block) Hash() string {
 	//   to follow the latest set of blocks being produced. The do not validate
 	//   blocks, but can prove a transaction is in a block.
 
-	return signature.Hash(b.Header)
+	return b.Header.Hash()
 }
 
 // ValidateBlock takes a block and validates it to be included into the blockchain.
-func (b Block) ValidateBlock(previousBlock Block, stateRoot string, evHandler func(v string, args ...any)) error {
+func (b Block) ValidateBlock(genesis genesis.Genesis, previousBlock Block, stateRoot string, evHandler func(v string, args ...any)) error {
 	evHandler("database: ValidateBlock: validate: blk[%d]: check: chain is not forked", b.Header.Number)
 
 	// The node who sent this block has a chain that is two or more blocks ahead
@@ -205,43 +231,147 @@ func (b Block) ValidateBlock(previousBlock Block, stateRoot string, evHandler fu
 		return ErrChainForked
 	}
 
-	evHandler("database: ValidateBlock: validate: blk[%d]: check: block difficulty is the same or greater than parent block difficulty", b.Header.Number)
+	if err := b.Header.ValidateHeader(genesis, previousBlock.Header, evHandler); err != nil {
+		return err
+	}
+
+	evHandler("database: ValidateBlock: validate: blk[%d]: check: state root hash does match current database", b.Header.Number)
+
+	if b.Header.StateRoot != stateRoot {
+		return fmt.Errorf("state of the accounts are wrong, current %s, expected %s", stateRoot, b.Header.StateRoot)
+	}
+
+	evHandler("database: ValidateBlock: validate: blk[%d]: check: merkle root does match transactions", b.Header.Number)
 
-	if b.Header.Difficulty < previousBlock.Header.Difficulty {
-		return fmt.Errorf("block difficulty is less than previous block difficulty, parent %d, block %d", previousBlock.Header.Difficulty, b.Header.Difficulty)
+	if b.Header.TransRoot != b.MerkleTree.RootHex() {
+		return fmt.Errorf("merkle root does not match transactions, got %s, exp %s", b.MerkleTree.RootHex(), b.Header.TransRoot)
 	}
 
-	evHandler("database: ValidateBlock: validate: blk[%d]: check: block hash has been solved", b.Header.Number)
+	evHandler("database: ValidateBlock: validate: blk[%d]: check: transactions fit within the block gas limit", b.Header.Number)
+
+	if err := validateBlockGas(genesis.Rules(b.Header.Number), b.MerkleTree.Values()); err != nil {
+		return err
+	}
+
+	evHandler("database: ValidateBlock: validate: blk[%d]: check: transactions are valid in this block", b.Header.Number)
+
+	if err := validateBlockWindows(genesis.Rules(b.Header.Number), b.MerkleTree.Values()); err != nil {
+		return err
+	}
+
+	evHandler("database: ValidateBlock: validate: blk[%d]: check: transactions pay the block base fee", b.Header.Number)
+
+	if err := b.validateBaseFee(genesis.Rules(b.Header.Number)); err != nil {
+		return err
+	}
+
+	evHandler("database: ValidateBlock: validate: blk[%d]: check: bloom does match transactions", b.Header.Number)
+
+	if bloom := blockBloom(genesis.Rules(b.Header.Number), b.MerkleTree.Values()); !bytes.Equal(b.Header.Bloom, bloom) {
+		return errors.New("bloom does not match transactions")
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// Hash returns the unique hash for the block header. See Block.Hash for
+// why only the header is hashed.
+func (bh BlockHeader) Hash() string {
+	if bh.Number == 0 {
+		return signature.ZeroHash
+	}
+
+	data, err := bh.Encode()
+	if err != nil {
+		return signature.ZeroHash
+	}
+
+	return signature.HashBytes(data)
+}
+
+// ValidateHeader validates the block header against its parent header. These
+// are the checks that don't need the transactions or the account state, so
+// a light client can run them with only the chain of block headers.
+func (bh BlockHeader) ValidateHeader(genesis genesis.Genesis, previousHeader BlockHeader, evHandler func(v string, args ...any)) error {
 
-	hash := b.Hash()
-	if !isHashSolved(b.Header.Difficulty, hash) {
+	// Capture the consensus rules that apply to this block.
+	rules := genesis.Rules(bh.Number)
+
+	if difficultyChecked(rules) {
+		evHandler("database: ValidateHeader: validate: blk[%d]: check: block difficulty is the same or greater than the required difficulty", bh.Number)
+
+		if bh.Difficulty < rules.Difficulty {
+			return fmt.Errorf("block difficulty is less than required difficulty, required %d, block %d", rules.Difficulty, bh.Difficulty)
+		}
+	}
+
+	evHandler("database: ValidateHeader: validate: blk[%d]: check: block difficulty is the same or greater than parent block difficulty", bh.Number)
+
+	if bh.Difficulty < previousHeader.Difficulty {
+		return fmt.Errorf("block difficulty is less than previous block difficulty, parent %d, block %d", previousHeader.Difficulty, bh.Difficulty)
+	}
+
+	evHandler("database: ValidateHeader: validate: blk[%d]: check: block header uses the required encoding", bh.Number)
+
+	if encoding := HeaderEncoding(rules); bh.Encoding != encoding {
+		return fmt.Errorf("block header encoding is wrong, got %d, exp %d", bh.Encoding, encoding)
+	}
+
+	evHandler("database: ValidateHeader: validate: blk[%d]: check: block header bloom has the required size", bh.Number)
+
+	if size := bloomSize(rules); len(bh.Bloom) != size {
+		return fmt.Errorf("block header bloom is the wrong size, got %d, exp %d", len(bh.Bloom), size)
+	}
+
+	evHandler("database: ValidateHeader: validate: blk[%d]: check: block base fee follows the parent block", bh.Number)
+
+	if baseFee := NextBaseFee(genesis, previousHeader); bh.BaseFee != baseFee {
+		return fmt.Errorf("block base fee is wrong, got %d, exp %d", bh.BaseFee, baseFee)
+	}
+
+	if rewardChecked(rules) {
+		evHandler("database: ValidateHeader: validate: blk[%d]: check: block mining reward follows the reward schedule", bh.Number)
+
+		if bh.MiningReward != rules.MiningReward {
+			return fmt.Errorf("block mining reward is wrong, got %d, exp %d", bh.MiningReward, rules.MiningReward)
+		}
+	}
+
+	evHandler("database: ValidateHeader: validate: blk[%d]: check: block hash has been solved", bh.Number)
+
+	hash := bh.Hash()
+	if !isHashSolved(bh.Difficulty, hash) {
 		return fmt.Errorf("%s invalid block hash", hash)
 	}
 
-	evHandler("database: ValidateBlock: validate: blk[%d]: check: block number is the next number", b.Header.Number)
+	evHandler("database: ValidateHeader: validate: blk[%d]: check: block number is the next number", bh.Number)
 
-	if b.Header.Number != nextNumber {
-		return fmt.Errorf("this block is not the next number, got %d, exp %d", b.Header.Number, nextNumber)
+	nextNumber := previousHeader.Number + 1
+	if bh.Number != nextNumber {
+		return fmt.Errorf("this block is not the next number, got %d, exp %d", bh.Number, nextNumber)
 	}
 
-	evHandler("database: ValidateBlock: validate: blk[%d]: check: parent hash does match parent block", b.Header.Number)
+	evHandler("database: ValidateHeader: validate: blk[%d]: check: parent hash does match parent block", bh.Number)
 
-	if b.Header.PrevBlockHash != previousBlock.Hash() {
-		return fmt.Errorf("parent block hash doesn't match our known parent, got %s, exp %s", b.Header.PrevBlockHash, previousBlock.Hash())
+	prevBlockHash := parentHash(rules, genesis.Hash(), previousHeader)
+	if bh.PrevBlockHash != prevBlockHash {
+		return fmt.Errorf("parent block hash doesn't match our known parent, got %s, exp %s", bh.PrevBlockHash, prevBlockHash)
 	}
 
-	if previousBlock.Header.TimeStamp > 0 {
-		evHandler("database: ValidateBlock: validate: blk[%d]: check: block's timestamp is greater than parent block's timestamp", b.Header.Number)
+	if previousHeader.TimeStamp > 0 {
+		evHandler("database: ValidateHeader: validate: blk[%d]: check: block's timestamp is greater than parent block's timestamp", bh.Number)
 
-		parentTime := time.Unix(int64(previousBlock.Header.TimeStamp), 0)
-		blockTime := time.Unix(int64(b.Header.TimeStamp), 0)
+		parentTime := time.Unix(int64(previousHeader.TimeStamp), 0)
+		blockTime := time.Unix(int64(bh.TimeStamp), 0)
 		if blockTime.Before(parentTime) {
 			return fmt.Errorf("block timestamp is before parent block, parent %s, block %s", parentTime, blockTime)
 		}
 
 		// This is a check that Ethereum does but we can't because we don't run all the time.
 
-		// evHandler("database: ValidateBlock: validate: blk[%d]: check: block is less than 15 minutes apart from parent block", b.Header.Number)
+		// evHandler("database: ValidateHeader: validate: blk[%d]: check: block is less than 15 minutes apart from parent block", bh.Number)
 
 		// dur := blockTime.Sub(parentTime)
 		// if dur.Seconds() > time.Duration(15*time.Second).Seconds() {
@@ -249,19 +379,41 @@ func (b Block) ValidateBlock(previousBlock Block, stateRoot string, evHandler fu
 		// }
 	}
 
-	evHandler("database: ValidateBlock: validate: blk[%d]: check: state root hash does match current database", b.Header.Number)
+	return nil
+}
 
-	if b.Header.StateRoot != stateRoot {
-		return fmt.Errorf("state of the accounts are wrong, current %s, expected %s", stateRoot, b.Header.StateRoot)
+// =============================================================================
+
+// newTransTree constructs the merkle tree for the transactions of a block
+// using the tree mode the rules require.
+func newTransTree(rules genesis.Rules, trans []BlockTx) (*merkle.Tree[BlockTx], error) {
+	if hardenedMerkle(rules) {
+		return merkle.NewTree(trans, merkle.WithHardened[BlockTx]())
 	}
 
-	evHandler("database: ValidateBlock: validate: blk[%d]: check: merkle root does match transactions", b.Header.Number)
+	return merkle.NewTree(trans)
+}
 
-	if b.Header.TransRoot != b.MerkleTree.RootHex() {
-		return fmt.Errorf("merkle root does not match transactions, got %s, exp %s", b.MerkleTree.RootHex(), b.Header.TransRoot)
+// hardenedMerkle reports whether the transaction merkle tree of a block is
+// constructed with the hardened tree mode under the specified rules.
+func hardenedMerkle(rules genesis.Rules) bool {
+	return rules.IsActive(genesis.ForkHardenedMerkle)
+}
+
+// parentHash returns the hash the next block needs to reference as its
+// parent. Once the genesisParent fork is active the first block in the chain
+// references the genesis hash so chains created from different genesis files
+// are incompatible. Before that it references the zero hash.
+func parentHash(rules genesis.Rules, genesisHash string, previousHeader BlockHeader) string {
+	if previousHeader.Number > 0 {
+		return previousHeader.Hash()
 	}
 
-	return nil
+	if rules.IsActive(genesis.ForkGenesisParent) {
+		return genesisHash
+	}
+
+	return signature.ZeroHash
 }
 
 // isHashSolved checks the hash to make sure it complies with
@@ -276,3 +428,15 @@ func isHashSolved(difficulty uint16, hash string) bool {
 	difficulty += 2
 	return hash[:difficulty] == match[:difficulty]
 }
+
+// difficultyChecked reports whether the difficulty of a block header must be
+// at least the difficulty of the genesis file under the rules.
+func difficultyChecked(rules genesis.Rules) bool {
+	return rules.IsActive(genesis.ForkMinimumDifficulty)
+}
+
+// rewardChecked reports whether the mining reward of a block header must
+// match the reward schedule under the rules.
+func rewardChecked(rules genesis.Rules) bool {
+	return rules.IsActive(genesis.ForkRewardSchedule)
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// CORE NOTE: Finding the blocks that touch an account means decoding every
+// transaction in every block. Once the headerBloom fork is active each block
+// header carries a fixed size bloom filter of the from and to accounts of its
+// transactions. A bloom can report an account is in a block when it isn't,
+// but never the other way around, so only the blocks whose bloom matches have
+// to be loaded. The bloom is part of the block hash, so a light wallet can
+// download just the headers and trust the filters.
+
+// BloomSize represents the number of bytes in a block header bloom filter.
+const BloomSize = 256
+
+// bloomHashes represents the number of bits set in the bloom per account.
+const bloomHashes = 3
+
+// Bloom represents a bloom filter over the accounts that send or receive a
+// transaction in a block. Blocks mined before the headerBloom fork have an
+// empty bloom.
+type Bloom []byte
+
+// NewBloom constructs an empty bloom that holds the specified accounts.
+func NewBloom(accountIDs ...AccountID) Bloom {
+	b := make(Bloom, BloomSize)
+	for _, accountID := range accountIDs {
+		b.Add(accountID)
+	}
+
+	return b
+}
+
+// Add sets the bits for the specified account in the bloom.
+func (b Bloom) Add(accountID AccountID) {
+	for _, bit := range bloomBits(accountID) {
+		b[BloomSize-1-bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// Test reports whether the account may be in the bloom. An empty bloom
+// always matches since nothing is known about the block.
+func (b Bloom) Test(accountID AccountID) bool {
+	if len(b) != BloomSize {
+		return true
+	}
+
+	for _, bit := range bloomBits(accountID) {
+		if b[BloomSize-1-bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MarshalText implements the encoding.TextMarshaler interface so the bloom
+// is written as a hex-encoded string.
+func (b Bloom) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(b).MarshalText()
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (b *Bloom) UnmarshalText(input []byte) error {
+	var data hexutil.Bytes
+	if err := data.UnmarshalText(input); err != nil {
+		return err
+	}
+
+	*b = Bloom(data)
+	return nil
+}
+
+// =============================================================================
+
+// blockBloom returns the bloom the header of a block with the specified
+// transactions must have under the rules.
+func blockBloom(rules genesis.Rules, trans []BlockTx) Bloom {
+	if !rules.IsActive(genesis.ForkHeaderBloom) {
+		return nil
+	}
+
+	b := NewBloom()
+	for _, tx := range trans {
+		b.Add(tx.FromID)
+		b.Add(tx.ToID)
+		if tx.FeePayerID != "" {
+			b.Add(tx.FeePayerID)
+		}
+		for _, recipient := range tx.batchRecipients() {
+			b.Add(recipient)
+		}
+	}
+
+	return b
+}
+
+// bloomSize returns the size the bloom of a block header must have under
+// the rules.
+func bloomSize(rules genesis.Rules) int {
+	if !rules.IsActive(genesis.ForkHeaderBloom) {
+		return 0
+	}
+
+	return BloomSize
+}
+
+// bloomBits returns the bits of the bloom that are set for the account.
+func bloomBits(accountID AccountID) [bloomHashes]uint {
+	hash := sha256.Sum256(accountBytes(accountID))
+
+	var bits [bloomHashes]uint
+	for i := range bits {
+		bits[i] = uint(binary.BigEndian.Uint16(hash[2*i:])) % (BloomSize * 8)
+	}
+
+	return bits
+}
@@ -0,0 +1,408 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/signature"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/smt"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/vm"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// CORE NOTE: Once the contracts fork is active a create transaction deploys
+// the transaction data as the code of a new contract account, whose address
+// comes from the sender and the nonce. The code runs once with no call data
+// so it can set up its storage. A call transaction runs the code of the
+// contract it's sent to with the transaction data as the call data. Each
+// contract has its own key/value storage in a sparse merkle tree, and the
+// root of that tree and the hash of the code are part of the contract's
+// account in the state trie. The gas a contract uses isn't known until it
+// runs, so create and call transactions reserve their whole gas limit in the
+// block, which keeps the gas of a block known before its transactions are
+// applied. The sender pays for the intrinsic gas and the gas the code used,
+// the fee for the rest of the gas limit is refunded once the code has run.
+// The code runs against a copy of the contract's storage and balance. When
+// the code reverts, the copy is thrown away, but the gas it used is still
+// charged. Any other failure charges the whole gas limit. In both cases
+// the nonce of the sender is used, so the failed transaction can't be mined
+// again.
+
+// Set of transaction types that deploy and run contracts.
+const (
+	TxTypeCreate TxType = 1
+	TxTypeCall   TxType = 2
+)
+
+// Contract represents the code and storage of a contract account. The code
+// never changes once stored in the database. The storage is changed in place
+// by the calls to the contract, so the database hands out copies of it.
+type Contract struct {
+	Code    []byte
+	Storage map[vm.Word]vm.Word
+
+	// trie is the sparse merkle tree of the storage. It's kept with the
+	// contract so only the slots the code changes need to be updated.
+	trie *smt.Tree
+}
+
+// CodeHash returns the hash of the contract code stored in its account.
+func (c Contract) CodeHash() string {
+	return signature.HashBytes(c.Code)
+}
+
+// StorageRoot returns the root of the sparse merkle tree of the contract
+// storage, which is stored in its account.
+func (c Contract) StorageRoot() string {
+	return c.storageTrie().RootHex()
+}
+
+// storageTrie returns the sparse merkle tree of the contract storage, which
+// is built from the storage when the contract doesn't have it yet.
+func (c Contract) storageTrie() *smt.Tree {
+	if c.trie != nil {
+		return c.trie
+	}
+
+	trie := smt.NewTree()
+	for key, value := range c.Storage {
+		trie.Update(storageKey(key), value[:])
+	}
+
+	return trie
+}
+
+// Slots returns the values in the contract storage sorted by key.
+func (c Contract) Slots() []StorageSlot {
+	slots := make([]StorageSlot, 0, len(c.Storage))
+	for key, value := range c.Storage {
+		slots = append(slots, StorageSlot{Key: key, Value: value})
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		return bytes.Compare(slots[i].Key[:], slots[j].Key[:]) < 0
+	})
+
+	return slots
+}
+
+// copyStorage returns a copy of the storage of a contract.
+func copyStorage(storage map[vm.Word]vm.Word) map[vm.Word]vm.Word {
+	cp := make(map[vm.Word]vm.Word, len(storage))
+	for key, value := range storage {
+		cp[key] = value
+	}
+
+	return cp
+}
+
+// ContractAccountID returns the account of the contract deployed by the
+// create transaction with the specified sender and nonce.
+func ContractAccountID(fromID AccountID, nonce uint64) AccountID {
+	address := crypto.CreateAddress(common.HexToAddress(string(fromID)), nonce)
+	return AccountID(address.Hex())
+}
+
+// storageKey returns the key of a storage slot in the contract's tree.
+func storageKey(key vm.Word) smt.Key {
+	return smt.NewKey(key[:])
+}
+
+// =============================================================================
+
+// ContractSnapshot represents the code and storage of a contract account
+// in a snapshot of the state.
+type ContractSnapshot struct {
+	AccountID AccountID     `json:"account"`
+	Code      hexutil.Bytes `json:"code"`
+	Storage   []StorageSlot `json:"storage,omitempty"`
+}
+
+// StorageSlot represents a value in the storage of a contract.
+type StorageSlot struct {
+	Key   vm.Word `json:"key"`
+	Value vm.Word `json:"value"`
+}
+
+// newContractSnapshot constructs the snapshot of a contract.
+func newContractSnapshot(accountID AccountID, contract Contract) ContractSnapshot {
+	return ContractSnapshot{
+		AccountID: accountID,
+		Code:      contract.Code,
+		Storage:   contract.Slots(),
+	}
+}
+
+// contract converts the snapshot back into the contract.
+func (cs ContractSnapshot) contract() Contract {
+	contract := Contract{
+		Code:    cs.Code,
+		Storage: make(map[vm.Word]vm.Word, len(cs.Storage)),
+	}
+
+	for _, slot := range cs.Storage {
+		contract.Storage[slot.Key] = slot.Value
+	}
+	contract.trie = contract.storageTrie()
+
+	return contract
+}
+
+// =============================================================================
+
+// txExecutor is implemented by the types of transaction that run contract
+// code. The gas the code uses isn't known until it runs, so these
+// transactions reserve their whole gas limit and are refunded the fee for
+// the gas the code didn't use.
+type txExecutor interface {
+	executes()
+}
+
+// createHandler deploys the transaction data as the code of a new contract.
+type createHandler struct{}
+
+// Name implements the TxHandler interface.
+func (createHandler) Name() string {
+	return "create"
+}
+
+// Fork implements the TxHandler interface.
+func (createHandler) Fork() string {
+	return genesis.ForkContracts
+}
+
+// executes implements the txExecutor interface.
+func (createHandler) executes() {}
+
+// Validate implements the TxHandler interface.
+func (createHandler) Validate(rules genesis.Rules, tx Tx) error {
+	if tx.ToID != "" {
+		return errors.New("create transactions can't have a to account")
+	}
+
+	if len(tx.Data) == 0 {
+		return errors.New("create transactions need the contract code as data")
+	}
+
+	if len(tx.Data) > vm.MaxCodeSize {
+		return fmt.Errorf("contract code is %d bytes, the limit is %d", len(tx.Data), vm.MaxCodeSize)
+	}
+
+	return nil
+}
+
+// Apply implements the TxHandler interface.
+func (createHandler) Apply(tc *TxContext) error {
+	contractID := ContractAccountID(tc.From.AccountID, tc.Tx.Nonce)
+
+	if account := tc.Account(contractID); account.registered() {
+		return fmt.Errorf("transaction invalid, contract %s already exists", contractID)
+	}
+
+	contract := Contract{
+		Code:    tc.Tx.Data,
+		Storage: make(map[vm.Word]vm.Word),
+	}
+
+	return runContract(tc, contractID, contract, nil)
+}
+
+// =============================================================================
+
+// callHandler runs the code of the contract the transaction is sent to.
+type callHandler struct{}
+
+// Name implements the TxHandler interface.
+func (callHandler) Name() string {
+	return "call"
+}
+
+// Fork implements the TxHandler interface.
+func (callHandler) Fork() string {
+	return genesis.ForkContracts
+}
+
+// executes implements the txExecutor interface.
+func (callHandler) executes() {}
+
+// Validate implements the TxHandler interface.
+func (callHandler) Validate(rules genesis.Rules, tx Tx) error {
+	if !tx.ToID.IsAccountID() {
+		return errors.New("to account is not properly formatted")
+	}
+
+	return nil
+}
+
+// Apply implements the TxHandler interface.
+func (callHandler) Apply(tc *TxContext) error {
+	contract, exists := tc.db.contracts[tc.Tx.ToID]
+	if !exists {
+		return fmt.Errorf("transaction invalid, account %s is not a contract", tc.Tx.ToID)
+	}
+
+	return runContract(tc, tc.Tx.ToID, contract, tc.Tx.Data)
+}
+
+// =============================================================================
+
+// runContract runs the code of the contract, keeping the slots it stores
+// apart from its storage, and stores the changes when the code succeeds.
+// The storage isn't copied, so a call only costs the host work for the
+// slots it touches, the same as the gas it's charged.
+func runContract(tc *TxContext, contractID AccountID, contract Contract, callData []byte) error {
+	intrinsic, err := tc.Tx.intrinsicGas(tc.Rules)
+	if err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+
+	if tc.Tx.GasUnits < intrinsic {
+		return fmt.Errorf("transaction invalid, gas units %d don't cover the intrinsic gas %d", tc.Tx.GasUnits, intrinsic)
+	}
+
+	account := tc.Account(contractID)
+
+	balance, err := addBalance(tc.Rules, account.Balance, tc.Tx.Value)
+	if err != nil {
+		return fmt.Errorf("transaction invalid, contract balance: %w", err)
+	}
+
+	host := contractHost{
+		address: accountBytes(contractID),
+		balance: balance,
+		storage: contract.Storage,
+		dirty:   make(map[vm.Word]vm.Word),
+		credits: make(map[AccountID]uint64),
+	}
+
+	ctx := vm.Context{
+		Address:   vm.WordFromBytes(host.address),
+		Caller:    vm.WordFromBytes(accountBytes(tc.From.AccountID)),
+		Value:     tc.Tx.Value,
+		Data:      callData,
+		Number:    tc.Header.Number,
+		TimeStamp: tc.Header.TimeStamp,
+		Gas:       tc.Tx.GasUnits - intrinsic,
+	}
+
+	// Code that reverts pays for the gas it used, any other failure uses
+	// the whole gas limit.
+	used, err := vm.Run(ctx, contract.Code, &host)
+	if err == nil || errors.Is(err, vm.ErrRevert) {
+		tc.GasUsed = intrinsic + used
+	}
+	if err != nil {
+		return fmt.Errorf("contract %s failed: %w", contractID, err)
+	}
+
+	// Check every account paid by the contract can hold the value before
+	// anything is stored.
+	sender := tc.From
+	accounts := make([]Account, 0, len(host.credits))
+	for _, accountID := range host.creditOrder {
+		credit := host.credits[accountID]
+
+		if accountID == sender.AccountID {
+			if sender.Balance, err = addBalance(tc.Rules, sender.Balance, credit); err != nil {
+				return fmt.Errorf("transaction invalid, to balance: %w", err)
+			}
+			continue
+		}
+
+		to := tc.Account(accountID)
+		if to.Balance, err = addBalance(tc.Rules, to.Balance, credit); err != nil {
+			return fmt.Errorf("transaction invalid, to balance: %w", err)
+		}
+		accounts = append(accounts, to)
+	}
+
+	// Only the slots the code changed are updated in the storage tree. The
+	// tree is copied first since the stored contract can't change.
+	trie := contract.storageTrie().Copy()
+	for key, value := range host.dirty {
+		if value.IsZero() {
+			trie.Update(storageKey(key), nil)
+			continue
+		}
+		trie.Update(storageKey(key), value[:])
+	}
+	tc.db.updateStorage(contractID, contract.Storage, host.dirty)
+
+	contract = Contract{
+		Code:    contract.Code,
+		Storage: contract.Storage,
+		trie:    trie,
+	}
+
+	account.Balance = host.balance
+	account.CodeHash = contract.CodeHash()
+	account.StorageRoot = contract.StorageRoot()
+
+	tc.From = sender
+	tc.From.Balance -= tc.Tx.Value
+	tc.SetAccount(account)
+	tc.db.setContract(contractID, contract)
+
+	for _, to := range accounts {
+		tc.SetAccount(to)
+	}
+
+	return nil
+}
+
+// contractHost gives the running code access to the contract's storage and
+// a copy of its balance. The slots the code stores are kept in dirty, with
+// zero for a removed slot, and the storage itself is only read.
+type contractHost struct {
+	address     []byte
+	balance     uint64
+	storage     map[vm.Word]vm.Word
+	dirty       map[vm.Word]vm.Word
+	credits     map[AccountID]uint64
+	creditOrder []AccountID
+}
+
+// Load implements the vm.Host interface.
+func (ch *contractHost) Load(key vm.Word) vm.Word {
+	if value, exists := ch.dirty[key]; exists {
+		return value
+	}
+
+	return ch.storage[key]
+}
+
+// Store implements the vm.Host interface.
+func (ch *contractHost) Store(key vm.Word, value vm.Word) {
+	ch.dirty[key] = value
+}
+
+// Balance implements the vm.Host interface.
+func (ch *contractHost) Balance() uint64 {
+	return ch.balance
+}
+
+// Transfer implements the vm.Host interface.
+func (ch *contractHost) Transfer(to []byte, amount uint64) error {
+	if amount > ch.balance {
+		return fmt.Errorf("contract balance %d is less than the transfer of %d", ch.balance, amount)
+	}
+
+	if bytes.Equal(to, ch.address) {
+		return nil
+	}
+
+	accountID := AccountID(common.BytesToAddress(to).Hex())
+	if _, exists := ch.credits[accountID]; !exists {
+		ch.creditOrder = append(ch.creditOrder, accountID)
+	}
+
+	// The total can't overflow since it's limited by the contract balance.
+	ch.credits[accountID] += amount
+	ch.balance -= amount
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/vm"
+)
+
+// storeOrRevert stores the second word of the call data in slot 1 and
+// reverts when the first word isn't zero.
+const storeOrRevert = `
+	PUSH 32
+	CALLDATALOAD
+	PUSH 1
+	SSTORE          ; storage[1] = data[32:64]
+	PUSH 0
+	CALLDATALOAD
+	PUSH @fail
+	JUMPI           ; revert when data[0:32] != 0
+	STOP
+fail:
+	REVERT
+`
+
+// callData builds the call data for the storeOrRevert contract.
+func callData(revert bool, value uint64) []byte {
+	var flag vm.Word
+	if revert {
+		flag = vm.WordFromUint64(1)
+	}
+	word := vm.WordFromUint64(value)
+
+	return append(flag[:], word[:]...)
+}
+
+func TestContractStorage(t *testing.T) {
+	gen := genesis.Genesis{
+		Date:          time.Date(2021, 12, 17, 0, 0, 0, 0, time.UTC),
+		ChainID:       1,
+		TransPerBlock: 10,
+		Difficulty:    1,
+		MiningReward:  700,
+		GasPrice:      1,
+		Gas:           &genesis.GasSchedule{Base: 10, PerByte: 1, BlockLimit: 1_000_000},
+		Balances:      map[string]uint64{pavel: 1_000_000},
+		Forks: map[string]uint64{
+			genesis.ForkGasMetering:   0,
+			genesis.ForkStateTrie:     0,
+			genesis.ForkAccountReload: 0,
+			genesis.ForkContracts:     0,
+		},
+	}
+	db := newTestDatabase(t, gen)
+
+	code, err := vm.Assemble(storeOrRevert)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to assemble the contract : %s", failed, err)
+	}
+	contractID := database.ContractAccountID(pavel, 1)
+	slot := vm.WordFromUint64(1)
+
+	// apply applies the transaction from pavel in the block.
+	apply := func(number uint64, tx database.Tx) error {
+		tx.ChainID = 1
+		tx.FromID = pavel
+		tx.GasLimit = 10_000
+		block := database.Block{Header: database.BlockHeader{Number: number, BeneficiaryID: kennedy}}
+
+		return db.ApplyTransaction(block, database.NewBlockTx(signTx(t, tx), 1, tx.GasLimit))
+	}
+
+	// stored returns the value of the slot in the current state.
+	stored := func() vm.Word {
+		contract, err := db.Contract(contractID)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to read the contract : %s", failed, err)
+		}
+		return contract.Storage[slot]
+	}
+
+	t.Log("Given the need to keep the storage of a contract only when its code succeeds.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a call stores a value.", testID)
+		{
+			if err := apply(1, database.Tx{Nonce: 1, Type: database.TxTypeCreate, Data: code}); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to deploy the contract : %s", failed, testID, err)
+			}
+			if err := apply(1, database.Tx{Nonce: 2, Type: database.TxTypeCall, ToID: contractID, Data: callData(false, 5)}); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to call the contract : %s", failed, testID, err)
+			}
+			if got := stored(); got != vm.WordFromUint64(5) {
+				t.Fatalf("\t%s\tTest %d:\tShould store the value : got %s", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould store the value.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen a call stores a value and reverts.", testID)
+		{
+			if err := apply(1, database.Tx{Nonce: 3, Type: database.TxTypeCall, ToID: contractID, Data: callData(true, 9)}); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould fail the transaction.", failed, testID)
+			}
+			if got := stored(); got != vm.WordFromUint64(5) {
+				t.Fatalf("\t%s\tTest %d:\tShould throw away the stored value : got %s", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould throw away the stored value.", success, testID)
+		}
+
+		testID = 2
+		t.Logf("\tTest %d:\tWhen a call changes a slot after the block was committed.", testID)
+		{
+			db.UpdateLatestBlock(database.Block{Header: database.BlockHeader{Number: 1}})
+
+			if err := apply(2, database.Tx{Nonce: 4, Type: database.TxTypeCall, ToID: contractID, Data: callData(false, 11)}); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to call the contract : %s", failed, testID, err)
+			}
+			if got := stored(); got != vm.WordFromUint64(11) {
+				t.Fatalf("\t%s\tTest %d:\tShould store the value : got %s", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould store the value.", success, testID)
+
+			chunk, err := db.SnapshotChunk(1, 0)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to read the committed snapshot : %s", failed, testID, err)
+			}
+
+			var found bool
+			for _, cs := range chunk.Contracts {
+				if cs.AccountID != contractID {
+					continue
+				}
+				found = true
+				if len(cs.Storage) != 1 || cs.Storage[0].Key != slot || cs.Storage[0].Value != vm.WordFromUint64(5) {
+					t.Fatalf("\t%s\tTest %d:\tShould keep the committed value in the snapshot : got %v", failed, testID, cs.Storage)
+				}
+			}
+			if !found {
+				t.Fatalf("\t%s\tTest %d:\tShould have the contract in the snapshot.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould keep the committed value in the snapshot.", success, testID)
+		}
+	}
+}
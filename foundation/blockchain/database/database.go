@@ -3,19 +3,34 @@
 package database
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"sort"
+	"math/big"
 	"sync"
 
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/amount"
 	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
-	"github.com/PhyoYazar/blockchain/foundation/blockchain/signature"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/smt"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/vm"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
+// ErrNotFound is returned when a requested value doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrPruned is returned when the transactions of a requested block have been
+// deleted by a pruned node. The block header is still available.
+var ErrPruned = errors.New("pruned")
+
 // Storage interface represents the behavior required to be implemented by any
 // package providing support for reading and writing the blockchain.
 type Storage interface {
 	Write(blockData BlockData) error
 	GetBlock(num uint64) (BlockData, error)
+	Prune(num uint64) error
+	WriteSnapshot(snapshot Snapshot) error
+	GetSnapshot() (Snapshot, error)
 	ForEach() Iterator
 	Close() error
 }
@@ -35,47 +50,165 @@ type Database struct {
 	genesis     genesis.Genesis
 	latestBlock Block
 	accounts    map[AccountID]Account
+	contracts   map[AccountID]Contract
+	trie        *smt.Tree
+	txIndex     map[string]uint64
+	blooms      map[uint64]Bloom
 	storage     Storage
+
+	// The state the latest block header commits to, so account proofs can
+	// be verified by light clients that only have the block headers. Only
+	// the accounts and contracts changed since the latest block was set are
+	// kept, with the values they had then. A nil value means the account or
+	// contract didn't exist.
+	committedTrie      *smt.Tree
+	committedAccounts  map[AccountID]*Account
+	committedContracts map[AccountID]*Contract
+
+	// The storage of a contract is changed in place, so the value each
+	// changed slot had is kept by contract, with zero for an empty slot.
+	committedSlots map[AccountID]map[vm.Word]vm.Word
+
+	// The lock accounts by the block their lock expires at and the settled
+	// lock accounts by the block they are removed from the state at.
+	expiries *dueIndex
+	removals *dueIndex
+
+	// The accounts with time locks by the block their first one unlocks at.
+	unlocks *dueIndex
+
+	// The totals of the balances and the time locks of the accounts, so the
+	// supply doesn't have to add up every account.
+	circulating *big.Int
+	locked      *big.Int
+
+	// The highest block number whose transactions have been pruned.
+	prunedTo uint64
+
+	// The snapshots peers are downloading the chunks of, oldest first.
+	pinMu  sync.Mutex
+	pinned []Snapshot
 }
 
 // New constructs a new database and applies account genesis information and
 // reads/writes the blockchain database on disk if a dbPath is provided.
 func New(genesis genesis.Genesis, storage Storage, evHandler func(v string, args ...any)) (*Database, error) {
 	db := Database{
-		genesis:  genesis,
-		accounts: make(map[AccountID]Account),
-		storage:  storage,
+		genesis:   genesis,
+		accounts:  make(map[AccountID]Account),
+		contracts: make(map[AccountID]Contract),
+		trie:      smt.NewTree(),
+		txIndex:   make(map[string]uint64),
+		blooms:    make(map[uint64]Bloom),
+		storage:   storage,
+
+		committedTrie:      smt.NewTree(),
+		committedAccounts:  make(map[AccountID]*Account),
+		committedContracts: make(map[AccountID]*Contract),
+		committedSlots:     make(map[AccountID]map[vm.Word]vm.Word),
+
+		expiries: newDueIndex(),
+		removals: newDueIndex(),
+		unlocks:  newDueIndex(),
+
+		circulating: new(big.Int),
+		locked:      new(big.Int),
+	}
+
+	// A pruned node keeps a snapshot of the account state since the pruned
+	// blocks can't be replayed.
+	snapshot, err := storage.GetSnapshot()
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
 	}
 
 	// Update the database with account balance information from genesis.
 	for accountStr, balance := range genesis.Balances {
-		accountID, err := ToAccountID(accountStr)
+		accountID, err := genesisAccountID(genesis, accountStr)
 		if err != nil {
 			return nil, err
 		}
-		db.accounts[accountID] = newAccount(accountID, balance)
+		db.setAccount(accountID, newAccount(accountID, balance))
+	}
+
+	// Lock the vesting balances from genesis until their block is mined.
+	for _, vesting := range genesis.Vesting {
+		accountID, err := genesisAccountID(genesis, vesting.Account)
+		if err != nil {
+			return nil, err
+		}
+
+		account := db.account(accountID)
+		if err := account.lockBalance(genesis.Rules(0), vesting.Amount, vesting.UnlockAt); err != nil {
+			return nil, fmt.Errorf("vesting %s: %w", accountID, err)
+		}
+		db.setAccount(accountID, account)
 	}
 
 	// Read all the blocks from storage.
 	iter := db.ForEach()
 	for block, err := iter.Next(); !iter.Done(); block, err = iter.Next() {
-		if err != nil {
+		pruned := errors.Is(err, ErrPruned)
+		if err != nil && !pruned {
 			return nil, err
 		}
 
+		if pruned {
+			db.prunedTo = block.Header.Number
+		}
+
+		// The blocks before the snapshot only need their headers validated
+		// since the snapshot already holds the state they produced.
+		if block.Header.Number < snapshot.BlockNumber {
+			if err := block.Header.ValidateHeader(db.genesis, db.latestBlock.Header, evHandler); err != nil {
+				return nil, err
+			}
+			db.UpdateLatestBlock(block)
+			continue
+		}
+
+		if pruned {
+			return nil, fmt.Errorf("block %d: %w, the state snapshot is for block %d", block.Header.Number, ErrPruned, snapshot.BlockNumber)
+		}
+
+		if block.Header.Number == snapshot.BlockNumber {
+			evHandler("database: New: restoring state snapshot: blk[%d]", snapshot.BlockNumber)
+			db.restoreSnapshot(snapshot)
+		}
+
 		// Validate the block values and cryptographic audit trail.
-		if err := block.ValidateBlock(db.latestBlock, db.HashState(), evHandler); err != nil {
+		if err := block.ValidateBlock(db.genesis, db.latestBlock, db.HashState(block.Header.Number), evHandler); err != nil {
 			return nil, err
 		}
 
+		// Update the current latest block.
+		db.UpdateLatestBlock(block)
+
 		// Update the database with the transaction information.
 		for _, tx := range block.MerkleTree.Values() {
 			db.ApplyTransaction(block, tx)
 		}
-		db.ApplyMiningReward(block)
+		if err := db.ApplyMiningReward(block); err != nil {
+			evHandler("database: New: blk[%d]: WARNING: %s", block.Header.Number, err)
+		}
+		if err := db.ExpireLocks(block); err != nil {
+			evHandler("database: New: blk[%d]: WARNING: %s", block.Header.Number, err)
+		}
+		if err := db.UnlockBalances(block); err != nil {
+			evHandler("database: New: blk[%d]: WARNING: %s", block.Header.Number, err)
+		}
+	}
 
-		// Update the current latest block.
-		db.latestBlock = block
+	switch {
+	case db.latestBlock.Header.Number+1 == snapshot.BlockNumber:
+
+		// The snapshot was installed from a peer and the block at the
+		// snapshot hasn't been downloaded yet.
+		evHandler("database: New: restoring state snapshot: blk[%d]", snapshot.BlockNumber)
+		db.restoreSnapshot(snapshot)
+
+	case db.latestBlock.Header.Number < snapshot.BlockNumber:
+		return nil, fmt.Errorf("the state snapshot is for block %d, but the chain ends at block %d", snapshot.BlockNumber, db.latestBlock.Header.Number)
 	}
 
 	return &db, nil
@@ -107,8 +240,20 @@ func (db *Database) CopyAccounts() map[AccountID]Account {
 }
 
 // HashState returns a hash based on the contents of the accounts and
-// their balances. This is added to each block and checked by peers.
-func (db *Database) HashState() string {
+// their balances. This is added to each block and checked by peers. The
+// block number identifies the block the hash is for, since the rules decide
+// how the hash is calculated.
+func (db *Database) HashState(blockNumber uint64) string {
+
+	// Once the state trie is active, the root of the trie is the state root.
+	// It's kept up to date as accounts change.
+	if db.genesis.Rules(blockNumber).IsActive(genesis.ForkStateTrie) {
+		db.mu.RLock()
+		defer db.mu.RUnlock()
+
+		return db.trie.RootHex()
+	}
+
 	accounts := make([]Account, 0, len(db.accounts))
 	db.mu.RLock()
 	{
@@ -118,8 +263,7 @@ func (db *Database) HashState() string {
 	}
 	db.mu.RUnlock()
 
-	sort.Sort(byAccount(accounts))
-	return signature.Hash(accounts)
+	return stateRoot(db.genesis.Rules(blockNumber), accounts)
 }
 
 // Write adds a new block to the chain.
@@ -127,23 +271,693 @@ func (db *Database) Write(block Block) error {
 	return db.storage.Write(NewBlockData(block))
 }
 
-// UpdateLatestBlock provides safe access to update the latest block.
+// UpdateLatestBlock provides safe access to update the latest block. The
+// transactions in the block are indexed so they can be found by hash and the
+// header bloom is kept so blocks can be searched by account. This
+// must be called before the transactions of the block are applied, since the
+// current state is kept as the state the block header commits to.
 func (db *Database) UpdateLatestBlock(block Block) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
+	// The trie shares its nodes with the copy, and from here on only the
+	// accounts and contracts that change are kept.
+	db.committedTrie = db.trie.Copy()
+	db.committedAccounts = make(map[AccountID]*Account)
+	db.committedContracts = make(map[AccountID]*Contract)
+	db.committedSlots = make(map[AccountID]map[vm.Word]vm.Word)
+
+	// A pruned block only has its header.
+	if block.MerkleTree != nil {
+		for _, tx := range block.MerkleTree.Values() {
+			hash, err := tx.Hash()
+			if err != nil {
+				continue
+			}
+			db.txIndex[hexutil.Encode(hash)] = block.Header.Number
+		}
+	}
+
+	if len(block.Header.Bloom) > 0 {
+		db.blooms[block.Header.Number] = block.Header.Bloom
+	}
+
 	db.latestBlock = block
 }
 
-// ApplyMiningReward gives the specififed account the mining reward.
-func (db *Database) ApplyMiningReward(block Block) {
+// Prune deletes the transactions of the blocks that are more than the
+// specified depth behind the latest block. The headers are kept. A snapshot
+// of the state the latest block is applied to is stored first, so the node
+// can start without the pruned blocks.
+func (db *Database) Prune(depth uint64) error {
+	if depth == 0 {
+		return errors.New("prune depth must be greater than 0")
+	}
+
+	db.mu.RLock()
+	latest := db.latestBlock.Header
+	committed, contracts := db.committedState()
+	snapshot := newSnapshot(latest, committed, contracts)
+	db.mu.RUnlock()
+
+	if committed == nil {
+		return errStateUnavailable
+	}
+
+	if latest.Number <= depth {
+		return nil
+	}
+
+	if err := db.storage.WriteSnapshot(snapshot); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+
+	pruneTo := latest.Number - depth
+	for number := db.prunedTo + 1; number <= pruneTo; number++ {
+		if err := db.storage.Prune(number); err != nil {
+			return fmt.Errorf("pruning block %d: %w", number, err)
+		}
+		db.prunedTo = number
+	}
+
+	return nil
+}
+
+// SnapshotChunk returns the specified chunk of the snapshot of the state the
+// specified block header commits to. A download starts at the latest block
+// and its snapshot is pinned, so the chunks can still be served after new
+// blocks arrive. Once a snapshot is no longer kept, ErrSnapshotMoved is
+// returned and the peer needs to start over from the latest block.
+func (db *Database) SnapshotChunk(blockNumber uint64, chunk int) (SnapshotChunk, error) {
+	snapshot, err := db.pinnedSnapshot(blockNumber)
+	if err != nil {
+		return SnapshotChunk{}, err
+	}
+
+	chunks := (len(snapshot.Accounts) + SnapshotChunkSize - 1) / SnapshotChunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+
+	if chunk < 0 || chunk >= chunks {
+		return SnapshotChunk{}, fmt.Errorf("snapshot has %d chunks: %w", chunks, ErrNotFound)
+	}
+
+	start := chunk * SnapshotChunkSize
+	end := start + SnapshotChunkSize
+	if end > len(snapshot.Accounts) {
+		end = len(snapshot.Accounts)
+	}
+
+	sc := SnapshotChunk{
+		BlockNumber: snapshot.BlockNumber,
+		StateRoot:   snapshot.StateRoot,
+		Chunk:       chunk,
+		Chunks:      chunks,
+		Accounts:    snapshot.Accounts[start:end],
+	}
+
+	// The contracts travel with the chunk holding their accounts.
+	inChunk := make(map[AccountID]bool, len(sc.Accounts))
+	for _, account := range sc.Accounts {
+		inChunk[account.AccountID] = true
+	}
+	for _, cs := range snapshot.Contracts {
+		if inChunk[cs.AccountID] {
+			sc.Contracts = append(sc.Contracts, cs)
+		}
+	}
+
+	return sc, nil
+}
+
+// pinnedSnapshot returns the snapshot of the specified block. The snapshot of
+// the latest block is built the first time it's requested and kept until
+// newer snapshots replace it.
+func (db *Database) pinnedSnapshot(blockNumber uint64) (Snapshot, error) {
+	db.pinMu.Lock()
+	defer db.pinMu.Unlock()
+
+	for _, snapshot := range db.pinned {
+		if snapshot.BlockNumber == blockNumber {
+			return snapshot, nil
+		}
+	}
+
+	db.mu.RLock()
+	latest := db.latestBlock.Header
+	committed, contracts := db.committedState()
+	snapshot := newSnapshot(latest, committed, contracts)
+	db.mu.RUnlock()
+
+	switch {
+	case latest.Number == 0 || blockNumber > latest.Number:
+		return Snapshot{}, fmt.Errorf("snapshot is only available for the latest block %d: %w", latest.Number, ErrNotFound)
+	case blockNumber < latest.Number:
+		return Snapshot{}, fmt.Errorf("snapshot for block %d isn't kept, the latest block is %d: %w", blockNumber, latest.Number, ErrSnapshotMoved)
+	}
+
+	if committed == nil {
+		return Snapshot{}, errStateUnavailable
+	}
+
+	db.pinned = append(db.pinned, snapshot)
+	if len(db.pinned) > snapshotsPinned {
+		db.pinned = db.pinned[len(db.pinned)-snapshotsPinned:]
+	}
+
+	return snapshot, nil
+}
+
+// InstallSnapshot starts an empty database from a snapshot of a peer's state.
+// The headers must be the chain of block headers up to and including the
+// block the snapshot is for. The headers before the snapshot are stored like
+// pruned blocks, and the blocks from the snapshot forward need to be applied
+// as they are downloaded.
+func (db *Database) InstallSnapshot(headers []BlockHeader, snapshot Snapshot, evHandler func(v string, args ...any)) error {
+	if db.LatestBlock().Header.Number != 0 {
+		return errors.New("a snapshot can only be installed in an empty database")
+	}
+
+	if len(headers) == 0 || headers[len(headers)-1].Number != snapshot.BlockNumber {
+		return fmt.Errorf("headers must end at the snapshot block %d", snapshot.BlockNumber)
+	}
+
+	// Validate the header chain so the state root the snapshot is checked
+	// against can be trusted.
+	var previous BlockHeader
+	for _, header := range headers {
+		if err := header.ValidateHeader(db.genesis, previous, evHandler); err != nil {
+			return fmt.Errorf("block %d: %w", header.Number, err)
+		}
+		previous = header
+	}
+
+	if err := snapshot.Verify(db.genesis, headers[len(headers)-1]); err != nil {
+		return err
+	}
+
+	// Store the headers before the snapshot as pruned blocks. The snapshot is
+	// written last, so a partial install is caught when the node starts.
+	var latest Block
+	for _, header := range headers[:len(headers)-1] {
+		blockData := BlockData{
+			Version: BlockDataVersion,
+			Hash:    header.Hash(),
+			Header:  header,
+		}
+
+		if err := db.storage.Write(blockData); err != nil {
+			return err
+		}
+		if err := db.storage.Prune(header.Number); err != nil {
+			return err
+		}
+
+		latest = Block{Header: header}
+	}
+
+	if err := db.storage.WriteSnapshot(snapshot); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.restoreSnapshot(snapshot)
+	db.latestBlock = latest
+	db.prunedTo = latest.Header.Number
+
+	return nil
+}
+
+// restoreSnapshot replaces the account state with the accounts from the
+// snapshot. The state the latest block header commits to isn't available
+// until the next block is set.
+func (db *Database) restoreSnapshot(snapshot Snapshot) {
+	db.accounts = make(map[AccountID]Account)
+	db.contracts = make(map[AccountID]Contract)
+	db.trie = smt.NewTree()
+	db.expiries = newDueIndex()
+	db.removals = newDueIndex()
+	db.unlocks = newDueIndex()
+	db.circulating = new(big.Int)
+	db.locked = new(big.Int)
+
+	db.committedTrie = nil
+	db.committedAccounts = nil
+	db.committedContracts = nil
+	db.committedSlots = nil
+
+	for _, account := range snapshot.Accounts {
+		db.setAccount(account.AccountID, account)
+	}
+
+	for _, cs := range snapshot.Contracts {
+		db.setContract(cs.AccountID, cs.contract())
+	}
+}
+
+// TxProof returns the transaction with the specified hash along with the
+// block it was mined in and the merkle proof of its inclusion.
+func (db *Database) TxProof(txHash string) (TxProof, error) {
+	leafHash, err := hexutil.Decode(txHash)
+	if err != nil {
+		return TxProof{}, fmt.Errorf("invalid transaction hash: %w", err)
+	}
+
+	db.mu.RLock()
+	blockNumber, exists := db.txIndex[hexutil.Encode(leafHash)]
+	db.mu.RUnlock()
+
+	if !exists {
+		return TxProof{}, ErrNotFound
+	}
+
+	blockData, err := db.storage.GetBlock(blockNumber)
+	if err != nil {
+		return TxProof{}, err
+	}
+
+	block, err := ToBlock(db.genesis, blockData)
+	if err != nil {
+		return TxProof{}, err
+	}
+
+	for _, tx := range block.MerkleTree.Values() {
+		hash, err := tx.Hash()
+		if err != nil {
+			return TxProof{}, err
+		}
+
+		if !bytes.Equal(hash, leafHash) {
+			continue
+		}
+
+		proof, order, err := block.MerkleTree.Proof(tx)
+		if err != nil {
+			return TxProof{}, err
+		}
+
+		txProof := TxProof{
+			Tx:         tx,
+			LeafHash:   leafHash,
+			BlockHash:  block.Hash(),
+			Header:     block.Header,
+			Root:       block.MerkleTree.MerkleRoot,
+			Proof:      proof,
+			ProofOrder: order,
+			Hardened:   block.MerkleTree.Hardened(),
+		}
+
+		return txProof, nil
+	}
+
+	return TxProof{}, ErrNotFound
+}
+
+// TxMultiProof returns the transactions with the specified hashes along with
+// the block they were mined in and a single proof of their inclusion. All the
+// transactions must be mined in the same block.
+func (db *Database) TxMultiProof(txHashes []string) (TxMultiProof, error) {
+	if len(txHashes) == 0 {
+		return TxMultiProof{}, errors.New("no transaction hashes provided")
+	}
+
+	wanted := make(map[string]bool, len(txHashes))
+	var blockNumber uint64
+
+	db.mu.RLock()
+	for i, txHash := range txHashes {
+		leafHash, err := hexutil.Decode(txHash)
+		if err != nil {
+			db.mu.RUnlock()
+			return TxMultiProof{}, fmt.Errorf("invalid transaction hash %q: %w", txHash, err)
+		}
+		key := hexutil.Encode(leafHash)
+
+		number, exists := db.txIndex[key]
+		if !exists {
+			db.mu.RUnlock()
+			return TxMultiProof{}, ErrNotFound
+		}
+
+		if i > 0 && number != blockNumber {
+			db.mu.RUnlock()
+			return TxMultiProof{}, fmt.Errorf("transactions are mined in different blocks, %d and %d", blockNumber, number)
+		}
+
+		blockNumber = number
+		wanted[key] = true
+	}
+	db.mu.RUnlock()
+
+	blockData, err := db.storage.GetBlock(blockNumber)
+	if err != nil {
+		return TxMultiProof{}, err
+	}
+
+	block, err := ToBlock(db.genesis, blockData)
+	if err != nil {
+		return TxMultiProof{}, err
+	}
+
+	values := block.MerkleTree.Values()
+	hashes := make([][]byte, len(values))
+	var trans []BlockTx
+
+	for i, tx := range values {
+		hash, err := tx.Hash()
+		if err != nil {
+			return TxMultiProof{}, err
+		}
+		hashes[i] = hash
+
+		if wanted[hexutil.Encode(hash)] {
+			trans = append(trans, tx)
+		}
+	}
+
+	proof, err := block.MerkleTree.MultiProof(trans)
+	if err != nil {
+		return TxMultiProof{}, err
+	}
+
+	// The proof indices are sorted, so order the transactions to match.
+	txMultiProof := TxMultiProof{
+		Trans:      make([]BlockTx, len(proof.Indices)),
+		LeafHashes: make([][]byte, len(proof.Indices)),
+		BlockHash:  block.Hash(),
+		Header:     block.Header,
+		Root:       block.MerkleTree.MerkleRoot,
+		Proof:      proof,
+		Hardened:   block.MerkleTree.Hardened(),
+	}
+
+	for i, index := range proof.Indices {
+		txMultiProof.Trans[i] = values[index]
+		txMultiProof.LeafHashes[i] = hashes[index]
+	}
+
+	return txMultiProof, nil
+}
+
+// ApplyMiningReward gives the specififed account the mining reward. The
+// reward isn't given if the balance of the account would overflow.
+func (db *Database) ApplyMiningReward(block Block) error {
+	rules := db.genesis.Rules(block.Header.Number)
+
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
 	account := db.accounts[block.Header.BeneficiaryID]
-	account.Balance += block.Header.MiningReward
 
-	db.accounts[block.Header.BeneficiaryID] = account
+	// The reward can't be spent until it matures once the lockedBalances
+	// fork is active.
+	if rules.IsActive(genesis.ForkLockedBalances) && rules.Maturity > 0 {
+		if err := account.lockBalance(rules, block.Header.MiningReward, block.Header.Number+rules.Maturity); err != nil {
+			return fmt.Errorf("mining reward invalid, beneficiary %w", err)
+		}
+
+		db.setAccount(block.Header.BeneficiaryID, account)
+		return nil
+	}
+
+	balance, err := addBalance(rules, account.Balance, block.Header.MiningReward)
+	if err != nil {
+		return fmt.Errorf("mining reward invalid, beneficiary balance: %w", err)
+	}
+	account.Balance = balance
+
+	db.setAccount(block.Header.BeneficiaryID, account)
+
+	return nil
+}
+
+// AccountProof returns the account along with a proof the account is part
+// of the current state trie. If the account doesn't exist, the proof shows
+// the account is not part of the trie.
+func (db *Database) AccountProof(accountID AccountID) (AccountProof, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	ap := AccountProof{
+		BlockNumber: db.latestBlock.Header.Number,
+		StateRoot:   db.trie.RootHex(),
+		Key:         accountKey(accountID),
+	}
+
+	account, exists := db.accounts[accountID]
+	if exists {
+		value, err := account.Encode()
+		if err != nil {
+			return AccountProof{}, err
+		}
+		ap.Account = account
+		ap.Value = value
+	}
+
+	ap.Proof = db.trie.Proof(ap.Key)
+
+	return ap, nil
+}
+
+// Contract returns the code and storage of the specified contract account.
+func (db *Database) Contract(accountID AccountID) (Contract, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	contract, exists := db.contracts[accountID]
+	if !exists {
+		return Contract{}, ErrNotFound
+	}
+	contract.Storage = copyStorage(contract.Storage)
+
+	return contract, nil
+}
+
+// Tokens returns the tokens issued on the chain.
+func (db *Database) Tokens() map[AccountID]Token {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	tokens := make(map[AccountID]Token)
+	for accountID, account := range db.accounts {
+		if token, isToken := account.TokenInfo(); isToken {
+			tokens[accountID] = token
+		}
+	}
+
+	return tokens
+}
+
+// Token returns the specified token.
+func (db *Database) Token(accountID AccountID) (Token, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	token, isToken := db.accounts[accountID].TokenInfo()
+	if !isToken {
+		return Token{}, ErrNotFound
+	}
+
+	return token, nil
+}
+
+// Supply represents the amount issued by the genesis and the mining rewards
+// and the part of it the accounts can spend.
+type Supply struct {
+	BlockNumber uint64 `json:"block_number"`
+	Reward      uint64 `json:"reward"`               // Mining reward of the next block.
+	Issued      uint64 `json:"issued"`               // Allocated by the genesis plus the mining rewards so far.
+	MaxSupply   uint64 `json:"max_supply,omitempty"` // Most the genesis and the rewards can ever issue.
+	Circulating uint64 `json:"circulating"`          // Held in the balances of the accounts.
+	Locked      uint64 `json:"locked"`               // Held in time locks that can't be spent yet.
+}
+
+// Supply returns the supply as of the latest block. The issued amount can be
+// more than the circulating and locked amounts together since base fees
+// without a treasury are burned.
+func (db *Database) Supply() (Supply, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	number := db.latestBlock.Header.Number
+
+	supply := Supply{
+		BlockNumber: number,
+		Reward:      db.genesis.Rules(number + 1).MiningReward,
+		Issued:      db.genesis.Issued(number),
+	}
+	if db.genesis.Rewards != nil {
+		supply.MaxSupply = db.genesis.Rewards.MaxSupply
+	}
+
+	if !db.circulating.IsUint64() {
+		return Supply{}, fmt.Errorf("circulating supply: %w", amount.ErrOverflow)
+	}
+	supply.Circulating = db.circulating.Uint64()
+
+	if !db.locked.IsUint64() {
+		return Supply{}, fmt.Errorf("locked supply: %w", amount.ErrOverflow)
+	}
+	supply.Locked = db.locked.Uint64()
+
+	return supply, nil
+}
+
+// updateSupply replaces the balance and the time locks of the previous value
+// of an account in the supply totals with the ones of its new value. The
+// caller must hold the write lock.
+func (db *Database) updateSupply(previous Account, account Account) {
+	db.circulating.Sub(db.circulating, new(big.Int).SetUint64(previous.Balance))
+	db.circulating.Add(db.circulating, new(big.Int).SetUint64(account.Balance))
+
+	if previous.Locked != account.Locked {
+		db.locked.Sub(db.locked, lockedAmount(previous))
+		db.locked.Add(db.locked, lockedAmount(account))
+	}
+}
+
+// lockedAmount returns the amount the account holds in time locks. Time locks
+// that can't be read count as nothing, the failure is reported when they are
+// due to unlock.
+func lockedAmount(account Account) *big.Int {
+	total := new(big.Int)
+
+	locks, err := account.TimeLocks()
+	if err != nil {
+		return total
+	}
+
+	for _, lock := range locks {
+		total.Add(total, new(big.Int).SetUint64(lock.Amount))
+	}
+
+	return total
+}
+
+// CommittedAccountProof returns the account along with a proof the account
+// is part of the state the latest block header commits to. Unlike the proof
+// for the current state, this proof can be verified right away by a client
+// that only has the block headers.
+func (db *Database) CommittedAccountProof(accountID AccountID) (AccountProof, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	header := db.latestBlock.Header
+	if header.Number == 0 {
+		return AccountProof{}, errors.New("no blocks have been mined")
+	}
+
+	if !db.genesis.Rules(header.Number).IsActive(genesis.ForkStateTrie) {
+		return AccountProof{}, fmt.Errorf("block %d state root is not a state trie root", header.Number)
+	}
+
+	if db.committedTrie == nil {
+		return AccountProof{}, errStateUnavailable
+	}
+
+	ap := AccountProof{
+		BlockNumber: header.Number - 1,
+		StateRoot:   header.StateRoot,
+		Key:         accountKey(accountID),
+	}
+
+	account, exists := db.committedAccount(accountID)
+	if exists {
+		value, err := account.Encode()
+		if err != nil {
+			return AccountProof{}, err
+		}
+		ap.Account = account
+		ap.Value = value
+	}
+
+	ap.Proof = db.committedTrie.Proof(ap.Key)
+
+	return ap, nil
+}
+
+// Headers returns the block headers for the specified range of block numbers.
+// The range is capped at the latest block.
+func (db *Database) Headers(from uint64, to uint64) ([]BlockHeader, error) {
+	if latest := db.LatestBlock().Header.Number; to > latest {
+		to = latest
+	}
+
+	var headers []BlockHeader
+	for number := from; number <= to; number++ {
+		blockData, err := db.storage.GetBlock(number)
+		if err != nil && !errors.Is(err, ErrPruned) {
+			return nil, err
+		}
+		headers = append(headers, blockData.Header)
+	}
+
+	return headers, nil
+}
+
+// Blocks returns the blocks for the specified range of block numbers. The
+// range is capped at the latest block. The ErrPruned error is returned when
+// a block in the range has been pruned.
+func (db *Database) Blocks(from uint64, to uint64) ([]BlockData, error) {
+	if latest := db.LatestBlock().Header.Number; to > latest {
+		to = latest
+	}
+
+	var blocks []BlockData
+	for number := from; number <= to; number++ {
+		blockData, err := db.storage.GetBlock(number)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, blockData)
+	}
+
+	return blocks, nil
+}
+
+// AccountBlocks returns the blocks in the specified range that have a
+// transaction from or to the specified account. The range is capped at the
+// latest block. Only the blocks whose header bloom matches the account are
+// read from storage. The ErrPruned error is returned when a matching block
+// has been pruned.
+func (db *Database) AccountBlocks(accountID AccountID, from uint64, to uint64) ([]BlockData, error) {
+	if latest := db.LatestBlock().Header.Number; to > latest {
+		to = latest
+	}
+	if from == 0 {
+		from = 1
+	}
+
+	var blocks []BlockData
+	for number := from; number <= to; number++ {
+		db.mu.RLock()
+		bloom := db.blooms[number]
+		db.mu.RUnlock()
+
+		// Blocks mined before the headerBloom fork have no bloom and always
+		// need to be read.
+		if !bloom.Test(accountID) {
+			continue
+		}
+
+		blockData, err := db.storage.GetBlock(number)
+		if err != nil {
+			return nil, err
+		}
+
+		// The bloom can match an account that isn't in the block.
+		for _, tx := range blockData.Trans {
+			if tx.HasAccount(accountID) {
+				blocks = append(blocks, blockData)
+				break
+			}
+		}
+	}
+
+	return blocks, nil
 }
 
 // ApplyTransaction performs the business logic for applying a transaction
@@ -156,82 +970,459 @@ func (db *Database) ApplyTransaction(block Block, tx BlockTx) error {
 		return fmt.Errorf("invalid signature, %s", err)
 	}
 
+	// Capture the consensus rules that apply to this block.
+	rules := db.genesis.Rules(block.Header.Number)
+
+	// A fee payer must have signed the transaction before it can be charged.
+	if err := tx.validateFeePayer(rules); err != nil {
+		return fmt.Errorf("invalid signature, %s", err)
+	}
+
+	// A transaction outside its validity window isn't charged either, since
+	// the sender didn't agree to pay for it in this block.
+	if err := tx.validateWindow(rules); err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+	if err := tx.checkWindow(block.Header.Number); err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+
 	db.mu.Lock()
 	defer db.mu.Unlock()
 	{
+		// A transaction from a multisig account is signed by its signers.
+		if fromID != tx.FromID && rules.IsActive(genesis.ForkMultisig) {
+			if err := tx.validateSigners(db.account(tx.FromID)); err != nil {
+				return fmt.Errorf("invalid signature, %s", err)
+			}
+			fromID = tx.FromID
+		}
+
 		// Capture these accounts from the database.
 		from, exists := db.accounts[fromID]
 		if !exists {
 			from = newAccount(fromID, 0)
 		}
 
-		to, exists := db.accounts[tx.ToID]
-		if !exists {
-			to = newAccount(tx.ToID, 0)
+		// Before the accountReload fork the beneficiary is paid on this copy,
+		// which is written over any change the transaction makes to it as the
+		// sender or the receiver.
+		reload := rules.IsActive(genesis.ForkAccountReload)
+		bnfc := db.account(block.Header.BeneficiaryID)
+
+		// From the contracts fork on, a transaction whose nonce was already
+		// used isn't charged, so a signed transaction can only be paid for
+		// once.
+		if rules.IsActive(genesis.ForkContracts) && tx.Nonce <= from.Nonce {
+			return fmt.Errorf("transaction invalid, nonce too small, current %d, provided %d", from.Nonce, tx.Nonce)
 		}
 
-		bnfc, exists := db.accounts[block.Header.BeneficiaryID]
-		if !exists {
-			bnfc = newAccount(block.Header.BeneficiaryID, 0)
+		// The gas and tip are paid by the fee payer of a sponsored
+		// transaction and by the sender otherwise.
+		payerID := tx.PayerID()
+		payer := from
+		if payerID != fromID {
+			payer = db.account(payerID)
 		}
 
 		// The account needs to pay the gas fee regardless. Take the
 		// remaining balance if the account doesn't hold enough for the
 		// full amount of gas. This is the only way to stop bad actors.
-		gasFee := tx.GasPrice * tx.GasUnits
-		if gasFee > from.Balance {
-			gasFee = from.Balance
+		gasPrice := effectiveGasPrice(rules, block.Header.BaseFee, tx)
+		gasFee, err := mulBalance(rules, gasPrice, tx.GasUnits)
+		if err != nil || gasFee > payer.Balance {
+			gasFee = payer.Balance
+		}
+
+		payer.Balance -= gasFee
+		db.setAccount(payerID, payer)
+
+		// The gas fee of a transaction that runs contract code is held until
+		// the code has run, since the fee for the gas it didn't use is
+		// refunded to the payer.
+		_, executes := txHandlers[tx.Type].(txExecutor)
+		if !executes {
+			switch {
+			case reload || rules.IsActive(genesis.ForkBaseFee):
+				if err := db.payGasFee(rules, block.Header.BeneficiaryID, gasFee); err != nil {
+					return fmt.Errorf("transaction invalid, %w", err)
+				}
+
+			default:
+				if bnfc.Balance, err = addBalance(rules, bnfc.Balance, gasFee); err != nil {
+					return fmt.Errorf("transaction invalid, beneficiary balance: %w", err)
+				}
+				db.setAccount(block.Header.BeneficiaryID, bnfc)
+			}
 		}
-		from.Balance -= gasFee
-		bnfc.Balance += gasFee
 
-		// Make sure these changes get applied.
-		db.accounts[fromID] = from
-		db.accounts[block.Header.BeneficiaryID] = bnfc
+		// settleGas pays the held gas fee for the gas used and refunds the
+		// rest to the payer.
+		gasUsed := tx.GasUnits
+		settleGas := func() error {
+			if !executes {
+				return nil
+			}
+
+			usedFee, err := mulBalance(rules, gasPrice, gasUsed)
+			if err != nil || usedFee > gasFee {
+				usedFee = gasFee
+			}
+
+			payer := db.account(payerID)
+			if payer.Balance, err = addBalance(rules, payer.Balance, gasFee-usedFee); err != nil {
+				return fmt.Errorf("transaction invalid, gas refund: %w", err)
+			}
+			db.setAccount(payerID, payer)
+
+			if err := db.payGasFee(rules, block.Header.BeneficiaryID, usedFee); err != nil {
+				return fmt.Errorf("transaction invalid, %w", err)
+			}
+
+			return nil
+		}
+
+		// The sender and the payer can be the beneficiary or the treasury,
+		// so both are reloaded. Before the accountReload fork the sender
+		// keeps its copy, charged for gas when it's the payer.
+		switch {
+		case reload:
+			from = db.account(fromID)
+			payer = db.account(payerID)
+
+		case payerID == fromID:
+			from = payer
+		}
+
+		// From the contracts fork on, a transaction that fails after it was
+		// charged for gas still uses its nonce. Otherwise the same signed
+		// transaction could be mined again and charged again.
+		failed := func(err error) error {
+			if rules.IsActive(genesis.ForkContracts) {
+				from := db.account(fromID)
+				if tx.Nonce > from.Nonce {
+					from.Nonce = tx.Nonce
+					db.setAccount(fromID, from)
+				}
+			}
+
+			if gasErr := settleGas(); gasErr != nil {
+				return gasErr
+			}
+
+			return err
+		}
 
 		// Perform basic accounting checks.
 		{
-			if tx.ChainID != db.genesis.ChainID {
-				return fmt.Errorf("transaction invalid, wrong chain id, got %d, exp %d", tx.ChainID, db.genesis.ChainID)
+			if tx.ChainID != rules.ChainID {
+				return failed(fmt.Errorf("transaction invalid, wrong chain id, got %d, exp %d", tx.ChainID, rules.ChainID))
 			}
 
-			if fromID == tx.ToID {
-				return fmt.Errorf("transaction invalid, sending money to yourself, from %s, to %s", fromID, tx.ToID)
+			if err := tx.validateEncoding(rules); err != nil {
+				return failed(fmt.Errorf("transaction invalid, %w", err))
+			}
+
+			// The payload is checked against the account that signed it.
+			payload := tx.Tx
+			payload.FromID = fromID
+			if err := payload.validateType(rules); err != nil {
+				return failed(fmt.Errorf("transaction invalid, %w", err))
 			}
 
 			if tx.Nonce <= from.Nonce {
 				return fmt.Errorf("transaction invalid, nonce too small, current %d, provided %d", from.Nonce, tx.Nonce)
 			}
 
-			if from.Balance == 0 || from.Balance < (tx.Value+tx.Tip) {
-				return fmt.Errorf("transaction invalid, insufficient funds, bal %d, needed %d", from.Balance, (tx.Value + tx.Tip))
+			needed, err := addBalance(rules, tx.Value, tx.Tip)
+			if err != nil {
+				return failed(fmt.Errorf("transaction invalid, value plus tip: %w", err))
+			}
+
+			switch {
+			case payerID == fromID:
+				if from.Balance == 0 || from.Balance < needed {
+					return failed(fmt.Errorf("transaction invalid, insufficient funds, bal %d, needed %d", from.Balance, needed))
+				}
+
+			default:
+				if from.Balance < tx.Value {
+					return failed(fmt.Errorf("transaction invalid, insufficient funds, bal %d, needed %d", from.Balance, tx.Value))
+				}
+
+				if payer.Balance < tx.Tip {
+					return failed(fmt.Errorf("transaction invalid, fee payer insufficient funds, bal %d, needed %d", payer.Balance, tx.Tip))
+				}
 			}
 		}
 
-		// Update the balances between the two parties.
-		from.Balance -= tx.Value
-		to.Balance += tx.Value
+		// Apply the effects of the type of transaction.
+		tc := TxContext{
+			Rules:   rules,
+			Header:  block.Header,
+			Tx:      tx,
+			From:    from,
+			GasUsed: gasUsed,
+			db:      db,
+		}
+		err = txHandlers[tx.Type].Apply(&tc)
+		gasUsed = tc.GasUsed
+		if err != nil {
+			return failed(err)
+		}
+		from = tc.From
+
+		// Take the tip. The fee payer is reloaded since the transaction could
+		// have sent it value.
+		switch {
+		case payerID == fromID:
+			from.Balance -= tx.Tip
 
-		// Give the beneficiary the tip.
-		from.Balance -= tx.Tip
-		bnfc.Balance += tx.Tip
+		default:
+			payer = db.account(payerID)
+			payer.Balance -= tx.Tip
+		}
 
 		// Update the nonce for the next transaction check.
 		from.Nonce = tx.Nonce
 
 		// Update the final changes to these accounts.
-		db.accounts[fromID] = from
-		db.accounts[tx.ToID] = to
-		db.accounts[block.Header.BeneficiaryID] = bnfc
+		db.setAccount(fromID, from)
+		if payerID != fromID {
+			db.setAccount(payerID, payer)
+		}
+
+		if err := settleGas(); err != nil {
+			return err
+		}
+
+		// Give the beneficiary the tip. The beneficiary is reloaded since the
+		// transaction could have paid it or it could be the sender or the fee
+		// payer that was just charged.
+		if reload {
+			bnfc = db.account(block.Header.BeneficiaryID)
+		}
+		bnfc.Balance, err = addBalance(rules, bnfc.Balance, tx.Tip)
+		if err != nil {
+			return fmt.Errorf("transaction invalid, beneficiary balance: %w", err)
+		}
+		db.setAccount(block.Header.BeneficiaryID, bnfc)
+	}
+
+	return nil
+}
+
+// payGasFee pays the gas fee taken from the payer of a transaction. Once the
+// base fee is active the fee is paid to the treasury or burned when there is
+// no treasury, before that it's paid to the beneficiary. The caller must hold
+// the write lock.
+func (db *Database) payGasFee(rules genesis.Rules, beneficiaryID AccountID, gasFee uint64) error {
+	name, accountID := "beneficiary", beneficiaryID
+	if rules.IsActive(genesis.ForkBaseFee) {
+		name, accountID = "treasury", AccountID(rules.Fees.Treasury)
+		if accountID == "" {
+			return nil
+		}
+	}
+
+	account := db.account(accountID)
+
+	balance, err := addBalance(rules, account.Balance, gasFee)
+	if err != nil {
+		return fmt.Errorf("%s balance: %w", name, err)
 	}
+	account.Balance = balance
+
+	db.setAccount(accountID, account)
 
 	return nil
 }
 
+// account returns the specified account or a new account with no balance
+// when it doesn't exist yet.
+func (db *Database) account(accountID AccountID) Account {
+	if account, exists := db.accounts[accountID]; exists {
+		return account
+	}
+
+	return newAccount(accountID, 0)
+}
+
+// setAccount stores the account and updates the state trie. The caller must
+// hold the write lock.
+func (db *Database) setAccount(accountID AccountID, account Account) {
+	db.keepCommitted(accountID)
+
+	previous := db.accounts[accountID]
+	db.accounts[accountID] = account
+
+	if account.HTLC != previous.HTLC {
+		db.indexHTLC(accountID, account)
+	}
+	if account.Locked != previous.Locked {
+		db.indexTimeLocks(accountID, account)
+	}
+	db.updateSupply(previous, account)
+
+	// The contract hashes are always set by the database, so this can't fail.
+	value, err := account.Encode()
+	if err != nil {
+		return
+	}
+	db.trie.Update(accountKey(accountID), value)
+}
+
+// deleteAccount removes the account from the state and the state trie. The
+// caller must hold the write lock.
+func (db *Database) deleteAccount(accountID AccountID) {
+	previous, exists := db.accounts[accountID]
+	if !exists {
+		return
+	}
+
+	db.keepCommitted(accountID)
+
+	delete(db.accounts, accountID)
+	db.indexHTLC(accountID, Account{})
+	db.indexTimeLocks(accountID, Account{})
+	db.updateSupply(previous, Account{})
+
+	db.trie.Update(accountKey(accountID), nil)
+}
+
+// keepCommitted keeps the value the account has in the state the latest
+// block header commits to before it's changed for the first time. The caller
+// must hold the write lock.
+func (db *Database) keepCommitted(accountID AccountID) {
+	if db.committedAccounts == nil {
+		return
+	}
+
+	if _, changed := db.committedAccounts[accountID]; changed {
+		return
+	}
+
+	var committed *Account
+	if current, exists := db.accounts[accountID]; exists {
+		committed = &current
+	}
+	db.committedAccounts[accountID] = committed
+}
+
+// setContract stores the code and storage of the contract account. The
+// caller must hold the write lock.
+func (db *Database) setContract(accountID AccountID, contract Contract) {
+	if db.committedContracts != nil {
+		if _, changed := db.committedContracts[accountID]; !changed {
+			var committed *Contract
+			if current, exists := db.contracts[accountID]; exists {
+				committed = &current
+			}
+			db.committedContracts[accountID] = committed
+		}
+	}
+
+	db.contracts[accountID] = contract
+}
+
+// updateStorage stores the changed slots in the storage of the contract in
+// place, removing the slots set to zero. The value each slot has in the
+// state the latest block commits to is kept first, unless the contract was
+// created after that block. The caller must hold the write lock.
+func (db *Database) updateStorage(accountID AccountID, storage map[vm.Word]vm.Word, slots map[vm.Word]vm.Word) {
+	_, exists := db.contracts[accountID]
+	committed, changed := db.committedContracts[accountID]
+
+	if db.committedSlots != nil && exists && (!changed || committed != nil) {
+		kept, exists := db.committedSlots[accountID]
+		if !exists {
+			kept = make(map[vm.Word]vm.Word)
+			db.committedSlots[accountID] = kept
+		}
+
+		for key := range slots {
+			if _, exists := kept[key]; !exists {
+				kept[key] = storage[key]
+			}
+		}
+	}
+
+	for key, value := range slots {
+		if value.IsZero() {
+			delete(storage, key)
+			continue
+		}
+		storage[key] = value
+	}
+}
+
+// committedAccount returns the account as the latest block header commits
+// to it. The caller must hold the read lock.
+func (db *Database) committedAccount(accountID AccountID) (Account, bool) {
+	if committed, changed := db.committedAccounts[accountID]; changed {
+		if committed == nil {
+			return Account{}, false
+		}
+		return *committed, true
+	}
+
+	account, exists := db.accounts[accountID]
+	return account, exists
+}
+
+// committedState returns the accounts and contracts the latest block header
+// commits to, nil when that state isn't available. The caller must hold the
+// read lock.
+func (db *Database) committedState() (map[AccountID]Account, map[AccountID]Contract) {
+	if db.committedTrie == nil {
+		return nil, nil
+	}
+
+	accounts := make(map[AccountID]Account, len(db.accounts))
+	for accountID, account := range db.accounts {
+		accounts[accountID] = account
+	}
+	for accountID, committed := range db.committedAccounts {
+		switch committed {
+		case nil:
+			delete(accounts, accountID)
+		default:
+			accounts[accountID] = *committed
+		}
+	}
+
+	contracts := make(map[AccountID]Contract, len(db.contracts))
+	for accountID, contract := range db.contracts {
+		contracts[accountID] = contract
+	}
+	for accountID, committed := range db.committedContracts {
+		switch committed {
+		case nil:
+			delete(contracts, accountID)
+		default:
+			contract := *committed
+			contract.Storage = copyStorage(contract.Storage)
+			for key, value := range db.committedSlots[accountID] {
+				if value.IsZero() {
+					delete(contract.Storage, key)
+					continue
+				}
+				contract.Storage[key] = value
+			}
+			contracts[accountID] = contract
+		}
+	}
+
+	return accounts, contracts
+}
+
 // ForEach returns an iterator to walk through all the blocks
 // starting with block number 1.
 func (db *Database) ForEach() DatabaseIterator {
-	return DatabaseIterator{iterator: db.storage.ForEach()}
+	return DatabaseIterator{
+		genesis:  db.genesis,
+		iterator: db.storage.ForEach(),
+	}
 }
 
 // =============================================================================
@@ -239,17 +1430,22 @@ func (db *Database) ForEach() DatabaseIterator {
 // DatabaseIterator provides support for iterating over the blocks in the
 // blockchain database using the configured storage option.
 type DatabaseIterator struct {
+	genesis  genesis.Genesis
 	iterator Iterator
 }
 
-// Next retrieves the next block from disk.
+// Next retrieves the next block from disk. A pruned block is returned with
+// only its header along with the ErrPruned error.
 func (di *DatabaseIterator) Next() (Block, error) {
 	blockData, err := di.iterator.Next()
 	if err != nil {
+		if errors.Is(err, ErrPruned) {
+			return Block{Header: blockData.Header}, err
+		}
 		return Block{}, err
 	}
 
-	return ToBlock(blockData)
+	return ToBlock(di.genesis, blockData)
 }
 
 // Done returns the end of chain value.
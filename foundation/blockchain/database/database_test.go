@@ -0,0 +1,187 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// signTx signs the transaction with the private key of the pavel account.
+func signTx(t *testing.T, tx database.Tx) database.SignedTx {
+	privateKey, err := crypto.HexToECDSA(testPrivateKey)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to load the private key : %s", failed, err)
+	}
+
+	signedTx, err := tx.Sign(privateKey)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to sign the transaction : %s", failed, err)
+	}
+
+	return signedTx
+}
+
+func TestApplyTransactionBeneficiary(t *testing.T) {
+	type balances struct {
+		pavel   uint64
+		kennedy uint64
+		nonce   uint64
+	}
+
+	tt := []struct {
+		name        string
+		forks       map[string]uint64
+		beneficiary database.AccountID
+		exp         balances
+	}{
+		{
+			name:        "the sender is the beneficiary before the accountReload fork",
+			beneficiary: pavel,
+			exp:         balances{pavel: 1020, kennedy: 1100, nonce: 0},
+		},
+		{
+			name:        "the receiver is the beneficiary before the accountReload fork",
+			beneficiary: kennedy,
+			exp:         balances{pavel: 880, kennedy: 1020, nonce: 1},
+		},
+		{
+			name:        "the sender is the beneficiary once the accountReload fork is active",
+			forks:       map[string]uint64{genesis.ForkAccountReload: 1},
+			beneficiary: pavel,
+			exp:         balances{pavel: 900, kennedy: 1100, nonce: 1},
+		},
+		{
+			name:        "the receiver is the beneficiary once the accountReload fork is active",
+			forks:       map[string]uint64{genesis.ForkAccountReload: 1},
+			beneficiary: kennedy,
+			exp:         balances{pavel: 880, kennedy: 1120, nonce: 1},
+		},
+	}
+
+	t.Log("Given the need to apply a transaction the beneficiary takes part in the same on every node.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen %s.", testID, test.name)
+			{
+				gen := genesis.Genesis{
+					Date:          time.Date(2021, 12, 17, 0, 0, 0, 0, time.UTC),
+					ChainID:       1,
+					TransPerBlock: 10,
+					Difficulty:    1,
+					MiningReward:  700,
+					GasPrice:      15,
+					Balances:      map[string]uint64{pavel: 1000, kennedy: 1000},
+					Forks:         test.forks,
+				}
+				db := newTestDatabase(t, gen)
+
+				tx := database.Tx{ChainID: 1, Nonce: 1, FromID: pavel, ToID: kennedy, Value: 100, Tip: 5}
+				block := database.Block{Header: database.BlockHeader{Number: 1, BeneficiaryID: test.beneficiary}}
+
+				if err := db.ApplyTransaction(block, database.NewBlockTx(signTx(t, tx), 15, 1)); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to apply the transaction : %s", failed, testID, err)
+				}
+
+				accounts := db.CopyAccounts()
+				got := balances{
+					pavel:   accounts[pavel].Balance,
+					kennedy: accounts[kennedy].Balance,
+					nonce:   accounts[pavel].Nonce,
+				}
+				if got != test.exp {
+					t.Fatalf("\t%s\tTest %d:\tShould leave the expected balances : got %+v, exp %+v", failed, testID, got, test.exp)
+				}
+				t.Logf("\t%s\tTest %d:\tShould leave the expected balances.", success, testID)
+			}
+		}
+	}
+}
+
+func TestSupply(t *testing.T) {
+	type supply struct {
+		reward      uint64
+		issued      uint64
+		circulating uint64
+		locked      uint64
+	}
+
+	tt := []struct {
+		name     string
+		maturity uint64
+		forks    map[string]uint64
+		exp      []supply
+	}{
+		{
+			name:  "rewards that reach the max supply",
+			forks: map[string]uint64{genesis.ForkRewardSchedule: 0},
+			exp: []supply{
+				{reward: 700, issued: 1700, circulating: 1700},
+				{reward: 700, issued: 2400, circulating: 2400},
+				{reward: 100, issued: 2500, circulating: 2500},
+				{reward: 0, issued: 2500, circulating: 2500},
+			},
+		},
+		{
+			name:     "rewards that are locked until they mature",
+			maturity: 2,
+			forks:    map[string]uint64{genesis.ForkRewardSchedule: 0, genesis.ForkLockedBalances: 0},
+			exp: []supply{
+				{reward: 700, issued: 1700, circulating: 1000, locked: 700},
+				{reward: 700, issued: 2400, circulating: 1000, locked: 1400},
+				{reward: 100, issued: 2500, circulating: 1700, locked: 800},
+				{reward: 0, issued: 2500, circulating: 2400, locked: 100},
+			},
+		},
+	}
+
+	t.Log("Given the need to keep the running totals of the supply as blocks are mined.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen mining %s.", testID, test.name)
+			{
+				gen := genesis.Genesis{
+					Date:          time.Date(2021, 12, 17, 0, 0, 0, 0, time.UTC),
+					ChainID:       1,
+					TransPerBlock: 10,
+					Difficulty:    1,
+					MiningReward:  700,
+					GasPrice:      15,
+					Maturity:      test.maturity,
+					Balances:      map[string]uint64{pavel: 1000},
+					Rewards:       &genesis.RewardSchedule{MaxSupply: 2500},
+					Forks:         test.forks,
+				}
+				db := newTestDatabase(t, gen)
+
+				for i, exp := range test.exp {
+					number := uint64(i + 1)
+					block := database.Block{Header: database.BlockHeader{Number: number, BeneficiaryID: kennedy, MiningReward: gen.Rules(number).MiningReward}}
+
+					if err := db.ApplyMiningReward(block); err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to apply the reward of block %d : %s", failed, testID, number, err)
+					}
+					if err := db.UnlockBalances(block); err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to unlock the balances of block %d : %s", failed, testID, number, err)
+					}
+					db.UpdateLatestBlock(block)
+
+					s, err := db.Supply()
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to read the supply : %s", failed, testID, err)
+					}
+					got := supply{reward: block.Header.MiningReward, issued: s.Issued, circulating: s.Circulating, locked: s.Locked}
+					if got != exp {
+						t.Fatalf("\t%s\tTest %d:\tShould have the expected supply after block %d : got %+v, exp %+v", failed, testID, number, got, exp)
+					}
+					if s.MaxSupply != 2500 {
+						t.Fatalf("\t%s\tTest %d:\tShould report the max supply : got %d", failed, testID, s.MaxSupply)
+					}
+				}
+				t.Logf("\t%s\tTest %d:\tShould have the expected supply after every block.", success, testID)
+			}
+		}
+	}
+}
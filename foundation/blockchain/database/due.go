@@ -0,0 +1,88 @@
+package database
+
+import (
+	"bytes"
+	"sort"
+)
+
+// dueIndex keeps the accounts that have work due at the end of a block by
+// the block number the work is due at, so the end of a block only looks at
+// the accounts that are due instead of every account in the state.
+type dueIndex struct {
+	numbers  []uint64                          // Block numbers with accounts due, in order.
+	accounts map[uint64]map[AccountID]struct{} // Accounts due at each block number.
+	dueAt    map[AccountID]uint64              // Block number each account is due at.
+}
+
+// newDueIndex constructs an empty index.
+func newDueIndex() *dueIndex {
+	return &dueIndex{
+		accounts: make(map[uint64]map[AccountID]struct{}),
+		dueAt:    make(map[AccountID]uint64),
+	}
+}
+
+// set marks the account as due at the specified block number, replacing the
+// block number it was due at before.
+func (di *dueIndex) set(accountID AccountID, number uint64) {
+	if current, exists := di.dueAt[accountID]; exists {
+		if current == number {
+			return
+		}
+		di.remove(accountID)
+	}
+
+	accounts, exists := di.accounts[number]
+	if !exists {
+		accounts = make(map[AccountID]struct{})
+		di.accounts[number] = accounts
+
+		i := sort.Search(len(di.numbers), func(i int) bool { return di.numbers[i] >= number })
+		di.numbers = append(di.numbers, 0)
+		copy(di.numbers[i+1:], di.numbers[i:])
+		di.numbers[i] = number
+	}
+
+	accounts[accountID] = struct{}{}
+	di.dueAt[accountID] = number
+}
+
+// remove takes the account out of the index.
+func (di *dueIndex) remove(accountID AccountID) {
+	number, exists := di.dueAt[accountID]
+	if !exists {
+		return
+	}
+	delete(di.dueAt, accountID)
+
+	accounts := di.accounts[number]
+	delete(accounts, accountID)
+	if len(accounts) > 0 {
+		return
+	}
+	delete(di.accounts, number)
+
+	i := sort.Search(len(di.numbers), func(i int) bool { return di.numbers[i] >= number })
+	di.numbers = append(di.numbers[:i], di.numbers[i+1:]...)
+}
+
+// due returns the accounts due at or before the specified block number in
+// the order of their addresses, so every node processes them the same way.
+// An account stays due until it's set to another block number or removed.
+func (di *dueIndex) due(number uint64) []AccountID {
+	var accountIDs []AccountID
+	for _, n := range di.numbers {
+		if n > number {
+			break
+		}
+		for accountID := range di.accounts[n] {
+			accountIDs = append(accountIDs, accountID)
+		}
+	}
+
+	sort.Slice(accountIDs, func(i, j int) bool {
+		return bytes.Compare(accountBytes(accountIDs[i]), accountBytes(accountIDs[j])) < 0
+	})
+
+	return accountIDs
+}
@@ -0,0 +1,332 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// CORE NOTE: Hashing and signing the JSON representation of a value ties the
+// result to Go's struct field order, JSON tags and the way *big.Int values are
+// formatted. Any client in another language has to reproduce that output byte
+// for byte. The canonical encoding is an RLP list of the fields in a fixed
+// order, with account ids encoded as their 20 address bytes and hashes as
+// their 32 bytes. It's enabled for block headers by the canonicalEncoding fork
+// and transactions choose it by setting their Encoding field. Test vectors
+// are published in testdata/canonical_vectors.json.
+
+// Encoding identifies how a value is serialized for hashing and signing.
+type Encoding uint8
+
+// Set of encodings that are supported.
+const (
+	EncodingJSON      Encoding = 0
+	EncodingCanonical Encoding = 1
+)
+
+// HeaderEncoding returns the encoding block headers must use under the
+// specified rules.
+func HeaderEncoding(rules genesis.Rules) Encoding {
+	if rules.IsActive(genesis.ForkCanonicalEncoding) {
+		return EncodingCanonical
+	}
+
+	return EncodingJSON
+}
+
+// =============================================================================
+
+// rlpTx is the canonical layout of a transaction.
+type rlpTx struct {
+	Encoding   Encoding
+	ChainID    uint16
+	Nonce      uint64
+	FromID     []byte
+	ToID       []byte
+	Value      uint64
+	Tip        uint64
+	Data       []byte
+	GasLimit   uint64 `rlp:"optional"`
+	MaxFee     uint64 `rlp:"optional"`
+	Type       TxType `rlp:"optional"`
+	FeePayerID []byte `rlp:"optional"`
+	ValidAfter uint64 `rlp:"optional"`
+	ValidUntil uint64 `rlp:"optional"`
+}
+
+// rlpBlockTx is the canonical layout of a transaction recorded in a block.
+type rlpBlockTx struct {
+	Tx        rlpTx
+	V         *big.Int
+	R         *big.Int
+	S         *big.Int
+	TimeStamp uint64
+	GasPrice  uint64
+	GasUnits  uint64
+
+	Signatures []rlpSignature `rlp:"optional"`
+	FeePayer   *rlpSignature  `rlp:"optional"`
+}
+
+// rlpSignature is the canonical layout of an additional signature.
+type rlpSignature struct {
+	V *big.Int
+	R *big.Int
+	S *big.Int
+}
+
+// rlpBlockHeader is the canonical layout of a block header.
+type rlpBlockHeader struct {
+	Encoding      Encoding
+	Number        uint64
+	PrevBlockHash []byte
+	TimeStamp     uint64
+	BeneficiaryID []byte
+	Difficulty    uint16
+	MiningReward  uint64
+	StateRoot     []byte
+	TransRoot     []byte
+	Nonce         uint64
+	Bloom         []byte `rlp:"optional"`
+	GasUsed       uint64 `rlp:"optional"`
+	BaseFee       uint64 `rlp:"optional"`
+}
+
+// rlpAccount is the canonical layout of an account stored in the state trie.
+type rlpAccount struct {
+	AccountID   []byte
+	Nonce       uint64
+	Balance     uint64
+	CodeHash    []byte `rlp:"optional"`
+	StorageRoot []byte `rlp:"optional"`
+	Multisig    []byte `rlp:"optional"`
+	Token       []byte `rlp:"optional"`
+	Tokens      []byte `rlp:"optional"`
+	HTLC        []byte `rlp:"optional"`
+	Locked      []byte `rlp:"optional"`
+}
+
+// =============================================================================
+
+// Encode returns the canonical bytes of the account that are stored as the
+// value of the account's leaf in the state trie.
+func (a Account) Encode() ([]byte, error) {
+	ra := rlpAccount{
+		AccountID: accountBytes(a.AccountID),
+		Nonce:     a.Nonce,
+		Balance:   a.Balance,
+	}
+
+	// Only contract accounts have these, so the encoding of every other
+	// account stays the same.
+	if a.CodeHash != "" {
+		var err error
+		if ra.CodeHash, err = hashBytes(a.CodeHash); err != nil {
+			return nil, fmt.Errorf("code hash: %w", err)
+		}
+		if ra.StorageRoot, err = hashBytes(a.StorageRoot); err != nil {
+			return nil, fmt.Errorf("storage root: %w", err)
+		}
+	}
+
+	if a.Multisig != "" {
+		var err error
+		if ra.Multisig, err = hexutil.Decode(a.Multisig); err != nil {
+			return nil, fmt.Errorf("multisig: %w", err)
+		}
+	}
+
+	if a.Token != "" {
+		var err error
+		if ra.Token, err = hexutil.Decode(a.Token); err != nil {
+			return nil, fmt.Errorf("token: %w", err)
+		}
+	}
+
+	if a.Tokens != "" {
+		var err error
+		if ra.Tokens, err = hexutil.Decode(a.Tokens); err != nil {
+			return nil, fmt.Errorf("token balances: %w", err)
+		}
+	}
+
+	if a.HTLC != "" {
+		var err error
+		if ra.HTLC, err = hexutil.Decode(a.HTLC); err != nil {
+			return nil, fmt.Errorf("htlc: %w", err)
+		}
+	}
+
+	if a.Locked != "" {
+		var err error
+		if ra.Locked, err = hexutil.Decode(a.Locked); err != nil {
+			return nil, fmt.Errorf("locked: %w", err)
+		}
+	}
+
+	return rlp.EncodeToBytes(ra)
+}
+
+// Encode returns the bytes that are hashed and signed for the transaction
+// based on the encoding the transaction has selected.
+func (tx Tx) Encode() ([]byte, error) {
+	switch tx.Encoding {
+	case EncodingJSON:
+		return json.Marshal(tx)
+
+	case EncodingCanonical:
+		return rlp.EncodeToBytes(tx.toRLP())
+	}
+
+	return nil, fmt.Errorf("unknown encoding %d", tx.Encoding)
+}
+
+// Encode returns the bytes that are hashed to produce the merkle leaf for
+// the block transaction.
+func (tx BlockTx) Encode() ([]byte, error) {
+	// The effective gas price is a receipt the block sets, so the hash stays the same
+	// from the mempool to the block.
+	tx.EffectiveGasPrice = 0
+
+	switch tx.Encoding {
+	case EncodingJSON:
+		return json.Marshal(tx)
+
+	case EncodingCanonical:
+		return rlp.EncodeToBytes(rlpBlockTx{
+			Tx:        tx.Tx.toRLP(),
+			V:         tx.V,
+			R:         tx.R,
+			S:         tx.S,
+			TimeStamp: tx.TimeStamp,
+			GasPrice:  tx.GasPrice,
+			GasUnits:  tx.GasUnits,
+
+			Signatures: tx.rlpSignatures(),
+			FeePayer:   tx.rlpFeePayer(),
+		})
+	}
+
+	return nil, fmt.Errorf("unknown encoding %d", tx.Encoding)
+}
+
+// Encode returns the bytes that are hashed to produce the block hash.
+func (bh BlockHeader) Encode() ([]byte, error) {
+	switch bh.Encoding {
+	case EncodingJSON:
+		return json.Marshal(bh)
+
+	case EncodingCanonical:
+		prevBlockHash, err := hashBytes(bh.PrevBlockHash)
+		if err != nil {
+			return nil, fmt.Errorf("prev_block_hash: %w", err)
+		}
+
+		stateRoot, err := hashBytes(bh.StateRoot)
+		if err != nil {
+			return nil, fmt.Errorf("state_root: %w", err)
+		}
+
+		transRoot, err := hashBytes(bh.TransRoot)
+		if err != nil {
+			return nil, fmt.Errorf("trans_root: %w", err)
+		}
+
+		return rlp.EncodeToBytes(rlpBlockHeader{
+			Encoding:      bh.Encoding,
+			Number:        bh.Number,
+			PrevBlockHash: prevBlockHash,
+			TimeStamp:     bh.TimeStamp,
+			BeneficiaryID: accountBytes(bh.BeneficiaryID),
+			Difficulty:    bh.Difficulty,
+			MiningReward:  bh.MiningReward,
+			StateRoot:     stateRoot,
+			TransRoot:     transRoot,
+			Nonce:         bh.Nonce,
+			Bloom:         bh.Bloom,
+			GasUsed:       bh.GasUsed,
+			BaseFee:       bh.BaseFee,
+		})
+	}
+
+	return nil, fmt.Errorf("unknown encoding %d", bh.Encoding)
+}
+
+// =============================================================================
+
+// toRLP converts the transaction into its canonical layout.
+func (tx Tx) toRLP() rlpTx {
+	rt := rlpTx{
+		Encoding: tx.Encoding,
+		ChainID:  tx.ChainID,
+		Nonce:    tx.Nonce,
+		FromID:   accountBytes(tx.FromID),
+		ToID:     accountBytes(tx.ToID),
+		Value:    tx.Value,
+		Tip:      tx.Tip,
+		Data:     tx.Data,
+		GasLimit: tx.GasLimit,
+		MaxFee:   tx.MaxFee,
+		Type:     tx.Type,
+
+		ValidAfter: tx.ValidAfter,
+		ValidUntil: tx.ValidUntil,
+	}
+
+	// Only sponsored transactions have a fee payer, so the encoding of every
+	// other transaction is unchanged.
+	if tx.FeePayerID != "" {
+		rt.FeePayerID = accountBytes(tx.FeePayerID)
+	}
+
+	return rt
+}
+
+// rlpSignatures converts the additional signatures into their canonical
+// layout.
+func (tx BlockTx) rlpSignatures() []rlpSignature {
+	if len(tx.Signatures) == 0 {
+		return nil
+	}
+
+	sigs := make([]rlpSignature, len(tx.Signatures))
+	for i, sig := range tx.Signatures {
+		sigs[i] = rlpSignature{V: sig.V, R: sig.R, S: sig.S}
+	}
+
+	return sigs
+}
+
+// rlpFeePayer converts the signature of the fee payer into its canonical
+// layout.
+func (tx BlockTx) rlpFeePayer() *rlpSignature {
+	if tx.FeePayer == nil {
+		return nil
+	}
+
+	return &rlpSignature{V: tx.FeePayer.V, R: tx.FeePayer.R, S: tx.FeePayer.S}
+}
+
+// accountBytes converts the account id into its 20 address bytes.
+func accountBytes(accountID AccountID) []byte {
+	return common.HexToAddress(string(accountID)).Bytes()
+}
+
+// hashBytes converts a hex-encoded hash into its 32 bytes.
+func hashBytes(hash string) ([]byte, error) {
+	b, err := hexutil.Decode(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) != common.HashLength {
+		return nil, fmt.Errorf("hash must be %d bytes, got %d", common.HashLength, len(b))
+	}
+
+	return b, nil
+}
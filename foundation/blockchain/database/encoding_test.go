@@ -0,0 +1,189 @@
+package database_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/signature"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// vectors represents the published test vectors for the canonical binary
+// encoding, generated by app/tooling/vectors.
+type vectors struct {
+	PrivateKey string `json:"private_key"`
+	Txs        []struct {
+		Description   string           `json:"description"`
+		Tx            database.Tx      `json:"tx"`
+		Encoded       string           `json:"encoded"`
+		Signature     string           `json:"signature"`
+		BlockTx       database.BlockTx `json:"block_tx"`
+		BlockTxEncode string           `json:"block_tx_encoded"`
+		LeafHash      string           `json:"leaf_hash"`
+	} `json:"txs"`
+	Rejected []struct {
+		Description string            `json:"description"`
+		SignedTx    database.SignedTx `json:"signed_tx"`
+		Encoded     string            `json:"encoded"`
+		Reason      string            `json:"reason"`
+	} `json:"rejected"`
+	Headers []struct {
+		Description string               `json:"description"`
+		Header      database.BlockHeader `json:"header"`
+		Encoded     string               `json:"encoded"`
+		Hash        string               `json:"hash"`
+	} `json:"headers"`
+}
+
+func loadVectors(t *testing.T) vectors {
+	data, err := os.ReadFile("testdata/canonical_vectors.json")
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to read the vectors : %s", failed, err)
+	}
+
+	var vs vectors
+	if err := json.Unmarshal(data, &vs); err != nil {
+		t.Fatalf("\t%s\tShould be able to decode the vectors : %s", failed, err)
+	}
+
+	return vs
+}
+
+func TestCanonicalTxVectors(t *testing.T) {
+	vs := loadVectors(t)
+
+	privateKey, err := crypto.HexToECDSA(vs.PrivateKey)
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to load the private key : %s", failed, err)
+	}
+
+	t.Log("Given the need to encode transactions the same as the published vectors.")
+	{
+		for testID, v := range vs.Txs {
+			t.Logf("\tTest %d:\tWhen handling the %s vector.", testID, v.Description)
+			{
+				encoded, err := v.Tx.Encode()
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to encode the transaction : %s", failed, testID, err)
+				}
+				if got := "0x" + hex.EncodeToString(encoded); got != v.Encoded {
+					t.Fatalf("\t%s\tTest %d:\tShould encode the transaction to the published bytes : got %s, exp %s", failed, testID, got, v.Encoded)
+				}
+				t.Logf("\t%s\tTest %d:\tShould encode the transaction to the published bytes.", success, testID)
+
+				signedTx, err := v.Tx.Sign(privateKey)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to sign the transaction : %s", failed, testID, err)
+				}
+				if got := signedTx.SignatureString(); got != v.Signature {
+					t.Fatalf("\t%s\tTest %d:\tShould sign the transaction with the published signature : got %s, exp %s", failed, testID, got, v.Signature)
+				}
+				t.Logf("\t%s\tTest %d:\tShould sign the transaction with the published signature.", success, testID)
+
+				blockTxEncoded, err := v.BlockTx.Encode()
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to encode the block transaction : %s", failed, testID, err)
+				}
+				if got := "0x" + hex.EncodeToString(blockTxEncoded); got != v.BlockTxEncode {
+					t.Fatalf("\t%s\tTest %d:\tShould encode the block transaction to the published bytes : got %s, exp %s", failed, testID, got, v.BlockTxEncode)
+				}
+				t.Logf("\t%s\tTest %d:\tShould encode the block transaction to the published bytes.", success, testID)
+
+				leafHash, err := v.BlockTx.Hash()
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to hash the block transaction : %s", failed, testID, err)
+				}
+				if got := "0x" + hex.EncodeToString(leafHash); got != v.LeafHash {
+					t.Fatalf("\t%s\tTest %d:\tShould hash the block transaction to the published leaf hash : got %s, exp %s", failed, testID, got, v.LeafHash)
+				}
+				t.Logf("\t%s\tTest %d:\tShould hash the block transaction to the published leaf hash.", success, testID)
+			}
+		}
+	}
+}
+
+func TestCanonicalRejectedVectors(t *testing.T) {
+	vs := loadVectors(t)
+
+	gen := genesis.Genesis{
+		ChainID: 1,
+		Forks:   map[string]uint64{genesis.ForkCanonicalEncoding: 0},
+	}
+	rules := gen.Rules(1)
+
+	t.Log("Given the need to reject transactions that only differ from a signed one in ways the encoding doesn't cover.")
+	{
+		for testID, v := range vs.Rejected {
+			t.Logf("\tTest %d:\tWhen handling the %s vector.", testID, v.Description)
+			{
+				encoded, err := v.SignedTx.Tx.Encode()
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to encode the transaction : %s", failed, testID, err)
+				}
+				if got := "0x" + hex.EncodeToString(encoded); got != v.Encoded {
+					t.Fatalf("\t%s\tTest %d:\tShould encode the transaction to the published bytes : got %s, exp %s", failed, testID, got, v.Encoded)
+				}
+				t.Logf("\t%s\tTest %d:\tShould encode the transaction to the published bytes.", success, testID)
+
+				signer, err := v.SignedTx.FromAccount()
+				if err != nil || signer != v.SignedTx.FromID {
+					t.Fatalf("\t%s\tTest %d:\tShould recover the sender from the signature : got %s, exp %s, %v", failed, testID, signer, v.SignedTx.FromID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould recover the sender from the signature.", success, testID)
+
+				if err := v.SignedTx.Validate(rules); err == nil {
+					t.Fatalf("\t%s\tTest %d:\tShould reject the transaction since %s.", failed, testID, v.Reason)
+				}
+				t.Logf("\t%s\tTest %d:\tShould reject the transaction since %s.", success, testID, v.Reason)
+
+				restored := v.SignedTx
+				if restored.ToID, err = database.ToAccountID(string(restored.ToID)); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to convert the recipient : %s", failed, testID, err)
+				}
+				if err := restored.Validate(rules); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould accept the transaction with the recipient in checksum form : %s", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould accept the transaction with the recipient in checksum form.", success, testID)
+			}
+		}
+	}
+}
+
+func TestCanonicalHeaderVectors(t *testing.T) {
+	vs := loadVectors(t)
+
+	t.Log("Given the need to encode block headers the same as the published vectors.")
+	{
+		for testID, v := range vs.Headers {
+			t.Logf("\tTest %d:\tWhen handling the %s vector.", testID, v.Description)
+			{
+				encoded, err := v.Header.Encode()
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to encode the header : %s", failed, testID, err)
+				}
+				if got := "0x" + hex.EncodeToString(encoded); got != v.Encoded {
+					t.Fatalf("\t%s\tTest %d:\tShould encode the header to the published bytes : got %s, exp %s", failed, testID, got, v.Encoded)
+				}
+				t.Logf("\t%s\tTest %d:\tShould encode the header to the published bytes.", success, testID)
+
+				if got := signature.HashBytes(encoded); got != v.Hash {
+					t.Fatalf("\t%s\tTest %d:\tShould hash the header to the published hash : got %s, exp %s", failed, testID, got, v.Hash)
+				}
+				if got := v.Header.Hash(); got != v.Hash {
+					t.Fatalf("\t%s\tTest %d:\tShould hash the header with Hash to the published hash : got %s, exp %s", failed, testID, got, v.Hash)
+				}
+				t.Logf("\t%s\tTest %d:\tShould hash the header to the published hash.", success, testID)
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/amount"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/signature"
+)
+
+// CORE NOTE: Once the feePayer fork is active a transaction can name a fee
+// payer, which lets an account with no balance send a transaction someone
+// else pays for. The sender signs the transaction with the fee payer in it,
+// then the fee payer adds its own signature. The fee payer signs the same
+// encoding with a prefix, so its signature can't be used as the sender's or
+// as the signature of a multisig signer. The gas and tip are taken from the
+// fee payer and the value from the sender. Like any sender, the fee payer
+// pays the gas when the transaction fails.
+
+// feePayerPrefix is added to the encoding of the transaction signed by the
+// fee payer.
+var feePayerPrefix = []byte("ardan fee payer:")
+
+// PayerID returns the account that pays the gas and tip of the transaction.
+func (tx Tx) PayerID() AccountID {
+	if tx.FeePayerID != "" {
+		return tx.FeePayerID
+	}
+
+	return tx.FromID
+}
+
+// feePayerData returns the bytes the fee payer signs.
+func (tx Tx) feePayerData() ([]byte, error) {
+	data, err := tx.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]byte{}, feePayerPrefix...), data...), nil
+}
+
+// SignFeePayer uses the specified private key to add the fee payer's
+// signature to a transaction that names it as the fee payer.
+func (tx SignedTx) SignFeePayer(privateKey *ecdsa.PrivateKey) (SignedTx, error) {
+	if tx.FeePayerID == "" {
+		return SignedTx{}, errors.New("transaction doesn't have a fee payer")
+	}
+
+	if PublicKeyToAccountID(privateKey.PublicKey) != tx.FeePayerID {
+		return SignedTx{}, fmt.Errorf("transaction names %s as the fee payer", tx.FeePayerID)
+	}
+
+	data, err := tx.Tx.feePayerData()
+	if err != nil {
+		return SignedTx{}, err
+	}
+
+	v, r, s, err := signature.SignBytes(data, privateKey)
+	if err != nil {
+		return SignedTx{}, err
+	}
+
+	tx.FeePayer = &Signature{V: v, R: r, S: s}
+
+	return tx, nil
+}
+
+// validateFeePayer checks the fee payer is allowed by the rules and signed
+// the transaction.
+func (tx SignedTx) validateFeePayer(rules genesis.Rules) error {
+	if tx.FeePayerID == "" {
+		if tx.FeePayer != nil {
+			return errors.New("fee payer signature without a fee payer")
+		}
+		return nil
+	}
+
+	if !rules.IsActive(genesis.ForkFeePayer) {
+		return fmt.Errorf("fee payers are not allowed before the %s fork", genesis.ForkFeePayer)
+	}
+
+	if !tx.FeePayerID.IsAccountID() {
+		return errors.New("fee payer account is not properly formatted")
+	}
+
+	if tx.FeePayerID == tx.FromID {
+		return errors.New("the sender can't be its own fee payer")
+	}
+
+	sig := tx.FeePayer
+	if sig == nil || sig.V == nil || sig.R == nil || sig.S == nil {
+		return fmt.Errorf("transaction needs the signature of fee payer %s", tx.FeePayerID)
+	}
+
+	if err := signature.VerifySignature(sig.V, sig.R, sig.S); err != nil {
+		return fmt.Errorf("fee payer signature: %w", err)
+	}
+
+	data, err := tx.Tx.feePayerData()
+	if err != nil {
+		return err
+	}
+
+	address, err := signature.FromAddressBytes(data, sig.V, sig.R, sig.S)
+	if err != nil {
+		return fmt.Errorf("fee payer signature: %w", err)
+	}
+
+	if AccountID(address) != tx.FeePayerID {
+		return errors.New("fee payer signature address doesn't match fee payer address")
+	}
+
+	return nil
+}
+
+// ValidateFeePayer checks the fee payer of a transaction holds enough to
+// pay for the gas and tip the transaction is charged. Once the baseFee fork
+// is active the base fee can rise up to the max fee before the transaction
+// is mined, so the payer needs to cover the gas at the max fee.
+func (db *Database) ValidateFeePayer(tx BlockTx) error {
+	if tx.FeePayerID == "" {
+		return nil
+	}
+
+	gasPrice := tx.GasPrice
+	if tx.MaxFee > gasPrice {
+		gasPrice = tx.MaxFee
+	}
+
+	gasFee, err := amount.Mul(gasPrice, tx.GasUnits)
+	if err != nil {
+		return fmt.Errorf("gas fee: %w", err)
+	}
+
+	needed, err := amount.Add(gasFee, tx.Tip)
+	if err != nil {
+		return fmt.Errorf("gas fee plus tip: %w", err)
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if balance := db.account(tx.FeePayerID).Balance; balance < needed {
+		return fmt.Errorf("fee payer %s has insufficient funds, bal %d, needed %d", tx.FeePayerID, balance, needed)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+)
+
+func TestValidateFeePayer(t *testing.T) {
+	gen := genesis.Genesis{
+		Date:          time.Date(2021, 12, 17, 0, 0, 0, 0, time.UTC),
+		ChainID:       1,
+		TransPerBlock: 10,
+		Difficulty:    1,
+		MiningReward:  700,
+		GasPrice:      15,
+		Balances:      map[string]uint64{pavel: 1000, kennedy: 100},
+	}
+	db := newTestDatabase(t, gen)
+
+	tt := []struct {
+		name     string
+		gasPrice uint64
+		maxFee   uint64
+		tip      uint64
+		valid    bool
+	}{
+		{name: "the payer covers the gas and the tip", gasPrice: 9, tip: 10, valid: true},
+		{name: "the payer doesn't cover the tip", gasPrice: 9, tip: 11, valid: false},
+		{name: "the payer covers the gas at the max fee", gasPrice: 5, maxFee: 9, tip: 10, valid: true},
+		{name: "the payer covers the gas at the base fee but not at the max fee", gasPrice: 5, maxFee: 10, tip: 10, valid: false},
+	}
+
+	t.Log("Given the need to only accept sponsored transactions the fee payer can pay for once mined.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen %s.", testID, test.name)
+			{
+				tx := database.Tx{ChainID: 1, Nonce: 1, FromID: pavel, ToID: kennedy, Value: 10, Tip: test.tip, MaxFee: test.maxFee, FeePayerID: kennedy}
+				blockTx := database.NewBlockTx(database.SignedTx{Tx: tx}, test.gasPrice, 10)
+
+				err := db.ValidateFeePayer(blockTx)
+				switch {
+				case test.valid && err != nil:
+					t.Fatalf("\t%s\tTest %d:\tShould accept the fee payer : %s", failed, testID, err)
+				case !test.valid && err == nil:
+					t.Fatalf("\t%s\tTest %d:\tShould reject the fee payer.", failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould accept the fee payer is %v.", success, testID, test.valid)
+			}
+		}
+	}
+}
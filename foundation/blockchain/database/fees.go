@@ -0,0 +1,142 @@
+package database
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/amount"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+)
+
+// CORE NOTE: Before the baseFee fork the gas price is fixed in the genesis
+// file and the whole gas fee goes to the beneficiary. Once the fork is active
+// each block header carries a base fee per unit of gas, following EIP-1559.
+// When the parent block used more gas than the target, which is the block gas
+// limit divided by the elasticity, the base fee goes up. When it used less,
+// the base fee goes down. The change is limited by the change denominator so
+// fees move smoothly. Every transaction in the block pays the base fee for its
+// gas, which is burned or paid to the treasury so the beneficiary can't mine
+// free transactions. The sender signs the max fee they accept to pay per unit
+// of gas and the tip is the priority fee paid to the beneficiary. The gas
+// price a transaction was accepted with is left as it is, so its hash doesn't
+// change when it's mined, and the base fee it paid is recorded next to it.
+
+// NextBaseFee returns the base fee per unit of gas for the block after the
+// specified parent header.
+func NextBaseFee(g genesis.Genesis, parent BlockHeader) uint64 {
+	rules := g.Rules(parent.Number + 1)
+	if !rules.IsActive(genesis.ForkBaseFee) {
+		return 0
+	}
+
+	// The first block of the fork starts from the initial base fee.
+	if parent.Number == 0 || !g.Rules(parent.Number).IsActive(genesis.ForkBaseFee) {
+		return rules.Fees.InitialBaseFee
+	}
+
+	target := rules.Gas.BlockLimit / rules.Fees.Elasticity
+
+	switch {
+	case parent.GasUsed > target:
+		delta := baseFeeDelta(parent.BaseFee, parent.GasUsed-target, target, rules.Fees.ChangeDenominator)
+		if delta == 0 {
+			delta = 1
+		}
+
+		baseFee, err := amount.Add(parent.BaseFee, delta)
+		if err != nil {
+			return math.MaxUint64
+		}
+		return baseFee
+
+	case parent.GasUsed < target:
+		delta := baseFeeDelta(parent.BaseFee, target-parent.GasUsed, target, rules.Fees.ChangeDenominator)
+		return parent.BaseFee - delta
+	}
+
+	return parent.BaseFee
+}
+
+// validateBaseFee checks the block records the gas its transactions use and
+// every transaction accepts to pay the base fee of the block.
+func (b Block) validateBaseFee(rules genesis.Rules) error {
+	trans := b.MerkleTree.Values()
+
+	if used := blockGasUsed(rules, trans); b.Header.GasUsed != used {
+		return fmt.Errorf("block gas used is wrong, got %d, exp %d", b.Header.GasUsed, used)
+	}
+
+	if !rules.IsActive(genesis.ForkBaseFee) {
+		return nil
+	}
+
+	for _, tx := range trans {
+		if tx.MaxFee < b.Header.BaseFee {
+			return fmt.Errorf("tx[%s]: max fee %d is below the block base fee %d", tx, tx.MaxFee, b.Header.BaseFee)
+		}
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// blockGasUsed returns the gas used by the transactions that the block
+// header needs to record under the rules.
+func blockGasUsed(rules genesis.Rules, trans []BlockTx) uint64 {
+	if !rules.IsActive(genesis.ForkBaseFee) {
+		return 0
+	}
+
+	var used uint64
+	for _, tx := range trans {
+		used += tx.GasUnits
+	}
+
+	return used
+}
+
+// baseFeeDelta calculates baseFee * gasDelta / target / denominator without
+// overflowing.
+func baseFeeDelta(baseFee uint64, gasDelta uint64, target uint64, denominator uint64) uint64 {
+	delta := new(big.Int).SetUint64(baseFee)
+	delta.Mul(delta, new(big.Int).SetUint64(gasDelta))
+	delta.Div(delta, new(big.Int).SetUint64(target))
+	delta.Div(delta, new(big.Int).SetUint64(denominator))
+
+	if !delta.IsUint64() {
+		return math.MaxUint64
+	}
+
+	return delta.Uint64()
+}
+
+// effectiveGasPrice returns the price of one unit of gas the transaction pays
+// in a block with the specified base fee. Once the baseFee fork is active
+// every transaction pays the base fee, before that the gas price it was
+// accepted with.
+func effectiveGasPrice(rules genesis.Rules, baseFee uint64, tx BlockTx) uint64 {
+	if rules.IsActive(genesis.ForkBaseFee) {
+		return baseFee
+	}
+
+	return tx.GasPrice
+}
+
+// withEffectiveGasPrice returns a copy of the transactions with the gas price
+// they pay in a block with the specified base fee recorded, once the baseFee
+// fork is active.
+func withEffectiveGasPrice(rules genesis.Rules, baseFee uint64, trans []BlockTx) []BlockTx {
+	if !rules.IsActive(genesis.ForkBaseFee) {
+		return trans
+	}
+
+	priced := make([]BlockTx, len(trans))
+	for i, tx := range trans {
+		tx.EffectiveGasPrice = effectiveGasPrice(rules, baseFee, tx)
+		priced[i] = tx
+	}
+
+	return priced
+}
@@ -0,0 +1,157 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/amount"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+)
+
+// CORE NOTE: Before the gasMetering fork every transaction uses one unit of
+// gas, so a transaction stuffed with data costs the same as a plain transfer.
+// Once the fork is active the gas a transaction uses comes from the schedule
+// in the genesis file: a base cost, a cost for each byte of data and an extra
+// cost based on the type of transaction, which a batch pays for every entry.
+// The sender signs a gas limit, which is the most gas they agree to pay for,
+// and a block can only hold transactions whose gas adds up to the block gas
+// limit.
+
+// Gas returns the units of gas the transaction reserves in a block under the
+// rules. A transaction that runs contract code reserves its whole gas limit
+// and is refunded the gas the code didn't use when it's applied.
+func (tx Tx) Gas(rules genesis.Rules) (uint64, error) {
+	units, err := tx.intrinsicGas(rules)
+	if err != nil {
+		return 0, err
+	}
+
+	if !rules.IsActive(genesis.ForkGasMetering) {
+		return units, nil
+	}
+
+	if _, executes := txHandlers[tx.Type].(txExecutor); executes && tx.GasLimit > units {
+		return tx.GasLimit, nil
+	}
+
+	return units, nil
+}
+
+// intrinsicGas returns the units of gas the transaction uses before any
+// contract code runs.
+func (tx Tx) intrinsicGas(rules genesis.Rules) (uint64, error) {
+	if !rules.IsActive(genesis.ForkGasMetering) {
+		const oneUnitOfGas = 1
+		return oneUnitOfGas, nil
+	}
+
+	dataGas, err := amount.Mul(uint64(len(tx.Data)), rules.Gas.PerByte)
+	if err != nil {
+		return 0, fmt.Errorf("data gas: %w", err)
+	}
+
+	units, err := amount.Add(rules.Gas.Base, dataGas)
+	if err != nil {
+		return 0, fmt.Errorf("gas units: %w", err)
+	}
+
+	handler, exists := txHandlers[tx.Type]
+	if !exists {
+		return 0, fmt.Errorf("unknown transaction type %d", uint8(tx.Type))
+	}
+
+	// The per type gas of a batch is charged for each of its entries.
+	typeGas := rules.Gas.PerType[handler.Name()]
+	if batcher, isBatch := handler.(txBatcher); isBatch {
+		entries, err := batcher.entries(tx)
+		if err != nil {
+			return 0, err
+		}
+
+		if typeGas, err = amount.Mul(typeGas, entries); err != nil {
+			return 0, fmt.Errorf("type gas: %w", err)
+		}
+	}
+
+	units, err = amount.Add(units, typeGas)
+	if err != nil {
+		return 0, fmt.Errorf("gas units: %w", err)
+	}
+
+	return units, nil
+}
+
+// validateGas checks the gas limit signed by the sender is allowed by the
+// rules and covers the gas the transaction uses.
+func (tx Tx) validateGas(rules genesis.Rules) error {
+	if !rules.IsActive(genesis.ForkGasMetering) {
+		if tx.GasLimit != 0 {
+			return fmt.Errorf("gas limit is not allowed before the %s fork", genesis.ForkGasMetering)
+		}
+		return nil
+	}
+
+	units, err := tx.Gas(rules)
+	if err != nil {
+		return err
+	}
+
+	if tx.GasLimit < units {
+		return fmt.Errorf("gas limit too low, limit %d, needed %d", tx.GasLimit, units)
+	}
+
+	if tx.GasLimit > rules.Gas.BlockLimit {
+		return fmt.Errorf("gas limit %d is over the block gas limit %d", tx.GasLimit, rules.Gas.BlockLimit)
+	}
+
+	return nil
+}
+
+// ValidateGas checks the block transaction is charged the gas the rules
+// require and it fits within the gas limit signed by the sender.
+func (tx BlockTx) ValidateGas(rules genesis.Rules) error {
+	if err := tx.validateGas(rules); err != nil {
+		return err
+	}
+
+	if !rules.IsActive(genesis.ForkGasMetering) {
+		return nil
+	}
+
+	units, err := tx.Gas(rules)
+	if err != nil {
+		return err
+	}
+
+	if tx.GasUnits != units {
+		return fmt.Errorf("gas units are wrong, got %d, exp %d", tx.GasUnits, units)
+	}
+
+	return nil
+}
+
+// validateBlockGas checks the transactions in a block are charged the
+// right gas and together fit within the block gas limit.
+func validateBlockGas(rules genesis.Rules, trans []BlockTx) error {
+	if !rules.IsActive(genesis.ForkGasMetering) {
+		return nil
+	}
+
+	var used uint64
+	for _, tx := range trans {
+		if err := tx.ValidateGas(rules); err != nil {
+			return fmt.Errorf("tx[%s]: %w", tx, err)
+		}
+
+		var err error
+		if used, err = amount.Add(used, tx.GasUnits); err != nil {
+			return errors.New("block gas used overflows")
+		}
+	}
+
+	if used > rules.Gas.BlockLimit {
+		return fmt.Errorf("block uses %d gas, over the block gas limit %d", used, rules.Gas.BlockLimit)
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+)
+
+// CORE NOTE: A light client only keeps the chain of block headers. Every
+// header is checked against its parent for the hash chain, the POW and the
+// difficulty, which proves the work went into the chain without needing the
+// transactions. The headers carry the transaction root and the state root, so
+// anything a peer claims about a transaction or an account can be checked
+// with a merkle proof against a header the client already trusts. The
+// headers are written to storage as blocks without transactions, so a
+// restarted client only syncs the headers mined while it was down.
+
+// HeaderChain maintains the validated chain of block headers for a node
+// running in light mode.
+type HeaderChain struct {
+	mu      sync.RWMutex
+	genesis genesis.Genesis
+	storage Storage
+	headers []BlockHeader
+}
+
+// NewHeaderChain constructs the header chain for the genesis from the
+// headers kept in the storage. Every stored header is validated again.
+func NewHeaderChain(genesis genesis.Genesis, storage Storage, evHandler func(v string, args ...any)) (*HeaderChain, error) {
+	hc := HeaderChain{
+		genesis: genesis,
+		storage: storage,
+	}
+
+	iter := storage.ForEach()
+	for blockData, err := iter.Next(); !iter.Done(); blockData, err = iter.Next() {
+		if err != nil {
+			return nil, err
+		}
+
+		if err := hc.validate(blockData.Header, evHandler); err != nil {
+			return nil, err
+		}
+		hc.headers = append(hc.headers, blockData.Header)
+	}
+
+	return &hc, nil
+}
+
+// Latest returns the latest header in the chain. The zero header with a
+// number of 0 is returned when the chain is empty.
+func (hc *HeaderChain) Latest() BlockHeader {
+	hc.mu.RLock()
+	defer hc.mu.RUnlock()
+
+	if len(hc.headers) == 0 {
+		return BlockHeader{}
+	}
+
+	return hc.headers[len(hc.headers)-1]
+}
+
+// Get returns the header for the specified block number.
+func (hc *HeaderChain) Get(number uint64) (BlockHeader, error) {
+	hc.mu.RLock()
+	defer hc.mu.RUnlock()
+
+	if number == 0 || number > uint64(len(hc.headers)) {
+		return BlockHeader{}, ErrNotFound
+	}
+
+	return hc.headers[number-1], nil
+}
+
+// Range returns the headers for the specified range of block numbers. The
+// range is capped at the latest header.
+func (hc *HeaderChain) Range(from uint64, to uint64) []BlockHeader {
+	hc.mu.RLock()
+	defer hc.mu.RUnlock()
+
+	if from == 0 {
+		from = 1
+	}
+	if to > uint64(len(hc.headers)) {
+		to = uint64(len(hc.headers))
+	}
+	if from > to {
+		return nil
+	}
+
+	headers := make([]BlockHeader, to-from+1)
+	copy(headers, hc.headers[from-1:to])
+
+	return headers
+}
+
+// Append validates the header against the latest header, writes it to
+// storage and adds it to the chain.
+func (hc *HeaderChain) Append(header BlockHeader, evHandler func(v string, args ...any)) error {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	if err := hc.validate(header, evHandler); err != nil {
+		return err
+	}
+
+	blockData := BlockData{
+		Version: BlockDataVersion,
+		Hash:    header.Hash(),
+		Header:  header,
+	}
+	if err := hc.storage.Write(blockData); err != nil {
+		return fmt.Errorf("block %d: writing header: %w", header.Number, err)
+	}
+
+	hc.headers = append(hc.headers, header)
+
+	return nil
+}
+
+// validate checks the header against the latest header in the chain. The
+// caller must hold the write lock.
+func (hc *HeaderChain) validate(header BlockHeader, evHandler func(v string, args ...any)) error {
+	var previous BlockHeader
+	if len(hc.headers) > 0 {
+		previous = hc.headers[len(hc.headers)-1]
+	}
+
+	if err := header.ValidateHeader(hc.genesis, previous, evHandler); err != nil {
+		return fmt.Errorf("block %d: %w", header.Number, err)
+	}
+
+	return nil
+}
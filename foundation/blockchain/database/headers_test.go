@@ -0,0 +1,118 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/storage/disk"
+)
+
+func TestHeaderChain(t *testing.T) {
+	gen := genesis.Genesis{
+		Date:          time.Date(2021, 12, 17, 0, 0, 0, 0, time.UTC),
+		ChainID:       1,
+		TransPerBlock: 10,
+		Difficulty:    1,
+		MiningReward:  700,
+		GasPrice:      15,
+		Balances:      map[string]uint64{pavel: 1000},
+	}
+	ev := func(v string, args ...any) {}
+	dir := t.TempDir()
+
+	// open constructs the header chain from the headers stored in the
+	// directory.
+	open := func() (*database.HeaderChain, error) {
+		storage, err := disk.New(dir)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to construct the storage : %s", failed, err)
+		}
+		return database.NewHeaderChain(gen, storage, ev)
+	}
+
+	var blocks []database.Block
+	var prevBlock database.Block
+	for i := 0; i < 3; i++ {
+		tx := database.Tx{ChainID: 1, Nonce: uint64(i + 1), FromID: pavel, ToID: kennedy, Value: 10}
+
+		block, err := database.POW(context.Background(), database.POWArgs{
+			GenesisHash:   gen.Hash(),
+			Rules:         gen.Rules(prevBlock.Header.Number + 1),
+			BeneficiaryID: kennedy,
+			Difficulty:    gen.Difficulty,
+			PrevBlock:     prevBlock,
+			Trans:         []database.BlockTx{database.NewBlockTx(signTx(t, tx), 15, 1)},
+			EvHandler:     ev,
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to mine block %d : %s", failed, i+1, err)
+		}
+		blocks = append(blocks, block)
+		prevBlock = block
+	}
+
+	t.Log("Given the need to keep the header chain of a light client across restarts.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen appending headers.", testID)
+		{
+			hc, err := open()
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to construct the header chain : %s", failed, testID, err)
+			}
+
+			for _, block := range blocks {
+				if err := hc.Append(block.Header, ev); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to append header %d : %s", failed, testID, block.Header.Number, err)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to append the headers.", success, testID)
+
+			bad := blocks[2].Header
+			if err := hc.Append(bad, ev); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould reject a header that isn't the next one.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject a header that isn't the next one.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen constructing the header chain again.", testID)
+		{
+			hc, err := open()
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to construct the header chain : %s", failed, testID, err)
+			}
+
+			if got, exp := hc.Latest().Hash(), blocks[2].Header.Hash(); got != exp {
+				t.Fatalf("\t%s\tTest %d:\tShould load the stored headers : got %s, exp %s", failed, testID, got, exp)
+			}
+			if headers := hc.Range(1, 10); len(headers) != len(blocks) {
+				t.Fatalf("\t%s\tTest %d:\tShould load every stored header : got %d, exp %d", failed, testID, len(headers), len(blocks))
+			}
+			t.Logf("\t%s\tTest %d:\tShould load the stored headers.", success, testID)
+		}
+
+		testID = 2
+		t.Logf("\tTest %d:\tWhen a stored header was tampered with.", testID)
+		{
+			storage, err := disk.New(dir)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to construct the storage : %s", failed, testID, err)
+			}
+
+			header := blocks[2].Header
+			header.Number = 4
+			if err := storage.Write(database.BlockData{Version: database.BlockDataVersion, Hash: header.Hash(), Header: header}); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to write the header : %s", failed, testID, err)
+			}
+
+			if _, err := open(); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould reject the stored header.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject the stored header.", success, testID)
+		}
+	}
+}
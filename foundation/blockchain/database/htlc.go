@@ -0,0 +1,520 @@
+package database
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// CORE NOTE: Once the htlc fork is active a lock transaction moves value
+// into a new lock account, whose address comes from the sender and the
+// nonce like a contract. The lock can be claimed for the recipient by
+// presenting the preimage of its hash before the expiry block, or refunded
+// to the sender from the expiry block on. The hash is SHA-256 so the same
+// secret can lock value on another chain, which is what makes an atomic swap
+// work: claiming on one chain publishes the preimage the other side needs.
+// At the end of every block the locks that have expired are refunded, so a
+// sender doesn't have to watch the chain to get the value back. The locks
+// are indexed by the block they expire at, so only the locks that are due
+// are looked at. Settled locks keep their record, including the preimage of
+// a claimed lock, so the other side of a swap can find it in the state.
+// Once the htlcCleanup fork is active a settled lock is removed from the
+// state HTLCRetention blocks after its expiry.
+
+// Set of transaction types that lock, claim and refund value.
+const (
+	TxTypeHTLCLock   TxType = 7
+	TxTypeHTLCClaim  TxType = 8
+	TxTypeHTLCRefund TxType = 9
+)
+
+// MaxPreimageSize represents the largest preimage that can be presented to
+// claim a lock.
+const MaxPreimageSize = 64
+
+// HTLCRetention represents the number of blocks after its expiry a settled
+// lock is kept in the state once the htlcCleanup fork is active.
+const HTLCRetention = 1000
+
+// HTLCStatus represents where a lock is in its life.
+type HTLCStatus uint8
+
+// Set of lock statuses.
+const (
+	HTLCLocked   HTLCStatus = 0
+	HTLCClaimed  HTLCStatus = 1
+	HTLCRefunded HTLCStatus = 2
+)
+
+// String implements the Stringer interface.
+func (hs HTLCStatus) String() string {
+	switch hs {
+	case HTLCLocked:
+		return "locked"
+	case HTLCClaimed:
+		return "claimed"
+	case HTLCRefunded:
+		return "refunded"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint8(hs))
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (hs HTLCStatus) MarshalText() ([]byte, error) {
+	return []byte(hs.String()), nil
+}
+
+// HTLC represents value locked until the preimage of the hash is presented
+// or the lock expires.
+type HTLC struct {
+	Sender    AccountID     `json:"sender"`
+	Recipient AccountID     `json:"recipient"`
+	HashLock  common.Hash   `json:"hash_lock"`
+	Expiry    uint64        `json:"expiry"`
+	Amount    uint64        `json:"amount"`
+	Status    HTLCStatus    `json:"status"`
+	Preimage  hexutil.Bytes `json:"preimage,omitempty"`
+}
+
+// rlpHTLC is the canonical layout of a lock stored in its account.
+type rlpHTLC struct {
+	Sender    []byte
+	Recipient []byte
+	HashLock  []byte
+	Expiry    uint64
+	Amount    uint64
+	Status    uint8
+	Preimage  []byte
+}
+
+// encode returns the canonical bytes of the lock.
+func (h HTLC) encode() ([]byte, error) {
+	rh := rlpHTLC{
+		Sender:    accountBytes(h.Sender),
+		Recipient: accountBytes(h.Recipient),
+		HashLock:  h.HashLock.Bytes(),
+		Expiry:    h.Expiry,
+		Amount:    h.Amount,
+		Status:    uint8(h.Status),
+		Preimage:  h.Preimage,
+	}
+
+	return rlp.EncodeToBytes(rh)
+}
+
+// decodeHTLC converts the canonical bytes back into the lock.
+func decodeHTLC(data []byte) (HTLC, error) {
+	var rh rlpHTLC
+	if err := rlp.DecodeBytes(data, &rh); err != nil {
+		return HTLC{}, fmt.Errorf("decoding htlc: %w", err)
+	}
+
+	h := HTLC{
+		Sender:    AccountID(common.BytesToAddress(rh.Sender).Hex()),
+		Recipient: AccountID(common.BytesToAddress(rh.Recipient).Hex()),
+		HashLock:  common.BytesToHash(rh.HashLock),
+		Expiry:    rh.Expiry,
+		Amount:    rh.Amount,
+		Status:    HTLCStatus(rh.Status),
+		Preimage:  rh.Preimage,
+	}
+
+	return h, nil
+}
+
+// HTLCAccountID returns the account of the lock created by the lock
+// transaction with the specified sender and nonce.
+func HTLCAccountID(fromID AccountID, nonce uint64) AccountID {
+	return ContractAccountID(fromID, nonce)
+}
+
+// HashPreimage returns the hash a lock needs for the specified preimage.
+func HashPreimage(preimage []byte) common.Hash {
+	return sha256.Sum256(preimage)
+}
+
+// HTLCInfo returns the lock stored in a lock account and false when the
+// account isn't a lock account.
+func (a Account) HTLCInfo() (HTLC, bool) {
+	if a.HTLC == "" {
+		return HTLC{}, false
+	}
+
+	data, err := hexutil.Decode(a.HTLC)
+	if err != nil {
+		return HTLC{}, false
+	}
+
+	h, err := decodeHTLC(data)
+	if err != nil {
+		return HTLC{}, false
+	}
+
+	return h, true
+}
+
+// setHTLCInfo stores the lock in the lock account.
+func (a *Account) setHTLCInfo(h HTLC) error {
+	data, err := h.encode()
+	if err != nil {
+		return err
+	}
+
+	a.HTLC = hexutil.Encode(data)
+	return nil
+}
+
+// =============================================================================
+
+// HTLCLock represents the data of a lock transaction.
+type HTLCLock struct {
+	HashLock common.Hash
+	Expiry   uint64
+}
+
+// rlpHTLCLock is the canonical layout of the data of a lock transaction.
+type rlpHTLCLock struct {
+	HashLock []byte
+	Expiry   uint64
+}
+
+// Encode returns the canonical bytes of the lock, which are the data of the
+// lock transaction.
+func (hl HTLCLock) Encode() ([]byte, error) {
+	rhl := rlpHTLCLock{
+		HashLock: hl.HashLock.Bytes(),
+		Expiry:   hl.Expiry,
+	}
+
+	return rlp.EncodeToBytes(rhl)
+}
+
+// DecodeHTLCLock converts the data of a lock transaction back into the lock.
+func DecodeHTLCLock(data []byte) (HTLCLock, error) {
+	var rhl rlpHTLCLock
+	if err := rlp.DecodeBytes(data, &rhl); err != nil {
+		return HTLCLock{}, fmt.Errorf("decoding htlc lock: %w", err)
+	}
+
+	if len(rhl.HashLock) != common.HashLength {
+		return HTLCLock{}, fmt.Errorf("hash lock must be %d bytes", common.HashLength)
+	}
+
+	hl := HTLCLock{
+		HashLock: common.BytesToHash(rhl.HashLock),
+		Expiry:   rhl.Expiry,
+	}
+
+	return hl, nil
+}
+
+// =============================================================================
+
+// htlcLockHandler moves the value of the transaction into a new lock.
+type htlcLockHandler struct{}
+
+// Name implements the TxHandler interface.
+func (htlcLockHandler) Name() string {
+	return "htlcLock"
+}
+
+// Fork implements the TxHandler interface.
+func (htlcLockHandler) Fork() string {
+	return genesis.ForkHTLC
+}
+
+// Validate implements the TxHandler interface.
+func (htlcLockHandler) Validate(rules genesis.Rules, tx Tx) error {
+	if !tx.ToID.IsAccountID() {
+		return errors.New("to account is not properly formatted")
+	}
+
+	if tx.FromID == tx.ToID {
+		return fmt.Errorf("locking money for yourself, from %s, to %s", tx.FromID, tx.ToID)
+	}
+
+	if tx.Value == 0 {
+		return errors.New("lock transactions need a value to lock")
+	}
+
+	_, err := DecodeHTLCLock(tx.Data)
+	return err
+}
+
+// Apply implements the TxHandler interface.
+func (htlcLockHandler) Apply(tc *TxContext) error {
+	hl, err := DecodeHTLCLock(tc.Tx.Data)
+	if err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+
+	if hl.Expiry <= tc.Header.Number {
+		return fmt.Errorf("transaction invalid, lock expires at block %d, which isn't after block %d", hl.Expiry, tc.Header.Number)
+	}
+
+	lockID := HTLCAccountID(tc.From.AccountID, tc.Tx.Nonce)
+	if tc.Tx.ToID == lockID {
+		return fmt.Errorf("transaction invalid, lock %s can't be its own recipient", lockID)
+	}
+
+	account := tc.Account(lockID)
+	if account.registered() {
+		return fmt.Errorf("transaction invalid, account %s already exists", lockID)
+	}
+
+	balance, err := addBalance(tc.Rules, account.Balance, tc.Tx.Value)
+	if err != nil {
+		return fmt.Errorf("transaction invalid, lock balance: %w", err)
+	}
+
+	h := HTLC{
+		Sender:    tc.From.AccountID,
+		Recipient: tc.Tx.ToID,
+		HashLock:  hl.HashLock,
+		Expiry:    hl.Expiry,
+		Amount:    tc.Tx.Value,
+		Status:    HTLCLocked,
+	}
+	if err := account.setHTLCInfo(h); err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+	account.Balance = balance
+
+	tc.From.Balance -= tc.Tx.Value
+	tc.SetAccount(account)
+
+	return nil
+}
+
+// =============================================================================
+
+// htlcClaimHandler pays a lock to its recipient when the transaction data
+// is the preimage of the lock's hash. Anyone can present the preimage, the
+// value always goes to the recipient.
+type htlcClaimHandler struct{}
+
+// Name implements the TxHandler interface.
+func (htlcClaimHandler) Name() string {
+	return "htlcClaim"
+}
+
+// Fork implements the TxHandler interface.
+func (htlcClaimHandler) Fork() string {
+	return genesis.ForkHTLC
+}
+
+// Validate implements the TxHandler interface.
+func (htlcClaimHandler) Validate(rules genesis.Rules, tx Tx) error {
+	if !tx.ToID.IsAccountID() {
+		return errors.New("to account is not properly formatted")
+	}
+
+	if tx.Value != 0 {
+		return errors.New("claim transactions can't send a value")
+	}
+
+	if len(tx.Data) == 0 || len(tx.Data) > MaxPreimageSize {
+		return fmt.Errorf("claim transactions need a preimage of 1 to %d bytes as data", MaxPreimageSize)
+	}
+
+	return nil
+}
+
+// Apply implements the TxHandler interface.
+func (htlcClaimHandler) Apply(tc *TxContext) error {
+	account, h, err := openLock(tc)
+	if err != nil {
+		return err
+	}
+
+	if tc.Header.Number >= h.Expiry {
+		return fmt.Errorf("transaction invalid, lock %s expired at block %d", account.AccountID, h.Expiry)
+	}
+
+	if HashPreimage(tc.Tx.Data) != h.HashLock {
+		return fmt.Errorf("transaction invalid, preimage doesn't match the hash of lock %s", account.AccountID)
+	}
+
+	h.Status = HTLCClaimed
+	h.Preimage = tc.Tx.Data
+
+	return settleLock(tc, account, h, h.Recipient)
+}
+
+// =============================================================================
+
+// htlcRefundHandler pays an expired lock back to its sender.
+type htlcRefundHandler struct{}
+
+// Name implements the TxHandler interface.
+func (htlcRefundHandler) Name() string {
+	return "htlcRefund"
+}
+
+// Fork implements the TxHandler interface.
+func (htlcRefundHandler) Fork() string {
+	return genesis.ForkHTLC
+}
+
+// Validate implements the TxHandler interface.
+func (htlcRefundHandler) Validate(rules genesis.Rules, tx Tx) error {
+	if !tx.ToID.IsAccountID() {
+		return errors.New("to account is not properly formatted")
+	}
+
+	if tx.Value != 0 {
+		return errors.New("refund transactions can't send a value")
+	}
+
+	return nil
+}
+
+// Apply implements the TxHandler interface.
+func (htlcRefundHandler) Apply(tc *TxContext) error {
+	account, h, err := openLock(tc)
+	if err != nil {
+		return err
+	}
+
+	if tc.Header.Number < h.Expiry {
+		return fmt.Errorf("transaction invalid, lock %s doesn't expire until block %d", account.AccountID, h.Expiry)
+	}
+
+	h.Status = HTLCRefunded
+
+	return settleLock(tc, account, h, h.Sender)
+}
+
+// =============================================================================
+
+// openLock returns the lock the transaction is sent to when it's still
+// locked.
+func openLock(tc *TxContext) (Account, HTLC, error) {
+	account := tc.Account(tc.Tx.ToID)
+
+	h, isLock := account.HTLCInfo()
+	if !isLock {
+		return Account{}, HTLC{}, fmt.Errorf("transaction invalid, account %s is not a lock", tc.Tx.ToID)
+	}
+
+	if h.Status != HTLCLocked {
+		return Account{}, HTLC{}, fmt.Errorf("transaction invalid, lock %s is already %s", tc.Tx.ToID, h.Status)
+	}
+
+	return account, h, nil
+}
+
+// settleLock pays the balance of the lock to the specified account and
+// stores the settled lock.
+func settleLock(tc *TxContext, account Account, h HTLC, payeeID AccountID) error {
+	if payeeID == tc.From.AccountID {
+		balance, err := addBalance(tc.Rules, tc.From.Balance, account.Balance)
+		if err != nil {
+			return fmt.Errorf("transaction invalid, to balance: %w", err)
+		}
+		tc.From.Balance = balance
+	} else {
+		payee := tc.Account(payeeID)
+		balance, err := addBalance(tc.Rules, payee.Balance, account.Balance)
+		if err != nil {
+			return fmt.Errorf("transaction invalid, to balance: %w", err)
+		}
+		payee.Balance = balance
+		tc.SetAccount(payee)
+	}
+
+	if err := account.setHTLCInfo(h); err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+	account.Balance = 0
+
+	tc.SetAccount(account)
+
+	return nil
+}
+
+// =============================================================================
+
+// ExpireLocks refunds the locks that have expired by the end of the
+// specified block to their senders. A lock that can't be refunded stays
+// locked and is tried again at the end of the next block. Once the
+// htlcCleanup fork is active the settled locks past their retention are
+// removed from the state.
+func (db *Database) ExpireLocks(block Block) error {
+	rules := db.genesis.Rules(block.Header.Number)
+	if !rules.IsActive(genesis.ForkHTLC) {
+		return nil
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var firstErr error
+	for _, lockID := range db.expiries.due(block.Header.Number) {
+		account := db.accounts[lockID]
+
+		h, isLock := account.HTLCInfo()
+		if !isLock || h.Status != HTLCLocked {
+			continue
+		}
+
+		sender := db.account(h.Sender)
+		balance, err := addBalance(rules, sender.Balance, account.Balance)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("expiring lock %s, sender balance: %w", lockID, err)
+			}
+			continue
+		}
+		sender.Balance = balance
+
+		h.Status = HTLCRefunded
+		if err := account.setHTLCInfo(h); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("expiring lock %s: %w", lockID, err)
+			}
+			continue
+		}
+		account.Balance = 0
+
+		db.setAccount(h.Sender, sender)
+		db.setAccount(lockID, account)
+	}
+
+	if rules.IsActive(genesis.ForkHTLCCleanup) {
+		for _, lockID := range db.removals.due(block.Header.Number) {
+			db.deleteAccount(lockID)
+		}
+	}
+
+	return firstErr
+}
+
+// indexHTLC files the lock account under the block its lock expires at while
+// it's locked, and under the block it's removed at once it's settled. The
+// caller must hold the write lock.
+func (db *Database) indexHTLC(accountID AccountID, account Account) {
+	db.expiries.remove(accountID)
+	db.removals.remove(accountID)
+
+	h, isLock := account.HTLCInfo()
+	if !isLock {
+		return
+	}
+
+	switch {
+	case h.Status == HTLCLocked:
+		db.expiries.set(accountID, h.Expiry)
+
+	// A lock expiring too close to the last block is never removed.
+	case h.Expiry <= math.MaxUint64-HTLCRetention:
+		db.removals.set(accountID, h.Expiry+HTLCRetention)
+	}
+}
@@ -0,0 +1,151 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+)
+
+func TestHTLC(t *testing.T) {
+	secret := []byte("the secret of the swap")
+	lockID := database.HTLCAccountID(pavel, 1)
+
+	type balances struct {
+		pavel   uint64
+		kennedy uint64
+		lock    uint64
+		status  database.HTLCStatus
+	}
+
+	tt := []struct {
+		name   string
+		number uint64
+		expire bool
+		tx     database.Tx
+		valid  bool
+		exp    balances
+	}{
+		{
+			name:   "claiming the lock with the preimage",
+			number: 5,
+			tx:     database.Tx{Type: database.TxTypeHTLCClaim, Data: secret},
+			valid:  true,
+			exp:    balances{pavel: 900, kennedy: 100, status: database.HTLCClaimed},
+		},
+		{
+			name:   "claiming the lock with the wrong preimage",
+			number: 5,
+			tx:     database.Tx{Type: database.TxTypeHTLCClaim, Data: []byte("a guess")},
+			exp:    balances{pavel: 900, lock: 100, status: database.HTLCLocked},
+		},
+		{
+			name:   "claiming the lock at its expiry",
+			number: 10,
+			tx:     database.Tx{Type: database.TxTypeHTLCClaim, Data: secret},
+			exp:    balances{pavel: 900, lock: 100, status: database.HTLCLocked},
+		},
+		{
+			name:   "refunding the lock before its expiry",
+			number: 9,
+			tx:     database.Tx{Type: database.TxTypeHTLCRefund},
+			exp:    balances{pavel: 900, lock: 100, status: database.HTLCLocked},
+		},
+		{
+			name:   "refunding the lock at its expiry",
+			number: 10,
+			tx:     database.Tx{Type: database.TxTypeHTLCRefund},
+			valid:  true,
+			exp:    balances{pavel: 1000, status: database.HTLCRefunded},
+		},
+		{
+			name:   "claiming the lock after it expired at the end of a block",
+			number: 11,
+			expire: true,
+			tx:     database.Tx{Type: database.TxTypeHTLCClaim, Data: secret},
+			exp:    balances{pavel: 1000, status: database.HTLCRefunded},
+		},
+		{
+			name:   "refunding the lock after it expired at the end of a block",
+			number: 11,
+			expire: true,
+			tx:     database.Tx{Type: database.TxTypeHTLCRefund},
+			exp:    balances{pavel: 1000, status: database.HTLCRefunded},
+		},
+	}
+
+	t.Log("Given the need to pay a lock to its recipient or back to its sender, never both.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen %s.", testID, test.name)
+			{
+				gen := genesis.Genesis{
+					Date:          time.Date(2021, 12, 17, 0, 0, 0, 0, time.UTC),
+					ChainID:       1,
+					TransPerBlock: 10,
+					Difficulty:    1,
+					MiningReward:  700,
+					Balances:      map[string]uint64{pavel: 1000},
+					Forks:         map[string]uint64{genesis.ForkHTLC: 0},
+				}
+				db := newTestDatabase(t, gen)
+
+				// apply applies the transaction from pavel in the block
+				// without charging any gas.
+				apply := func(number uint64, tx database.Tx) error {
+					tx.ChainID = 1
+					tx.FromID = pavel
+					block := database.Block{Header: database.BlockHeader{Number: number}}
+
+					return db.ApplyTransaction(block, database.NewBlockTx(signTx(t, tx), 0, 0))
+				}
+
+				data, err := database.HTLCLock{HashLock: database.HashPreimage(secret), Expiry: 10}.Encode()
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to encode the lock : %s", failed, testID, err)
+				}
+				if err := apply(1, database.Tx{Nonce: 1, Type: database.TxTypeHTLCLock, ToID: kennedy, Value: 100, Data: data}); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to lock the value : %s", failed, testID, err)
+				}
+
+				if test.expire {
+					if err := db.ExpireLocks(database.Block{Header: database.BlockHeader{Number: 10}}); err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to expire the locks : %s", failed, testID, err)
+					}
+				}
+
+				test.tx.Nonce = 2
+				test.tx.ToID = lockID
+				err = apply(test.number, test.tx)
+				switch {
+				case test.valid && err != nil:
+					t.Fatalf("\t%s\tTest %d:\tShould be able to apply the transaction : %s", failed, testID, err)
+				case !test.valid && err == nil:
+					t.Fatalf("\t%s\tTest %d:\tShould fail the transaction.", failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould apply the transaction is %v.", success, testID, test.valid)
+
+				accounts := db.CopyAccounts()
+				h, isLock := accounts[lockID].HTLCInfo()
+				if !isLock {
+					t.Fatalf("\t%s\tTest %d:\tShould keep the lock in its account.", failed, testID)
+				}
+				got := balances{
+					pavel:   accounts[pavel].Balance,
+					kennedy: accounts[kennedy].Balance,
+					lock:    accounts[lockID].Balance,
+					status:  h.Status,
+				}
+				if got != test.exp {
+					t.Fatalf("\t%s\tTest %d:\tShould leave the expected balances : got %+v, exp %+v", failed, testID, got, test.exp)
+				}
+				t.Logf("\t%s\tTest %d:\tShould leave the expected balances.", success, testID)
+
+				if h.Status == database.HTLCClaimed && string(h.Preimage) != string(secret) {
+					t.Fatalf("\t%s\tTest %d:\tShould publish the preimage in the claimed lock : got %q", failed, testID, h.Preimage)
+				}
+			}
+		}
+	}
+}
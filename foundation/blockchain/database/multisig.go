@@ -0,0 +1,306 @@
+package database
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/signature"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// CORE NOTE: Once the multisig fork is active a multisig transaction
+// registers a new account controlled by a set of signer accounts, whose
+// address comes from the sender and the nonce like a contract. The account has
+// no private key. A transaction from it carries the signature of one signer in
+// V, R and S like any other transaction, and the signatures of the other
+// signers in Signatures. Each signature is verified on its own, but only the
+// state knows who the signers of the account are, so the threshold is checked
+// when the transaction is accepted into the mempool and when it's applied.
+// The signers and threshold are stored in the account so they are covered by
+// the state root.
+
+// MaxMultisigSigners represents the largest number of signers a multisig
+// account can have.
+const MaxMultisigSigners = 16
+
+// TxTypeMultisig registers a multisig account.
+const TxTypeMultisig TxType = 3
+
+// MultisigConfig represents the signers that control a multisig account and
+// how many of them need to sign a transaction.
+type MultisigConfig struct {
+	Threshold uint16      `json:"threshold"`
+	Signers   []AccountID `json:"signers"`
+}
+
+// rlpMultisigConfig is the canonical layout of a multisig configuration.
+type rlpMultisigConfig struct {
+	Threshold uint16
+	Signers   [][]byte
+}
+
+// Encode returns the canonical bytes of the configuration, which are the
+// data of the registration transaction.
+func (mc MultisigConfig) Encode() ([]byte, error) {
+	rmc := rlpMultisigConfig{
+		Threshold: mc.Threshold,
+		Signers:   make([][]byte, len(mc.Signers)),
+	}
+	for i, signer := range mc.Signers {
+		rmc.Signers[i] = accountBytes(signer)
+	}
+
+	return rlp.EncodeToBytes(rmc)
+}
+
+// Validate checks the threshold can be met by the signers.
+func (mc MultisigConfig) Validate() error {
+	if len(mc.Signers) == 0 || len(mc.Signers) > MaxMultisigSigners {
+		return fmt.Errorf("multisig needs between 1 and %d signers, got %d", MaxMultisigSigners, len(mc.Signers))
+	}
+
+	if mc.Threshold == 0 || int(mc.Threshold) > len(mc.Signers) {
+		return fmt.Errorf("multisig threshold must be between 1 and %d, got %d", len(mc.Signers), mc.Threshold)
+	}
+
+	seen := make(map[AccountID]bool, len(mc.Signers))
+	for _, signer := range mc.Signers {
+		if !signer.IsAccountID() {
+			return fmt.Errorf("multisig signer %q is not properly formatted", signer)
+		}
+		if seen[signer] {
+			return fmt.Errorf("multisig signer %s is listed more than once", signer)
+		}
+		seen[signer] = true
+	}
+
+	return nil
+}
+
+// DecodeMultisigConfig converts the canonical bytes back into the
+// configuration and validates it.
+func DecodeMultisigConfig(data []byte) (MultisigConfig, error) {
+	var rmc rlpMultisigConfig
+	if err := rlp.DecodeBytes(data, &rmc); err != nil {
+		return MultisigConfig{}, fmt.Errorf("decoding multisig: %w", err)
+	}
+
+	mc := MultisigConfig{
+		Threshold: rmc.Threshold,
+		Signers:   make([]AccountID, len(rmc.Signers)),
+	}
+	for i, signer := range rmc.Signers {
+		if len(signer) != common.AddressLength {
+			return MultisigConfig{}, fmt.Errorf("multisig signer %d must be %d bytes", i, common.AddressLength)
+		}
+		mc.Signers[i] = AccountID(common.BytesToAddress(signer).Hex())
+	}
+
+	if err := mc.Validate(); err != nil {
+		return MultisigConfig{}, err
+	}
+
+	return mc, nil
+}
+
+// MultisigAccountID returns the account registered by the multisig
+// transaction with the specified sender and nonce.
+func MultisigAccountID(fromID AccountID, nonce uint64) AccountID {
+	return ContractAccountID(fromID, nonce)
+}
+
+// MultisigConfig returns the signers of a multisig account and false when
+// the account isn't a multisig account.
+func (a Account) MultisigConfig() (MultisigConfig, bool) {
+	if a.Multisig == "" {
+		return MultisigConfig{}, false
+	}
+
+	data, err := hexutil.Decode(a.Multisig)
+	if err != nil {
+		return MultisigConfig{}, false
+	}
+
+	mc, err := DecodeMultisigConfig(data)
+	if err != nil {
+		return MultisigConfig{}, false
+	}
+
+	return mc, true
+}
+
+// =============================================================================
+
+// Signature represents an additional signature of a transaction from a
+// multisig account.
+type Signature struct {
+	V *big.Int `json:"v"`
+	R *big.Int `json:"r"`
+	S *big.Int `json:"s"`
+}
+
+// CoSign uses the specified private key to add a signature to a transaction
+// from a multisig account.
+func (tx SignedTx) CoSign(privateKey *ecdsa.PrivateKey) (SignedTx, error) {
+	data, err := tx.Tx.Encode()
+	if err != nil {
+		return SignedTx{}, err
+	}
+
+	v, r, s, err := signature.SignBytes(data, privateKey)
+	if err != nil {
+		return SignedTx{}, err
+	}
+
+	signatures := make([]Signature, len(tx.Signatures), len(tx.Signatures)+1)
+	copy(signatures, tx.Signatures)
+	tx.Signatures = append(signatures, Signature{V: v, R: r, S: s})
+
+	return tx, nil
+}
+
+// Signers returns the accounts that signed the transaction, starting with
+// the account that produced V, R and S. Every signature must be valid and
+// come from a different account.
+func (tx SignedTx) Signers() ([]AccountID, error) {
+	data, err := tx.Tx.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	sigs := append([]Signature{{V: tx.V, R: tx.R, S: tx.S}}, tx.Signatures...)
+
+	signers := make([]AccountID, 0, len(sigs))
+	seen := make(map[AccountID]bool, len(sigs))
+	for i, sig := range sigs {
+		if sig.V == nil || sig.R == nil || sig.S == nil {
+			return nil, fmt.Errorf("signature %d is missing values", i)
+		}
+
+		if err := signature.VerifySignature(sig.V, sig.R, sig.S); err != nil {
+			return nil, fmt.Errorf("signature %d: %w", i, err)
+		}
+
+		address, err := signature.FromAddressBytes(data, sig.V, sig.R, sig.S)
+		if err != nil {
+			return nil, fmt.Errorf("signature %d: %w", i, err)
+		}
+
+		signer := AccountID(address)
+		if seen[signer] {
+			return nil, fmt.Errorf("signature %d: account %s signed more than once", i, signer)
+		}
+		seen[signer] = true
+
+		signers = append(signers, signer)
+	}
+
+	return signers, nil
+}
+
+// validateSigners checks the transaction is signed by enough of the signers
+// of the multisig account it's from.
+func (tx SignedTx) validateSigners(from Account) error {
+	mc, isMultisig := from.MultisigConfig()
+	if !isMultisig {
+		return errors.New("signature address doesn't match from address")
+	}
+
+	signers, err := tx.Signers()
+	if err != nil {
+		return err
+	}
+
+	allowed := make(map[AccountID]bool, len(mc.Signers))
+	for _, signer := range mc.Signers {
+		allowed[signer] = true
+	}
+
+	for _, signer := range signers {
+		if !allowed[signer] {
+			return fmt.Errorf("account %s is not a signer of multisig %s", signer, from.AccountID)
+		}
+	}
+
+	if len(signers) < int(mc.Threshold) {
+		return fmt.Errorf("multisig %s needs %d signatures, got %d", from.AccountID, mc.Threshold, len(signers))
+	}
+
+	return nil
+}
+
+// ValidateSigners checks a transaction whose from account didn't produce its
+// signature is signed by enough of the signers of the multisig account it's
+// from.
+func (db *Database) ValidateSigners(tx SignedTx) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return tx.validateSigners(db.account(tx.FromID))
+}
+
+// =============================================================================
+
+// multisigHandler registers a new multisig account with the signers and
+// threshold in the transaction data.
+type multisigHandler struct{}
+
+// Name implements the TxHandler interface.
+func (multisigHandler) Name() string {
+	return "multisig"
+}
+
+// Fork implements the TxHandler interface.
+func (multisigHandler) Fork() string {
+	return genesis.ForkMultisig
+}
+
+// Validate implements the TxHandler interface.
+func (multisigHandler) Validate(rules genesis.Rules, tx Tx) error {
+	if tx.ToID != "" {
+		return errors.New("multisig transactions can't have a to account")
+	}
+
+	_, err := DecodeMultisigConfig(tx.Data)
+	return err
+}
+
+// Apply implements the TxHandler interface.
+func (multisigHandler) Apply(tc *TxContext) error {
+	mc, err := DecodeMultisigConfig(tc.Tx.Data)
+	if err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+
+	multisigID := MultisigAccountID(tc.From.AccountID, tc.Tx.Nonce)
+
+	account := tc.Account(multisigID)
+	if account.registered() {
+		return fmt.Errorf("transaction invalid, account %s already exists", multisigID)
+	}
+
+	balance, err := addBalance(tc.Rules, account.Balance, tc.Tx.Value)
+	if err != nil {
+		return fmt.Errorf("transaction invalid, multisig balance: %w", err)
+	}
+
+	// Store the canonical encoding so the account doesn't depend on how the
+	// sender encoded the data.
+	data, err := mc.Encode()
+	if err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+
+	account.Balance = balance
+	account.Multisig = hexutil.Encode(data)
+
+	tc.From.Balance -= tc.Tx.Value
+	tc.SetAccount(account)
+
+	return nil
+}
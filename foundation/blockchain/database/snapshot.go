@@ -0,0 +1,207 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/signature"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/smt"
+)
+
+// SnapshotChunkSize represents the maximum number of accounts in a chunk
+// of a snapshot shared with peers.
+const SnapshotChunkSize = 500
+
+// snapshotsPinned represents the number of snapshots kept for peers
+// downloading their chunks, so a download started at a block can finish
+// after the next block arrives.
+const snapshotsPinned = 2
+
+// ErrSnapshotMoved is returned when the snapshot of a block is requested
+// after it stopped being kept, so the peer needs to start over from the
+// snapshot of the latest block.
+var ErrSnapshotMoved = errors.New("snapshot moved")
+
+// errStateUnavailable is returned when the state the latest block header
+// commits to isn't known, which happens after a snapshot is installed and
+// before the block at the snapshot is applied.
+var errStateUnavailable = errors.New("state for the latest block header isn't available yet")
+
+// CORE NOTE: A node that deletes old transactions can't rebuild the account
+// state by replaying every block. Instead it keeps a snapshot of the accounts
+// a recent block was applied to. That is the state the block header commits
+// to with its state root, so the snapshot is checked against the header when
+// the node starts and the blocks from the snapshot forward are replayed. The
+// same snapshot lets a new node start from a peer's state instead of
+// replaying the whole chain, since it can be checked against a header from
+// the validated header chain.
+
+// Snapshot represents the account state the specified block is applied to.
+type Snapshot struct {
+	BlockNumber uint64             `json:"block_number"`
+	StateRoot   string             `json:"state_root"`
+	Accounts    []Account          `json:"accounts"`
+	Contracts   []ContractSnapshot `json:"contracts,omitempty"`
+}
+
+// newSnapshot constructs a snapshot from the set of accounts and contracts
+// sorted so the same state always produces the same snapshot.
+func newSnapshot(header BlockHeader, accounts map[AccountID]Account, contracts map[AccountID]Contract) Snapshot {
+	snapshot := Snapshot{
+		BlockNumber: header.Number,
+		StateRoot:   header.StateRoot,
+		Accounts:    make([]Account, 0, len(accounts)),
+	}
+
+	for _, account := range accounts {
+		snapshot.Accounts = append(snapshot.Accounts, account)
+	}
+	sort.Sort(byAccount(snapshot.Accounts))
+
+	for accountID, contract := range contracts {
+		snapshot.Contracts = append(snapshot.Contracts, newContractSnapshot(accountID, contract))
+	}
+	sort.Slice(snapshot.Contracts, func(i, j int) bool {
+		return snapshot.Contracts[i].AccountID < snapshot.Contracts[j].AccountID
+	})
+
+	return snapshot
+}
+
+// Verify checks the accounts in the snapshot produce the state root of the
+// specified block header.
+func (s Snapshot) Verify(genesis genesis.Genesis, header BlockHeader) error {
+	if s.BlockNumber != header.Number {
+		return fmt.Errorf("snapshot is for block %d, header is for block %d", s.BlockNumber, header.Number)
+	}
+
+	accounts := make(map[AccountID]Account, len(s.Accounts))
+	for _, account := range s.Accounts {
+		if _, exists := accounts[account.AccountID]; exists {
+			return fmt.Errorf("snapshot has account %s more than once", account.AccountID)
+		}
+		accounts[account.AccountID] = account
+	}
+
+	// The state root only covers the hashes in the contract accounts, so
+	// the code and storage need to match them.
+	contracts := make(map[AccountID]bool, len(s.Contracts))
+	for _, cs := range s.Contracts {
+		if contracts[cs.AccountID] {
+			return fmt.Errorf("snapshot has contract %s more than once", cs.AccountID)
+		}
+		contracts[cs.AccountID] = true
+
+		contract := cs.contract()
+		if len(contract.Storage) != len(cs.Storage) {
+			return fmt.Errorf("snapshot contract %s has a storage key more than once", cs.AccountID)
+		}
+
+		account := accounts[cs.AccountID]
+		if account.CodeHash != contract.CodeHash() || account.StorageRoot != contract.StorageRoot() {
+			return fmt.Errorf("snapshot contract %s doesn't match its account", cs.AccountID)
+		}
+	}
+
+	for _, account := range s.Accounts {
+		if account.CodeHash != "" && !contracts[account.AccountID] {
+			return fmt.Errorf("snapshot is missing the contract %s", account.AccountID)
+		}
+	}
+
+	root := stateRoot(genesis.Rules(header.Number), s.Accounts)
+	if root != header.StateRoot {
+		return fmt.Errorf("snapshot state root doesn't match the block header, got %s, exp %s", root, header.StateRoot)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// SnapshotChunk represents a part of a snapshot shared with peers.
+type SnapshotChunk struct {
+	BlockNumber uint64             `json:"block_number"`
+	StateRoot   string             `json:"state_root"`
+	Chunk       int                `json:"chunk"`
+	Chunks      int                `json:"chunks"`
+	Accounts    []Account          `json:"accounts"`
+	Contracts   []ContractSnapshot `json:"contracts,omitempty"`
+}
+
+// SnapshotAssembler puts the chunks of a snapshot back together.
+type SnapshotAssembler struct {
+	snapshot Snapshot
+	chunks   int
+	next     int
+}
+
+// Add appends the next chunk to the snapshot. The chunks must be added in
+// order and belong to the same snapshot.
+func (sa *SnapshotAssembler) Add(chunk SnapshotChunk) error {
+	if sa.next == 0 {
+		sa.snapshot = Snapshot{
+			BlockNumber: chunk.BlockNumber,
+			StateRoot:   chunk.StateRoot,
+		}
+		sa.chunks = chunk.Chunks
+	}
+
+	switch {
+	case chunk.BlockNumber != sa.snapshot.BlockNumber || chunk.StateRoot != sa.snapshot.StateRoot:
+		return fmt.Errorf("chunk is for block %d, snapshot is for block %d", chunk.BlockNumber, sa.snapshot.BlockNumber)
+	case chunk.Chunk != sa.next || chunk.Chunks != sa.chunks:
+		return fmt.Errorf("got chunk %d of %d, exp chunk %d of %d", chunk.Chunk, chunk.Chunks, sa.next, sa.chunks)
+	case len(chunk.Accounts) > SnapshotChunkSize:
+		return fmt.Errorf("chunk has %d accounts, the limit is %d", len(chunk.Accounts), SnapshotChunkSize)
+	}
+
+	sa.snapshot.Accounts = append(sa.snapshot.Accounts, chunk.Accounts...)
+	sa.snapshot.Contracts = append(sa.snapshot.Contracts, chunk.Contracts...)
+	sa.next++
+
+	return nil
+}
+
+// Done returns true once every chunk of the snapshot has been added.
+func (sa *SnapshotAssembler) Done() bool {
+	return sa.next > 0 && sa.next == sa.chunks
+}
+
+// Snapshot returns the assembled snapshot.
+func (sa *SnapshotAssembler) Snapshot() (Snapshot, error) {
+	if !sa.Done() {
+		return Snapshot{}, fmt.Errorf("snapshot is missing chunks, have %d of %d", sa.next, sa.chunks)
+	}
+
+	return sa.snapshot, nil
+}
+
+// =============================================================================
+
+// stateRoot calculates the state root for the set of accounts using the
+// method the rules require.
+func stateRoot(rules genesis.Rules, accounts []Account) string {
+	if rules.IsActive(genesis.ForkStateTrie) {
+		trie := smt.NewTree()
+		for _, account := range accounts {
+
+			// An account with malformed contract hashes is left out, which
+			// produces the wrong root.
+			value, err := account.Encode()
+			if err != nil {
+				continue
+			}
+			trie.Update(accountKey(account.AccountID), value)
+		}
+		return trie.RootHex()
+	}
+
+	sorted := make([]Account, len(accounts))
+	copy(sorted, accounts)
+	sort.Sort(byAccount(sorted))
+
+	return signature.Hash(sorted)
+}
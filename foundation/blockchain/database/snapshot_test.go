@@ -0,0 +1,99 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+)
+
+func TestSnapshotChunk(t *testing.T) {
+	gen := genesis.Genesis{
+		Date:          time.Date(2021, 12, 17, 0, 0, 0, 0, time.UTC),
+		ChainID:       1,
+		TransPerBlock: 10,
+		Difficulty:    1,
+		MiningReward:  700,
+		Balances:      map[string]uint64{pavel: 1000},
+	}
+	db := newTestDatabase(t, gen)
+
+	// balance returns pavel's balance in the chunk.
+	balance := func(sc database.SnapshotChunk) uint64 {
+		for _, account := range sc.Accounts {
+			if account.AccountID == pavel {
+				return account.Balance
+			}
+		}
+		return 0
+	}
+
+	// transfer applies a transfer from pavel in the specified block and
+	// makes it the latest block.
+	transfer := func(number uint64, nonce uint64) {
+		tx := database.Tx{ChainID: 1, Nonce: nonce, FromID: pavel, ToID: kennedy, Value: 100}
+		block := database.Block{Header: database.BlockHeader{Number: number}}
+
+		if err := db.ApplyTransaction(block, database.NewBlockTx(signTx(t, tx), 0, 0)); err != nil {
+			t.Fatalf("\t%s\tShould be able to apply the transaction in block %d : %s", failed, number, err)
+		}
+		db.UpdateLatestBlock(block)
+	}
+
+	t.Log("Given the need to serve the chunks of a snapshot while new blocks arrive.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a new block arrives during a download.", testID)
+		{
+			transfer(1, 1)
+
+			sc, err := db.SnapshotChunk(1, 0)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to get the chunk of the latest block : %s", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to get the chunk of the latest block.", success, testID)
+
+			transfer(2, 2)
+
+			sc, err = db.SnapshotChunk(1, 0)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould still serve the chunk of the pinned block : %s", failed, testID, err)
+			}
+			if got := balance(sc); got != 900 {
+				t.Fatalf("\t%s\tTest %d:\tShould serve the state of the pinned block : got %d, exp %d", failed, testID, got, 900)
+			}
+			t.Logf("\t%s\tTest %d:\tShould still serve the state of the pinned block.", success, testID)
+
+			sc, err = db.SnapshotChunk(2, 0)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to get the chunk of the new block : %s", failed, testID, err)
+			}
+			if got := balance(sc); got != 800 {
+				t.Fatalf("\t%s\tTest %d:\tShould serve the state of the new block : got %d, exp %d", failed, testID, got, 800)
+			}
+			t.Logf("\t%s\tTest %d:\tShould serve the state of the new block.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen the snapshot is no longer kept.", testID)
+		{
+			transfer(3, 3)
+
+			if _, err := db.SnapshotChunk(3, 0); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to get the chunk of the latest block : %s", failed, testID, err)
+			}
+
+			if _, err := db.SnapshotChunk(1, 0); !errors.Is(err, database.ErrSnapshotMoved) {
+				t.Fatalf("\t%s\tTest %d:\tShould return that the snapshot moved : %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould return that the snapshot moved.", success, testID)
+
+			if _, err := db.SnapshotChunk(4, 0); !errors.Is(err, database.ErrNotFound) {
+				t.Fatalf("\t%s\tTest %d:\tShould not find the snapshot of a future block : %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not find the snapshot of a future block.", success, testID)
+		}
+	}
+}
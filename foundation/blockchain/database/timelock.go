@@ -0,0 +1,193 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/amount"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// CORE NOTE: Once the lockedBalances fork is active the mining reward of a
+// block isn't added to the balance of the beneficiary right away. It's kept
+// in a time lock on the account until the maturity number of blocks from the
+// genesis have been mined on top of it. If the block is orphaned before that
+// the reward disappears with it, so a miner can never spend a reward from a
+// block that doesn't end up in the chain. The vesting entries of the genesis
+// are time locks the accounts start with. The balance of an account is what
+// it can spend, the time locks are kept next to it and are part of the
+// state root. At the end of every block the time locks that reached their
+// block are moved into the balance. The accounts are indexed by the block
+// their first time lock unlocks at, so only the accounts that are due are
+// looked at.
+
+// TimeLock represents an amount held by an account that can't be spent until
+// the specified block has been mined.
+type TimeLock struct {
+	Amount   uint64 `json:"amount"`
+	UnlockAt uint64 `json:"unlock_at"`
+}
+
+// TimeLocks returns the time locks of the account ordered by the block they
+// unlock at.
+func (a Account) TimeLocks() ([]TimeLock, error) {
+	if a.Locked == "" {
+		return nil, nil
+	}
+
+	data, err := hexutil.Decode(a.Locked)
+	if err != nil {
+		return nil, fmt.Errorf("time locks: %w", err)
+	}
+
+	var locks []TimeLock
+	if err := rlp.DecodeBytes(data, &locks); err != nil {
+		return nil, fmt.Errorf("decoding time locks: %w", err)
+	}
+
+	return locks, nil
+}
+
+// LockedBalance returns the amount the account holds in time locks.
+func (a Account) LockedBalance() (uint64, error) {
+	locks, err := a.TimeLocks()
+	if err != nil {
+		return 0, err
+	}
+
+	var locked uint64
+	for _, lock := range locks {
+		if locked, err = amount.Add(locked, lock.Amount); err != nil {
+			return 0, fmt.Errorf("locked balance: %w", err)
+		}
+	}
+
+	return locked, nil
+}
+
+// setTimeLocks encodes the time locks into the account.
+func (a *Account) setTimeLocks(locks []TimeLock) error {
+	if len(locks) == 0 {
+		a.Locked = ""
+		return nil
+	}
+
+	sort.Slice(locks, func(i, j int) bool {
+		return locks[i].UnlockAt < locks[j].UnlockAt
+	})
+
+	data, err := rlp.EncodeToBytes(locks)
+	if err != nil {
+		return err
+	}
+
+	a.Locked = hexutil.Encode(data)
+	return nil
+}
+
+// lockBalance adds the amount to the time lock of the account that unlocks
+// at the specified block.
+func (a *Account) lockBalance(rules genesis.Rules, amt uint64, unlockAt uint64) error {
+	locks, err := a.TimeLocks()
+	if err != nil {
+		return err
+	}
+
+	for i := range locks {
+		if locks[i].UnlockAt == unlockAt {
+			if locks[i].Amount, err = addBalance(rules, locks[i].Amount, amt); err != nil {
+				return fmt.Errorf("time lock: %w", err)
+			}
+			return a.setTimeLocks(locks)
+		}
+	}
+
+	return a.setTimeLocks(append(locks, TimeLock{Amount: amt, UnlockAt: unlockAt}))
+}
+
+// =============================================================================
+
+// UnlockBalances moves the time locks that unlock at or before the specified
+// block into the balance of their accounts. It's called after the block has
+// been applied. A failure to unlock an account is returned once every other
+// account has been unlocked.
+func (db *Database) UnlockBalances(block Block) error {
+	rules := db.genesis.Rules(block.Header.Number)
+	if !rules.IsActive(genesis.ForkLockedBalances) {
+		return nil
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var firstErr error
+	for _, accountID := range db.unlocks.due(block.Header.Number) {
+		account := db.accounts[accountID]
+
+		locks, err := account.TimeLocks()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("unlocking account %s: %w", accountID, err)
+			}
+			continue
+		}
+
+		// The locks are ordered by the block they unlock at.
+		balance := account.Balance
+		var unlocked int
+		for _, lock := range locks {
+			if lock.UnlockAt > block.Header.Number {
+				break
+			}
+
+			if balance, err = addBalance(rules, balance, lock.Amount); err != nil {
+				break
+			}
+			unlocked++
+		}
+
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("unlocking account %s, balance: %w", accountID, err)
+			}
+			continue
+		}
+
+		if unlocked == 0 {
+			continue
+		}
+
+		if err := account.setTimeLocks(locks[unlocked:]); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("unlocking account %s: %w", accountID, err)
+			}
+			continue
+		}
+		account.Balance = balance
+
+		db.setAccount(accountID, account)
+	}
+
+	return firstErr
+}
+
+// indexTimeLocks files the account under the block its first time lock
+// unlocks at. An account whose time locks can't be read is always due, so
+// the failure is reported at the end of every block. The caller must hold
+// the write lock.
+func (db *Database) indexTimeLocks(accountID AccountID, account Account) {
+	if account.Locked == "" {
+		db.unlocks.remove(accountID)
+		return
+	}
+
+	locks, err := account.TimeLocks()
+	if err != nil || len(locks) == 0 {
+		db.unlocks.set(accountID, 0)
+		return
+	}
+
+	db.unlocks.set(accountID, locks[0].UnlockAt)
+}
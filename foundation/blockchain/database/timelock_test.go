@@ -0,0 +1,110 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+)
+
+func TestUnlockBalances(t *testing.T) {
+	type balances struct {
+		balance uint64
+		locked  uint64
+	}
+
+	tt := []struct {
+		name     string
+		through  uint64
+		transfer uint64
+		valid    bool
+		exp      balances
+	}{
+		{
+			name:    "the block before the vesting unlocks",
+			through: 2,
+			exp:     balances{balance: 0, locked: 1200},
+		},
+		{
+			name:    "the block the vesting unlocks at",
+			through: 3,
+			exp:     balances{balance: 500, locked: 700},
+		},
+		{
+			name:    "the block the reward matures at",
+			through: 4,
+			exp:     balances{balance: 1200, locked: 0},
+		},
+		{
+			name:     "spending the reward the block before it matures",
+			through:  3,
+			transfer: 600,
+			exp:      balances{balance: 500, locked: 700},
+		},
+		{
+			name:     "spending the reward once it matured",
+			through:  4,
+			transfer: 600,
+			valid:    true,
+			exp:      balances{balance: 600, locked: 0},
+		},
+	}
+
+	t.Log("Given the need to keep vesting balances and mining rewards locked until their block.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen unlocking through %s.", testID, test.name)
+			{
+				gen := genesis.Genesis{
+					Date:          time.Date(2021, 12, 17, 0, 0, 0, 0, time.UTC),
+					ChainID:       1,
+					TransPerBlock: 10,
+					Difficulty:    1,
+					MiningReward:  700,
+					Maturity:      3,
+					Balances:      map[string]uint64{},
+					Vesting:       []genesis.Vesting{{Account: pavel, Amount: 500, UnlockAt: 3}},
+					Forks:         map[string]uint64{genesis.ForkLockedBalances: 0},
+				}
+				db := newTestDatabase(t, gen)
+
+				// The reward of block 1 matures once block 4 has been mined.
+				reward := database.Block{Header: database.BlockHeader{Number: 1, BeneficiaryID: pavel, MiningReward: 700}}
+				if err := db.ApplyMiningReward(reward); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to apply the mining reward : %s", failed, testID, err)
+				}
+
+				for number := uint64(1); number <= test.through; number++ {
+					if err := db.UnlockBalances(database.Block{Header: database.BlockHeader{Number: number}}); err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to unlock the balances of block %d : %s", failed, testID, number, err)
+					}
+				}
+
+				if test.transfer > 0 {
+					tx := database.Tx{ChainID: 1, Nonce: 1, FromID: pavel, ToID: kennedy, Value: test.transfer}
+					block := database.Block{Header: database.BlockHeader{Number: test.through + 1}}
+
+					err := db.ApplyTransaction(block, database.NewBlockTx(signTx(t, tx), 0, 0))
+					switch {
+					case test.valid && err != nil:
+						t.Fatalf("\t%s\tTest %d:\tShould be able to spend the balance : %s", failed, testID, err)
+					case !test.valid && err == nil:
+						t.Fatalf("\t%s\tTest %d:\tShould fail to spend the locked balance.", failed, testID)
+					}
+					t.Logf("\t%s\tTest %d:\tShould spend the balance is %v.", success, testID, test.valid)
+				}
+
+				account := db.CopyAccounts()[pavel]
+				locked, err := account.LockedBalance()
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to read the locked balance : %s", failed, testID, err)
+				}
+				if got := (balances{balance: account.Balance, locked: locked}); got != test.exp {
+					t.Fatalf("\t%s\tTest %d:\tShould leave the expected balances : got %+v, exp %+v", failed, testID, got, test.exp)
+				}
+				t.Logf("\t%s\tTest %d:\tShould leave the expected balances.", success, testID)
+			}
+		}
+	}
+}
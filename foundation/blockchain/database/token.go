@@ -0,0 +1,598 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/amount"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// CORE NOTE: Once the tokens fork is active an issue transaction creates a
+// new fungible token and credits its whole supply to the issuer. The token
+// lives in its own account, whose address comes from the issuer and the
+// nonce like a contract, and that account stores the issuer, symbol,
+// decimals and supply. Token amounts can be larger than 64 bits, so they use
+// amount.Amount instead of the uint64 of the native balance. Every account
+// stores the token balances it holds next to its native balance, so both the
+// token supply and the balances are covered by the state root. A transfer
+// moves tokens between accounts and a burn destroys tokens held by the
+// sender and lowers the supply. Token transactions don't move any of the
+// native balance, but they pay gas and tips like any other transaction.
+
+// Set of transaction types that issue and move tokens.
+const (
+	TxTypeTokenIssue    TxType = 4
+	TxTypeTokenTransfer TxType = 5
+	TxTypeTokenBurn     TxType = 6
+)
+
+// Limits on the values of a token.
+const (
+	MaxTokenSymbol   = 12
+	MaxTokenDecimals = 18
+	maxTokenBits     = 256
+)
+
+// Token represents a fungible token issued on the chain.
+type Token struct {
+	Issuer   AccountID     `json:"issuer"`
+	Symbol   string        `json:"symbol"`
+	Decimals uint8         `json:"decimals"`
+	Supply   amount.Amount `json:"supply"`
+}
+
+// rlpToken is the canonical layout of a token stored in its account.
+type rlpToken struct {
+	Issuer   []byte
+	Symbol   string
+	Decimals uint8
+	Supply   *big.Int
+}
+
+// Denomination returns how amounts of the token are presented.
+func (t Token) Denomination() amount.Denomination {
+	return amount.Denomination{
+		Symbol:   t.Symbol,
+		Decimals: t.Decimals,
+	}
+}
+
+// encode returns the canonical bytes of the token.
+func (t Token) encode() ([]byte, error) {
+	rt := rlpToken{
+		Issuer:   accountBytes(t.Issuer),
+		Symbol:   t.Symbol,
+		Decimals: t.Decimals,
+		Supply:   t.Supply.Big(),
+	}
+
+	return rlp.EncodeToBytes(rt)
+}
+
+// decodeToken converts the canonical bytes back into the token.
+func decodeToken(data []byte) (Token, error) {
+	var rt rlpToken
+	if err := rlp.DecodeBytes(data, &rt); err != nil {
+		return Token{}, fmt.Errorf("decoding token: %w", err)
+	}
+
+	supply, err := amount.FromBig(rt.Supply)
+	if err != nil {
+		return Token{}, fmt.Errorf("token supply: %w", err)
+	}
+
+	t := Token{
+		Issuer:   AccountID(common.BytesToAddress(rt.Issuer).Hex()),
+		Symbol:   rt.Symbol,
+		Decimals: rt.Decimals,
+		Supply:   supply,
+	}
+
+	return t, nil
+}
+
+// TokenAccountID returns the account of the token created by the issue
+// transaction with the specified sender and nonce.
+func TokenAccountID(fromID AccountID, nonce uint64) AccountID {
+	return ContractAccountID(fromID, nonce)
+}
+
+// TokenInfo returns the token stored in a token account and false when the
+// account isn't a token account.
+func (a Account) TokenInfo() (Token, bool) {
+	if a.Token == "" {
+		return Token{}, false
+	}
+
+	data, err := hexutil.Decode(a.Token)
+	if err != nil {
+		return Token{}, false
+	}
+
+	t, err := decodeToken(data)
+	if err != nil {
+		return Token{}, false
+	}
+
+	return t, true
+}
+
+// setTokenInfo stores the token in the token account.
+func (a *Account) setTokenInfo(t Token) error {
+	data, err := t.encode()
+	if err != nil {
+		return err
+	}
+
+	a.Token = hexutil.Encode(data)
+	return nil
+}
+
+// =============================================================================
+
+// TokenBalance represents the amount of a token held by an account.
+type TokenBalance struct {
+	Token   AccountID     `json:"token"`
+	Balance amount.Amount `json:"balance"`
+}
+
+// rlpTokenBalance is the canonical layout of a token balance stored in the
+// account holding it.
+type rlpTokenBalance struct {
+	Token   []byte
+	Balance *big.Int
+}
+
+// TokenBalances returns the token balances held by the account sorted by
+// token.
+func (a Account) TokenBalances() ([]TokenBalance, error) {
+	if a.Tokens == "" {
+		return nil, nil
+	}
+
+	data, err := hexutil.Decode(a.Tokens)
+	if err != nil {
+		return nil, fmt.Errorf("token balances: %w", err)
+	}
+
+	var rtbs []rlpTokenBalance
+	if err := rlp.DecodeBytes(data, &rtbs); err != nil {
+		return nil, fmt.Errorf("decoding token balances: %w", err)
+	}
+
+	tbs := make([]TokenBalance, len(rtbs))
+	for i, rtb := range rtbs {
+		balance, err := amount.FromBig(rtb.Balance)
+		if err != nil {
+			return nil, fmt.Errorf("token balance: %w", err)
+		}
+
+		tbs[i] = TokenBalance{
+			Token:   AccountID(common.BytesToAddress(rtb.Token).Hex()),
+			Balance: balance,
+		}
+	}
+
+	return tbs, nil
+}
+
+// TokenBalance returns the amount of the specified token held by the
+// account.
+func (a Account) TokenBalance(token AccountID) (amount.Amount, error) {
+	tbs, err := a.TokenBalances()
+	if err != nil {
+		return amount.Amount{}, err
+	}
+
+	for _, tb := range tbs {
+		if tb.Token == token {
+			return tb.Balance, nil
+		}
+	}
+
+	return amount.Amount{}, nil
+}
+
+// setTokenBalance stores the amount of the specified token held by the
+// account. Tokens with a zero balance are removed so the encoding of an
+// account doesn't depend on the tokens it held in the past.
+func (a *Account) setTokenBalance(token AccountID, balance amount.Amount) error {
+	tbs, err := a.TokenBalances()
+	if err != nil {
+		return err
+	}
+
+	updated := make([]TokenBalance, 0, len(tbs)+1)
+	for _, tb := range tbs {
+		if tb.Token != token {
+			updated = append(updated, tb)
+		}
+	}
+	if !balance.IsZero() {
+		updated = append(updated, TokenBalance{Token: token, Balance: balance})
+	}
+
+	if len(updated) == 0 {
+		a.Tokens = ""
+		return nil
+	}
+
+	rtbs := make([]rlpTokenBalance, len(updated))
+	for i, tb := range updated {
+		rtbs[i] = rlpTokenBalance{
+			Token:   accountBytes(tb.Token),
+			Balance: tb.Balance.Big(),
+		}
+	}
+	sort.Slice(rtbs, func(i, j int) bool {
+		return bytes.Compare(rtbs[i].Token, rtbs[j].Token) < 0
+	})
+
+	data, err := rlp.EncodeToBytes(rtbs)
+	if err != nil {
+		return err
+	}
+
+	a.Tokens = hexutil.Encode(data)
+	return nil
+}
+
+// =============================================================================
+
+// TokenIssue represents the data of an issue transaction.
+type TokenIssue struct {
+	Symbol   string
+	Decimals uint8
+	Supply   amount.Amount
+}
+
+// rlpTokenIssue is the canonical layout of the data of an issue
+// transaction.
+type rlpTokenIssue struct {
+	Symbol   string
+	Decimals uint8
+	Supply   *big.Int
+}
+
+// Encode returns the canonical bytes of the issue, which are the data of the
+// issue transaction.
+func (ti TokenIssue) Encode() ([]byte, error) {
+	rti := rlpTokenIssue{
+		Symbol:   ti.Symbol,
+		Decimals: ti.Decimals,
+		Supply:   ti.Supply.Big(),
+	}
+
+	return rlp.EncodeToBytes(rti)
+}
+
+// Validate checks the values of the token being issued.
+func (ti TokenIssue) Validate() error {
+	if len(ti.Symbol) == 0 || len(ti.Symbol) > MaxTokenSymbol {
+		return fmt.Errorf("token symbol must be between 1 and %d characters, got %d", MaxTokenSymbol, len(ti.Symbol))
+	}
+
+	for _, c := range ti.Symbol {
+		if !('A' <= c && c <= 'Z') && !('0' <= c && c <= '9') {
+			return fmt.Errorf("token symbol %q can only hold upper case letters and digits", ti.Symbol)
+		}
+	}
+
+	if ti.Decimals > MaxTokenDecimals {
+		return fmt.Errorf("token decimals must be at most %d, got %d", MaxTokenDecimals, ti.Decimals)
+	}
+
+	if ti.Supply.IsZero() {
+		return errors.New("token supply must be more than zero")
+	}
+
+	if ti.Supply.Big().BitLen() > maxTokenBits {
+		return fmt.Errorf("token supply must fit in %d bits", maxTokenBits)
+	}
+
+	return nil
+}
+
+// DecodeTokenIssue converts the data of an issue transaction back into the
+// issue and validates it.
+func DecodeTokenIssue(data []byte) (TokenIssue, error) {
+	var rti rlpTokenIssue
+	if err := rlp.DecodeBytes(data, &rti); err != nil {
+		return TokenIssue{}, fmt.Errorf("decoding token issue: %w", err)
+	}
+
+	supply, err := amount.FromBig(rti.Supply)
+	if err != nil {
+		return TokenIssue{}, fmt.Errorf("token supply: %w", err)
+	}
+
+	ti := TokenIssue{
+		Symbol:   rti.Symbol,
+		Decimals: rti.Decimals,
+		Supply:   supply,
+	}
+
+	if err := ti.Validate(); err != nil {
+		return TokenIssue{}, err
+	}
+
+	return ti, nil
+}
+
+// =============================================================================
+
+// TokenAmount represents the data of a transfer or burn transaction.
+type TokenAmount struct {
+	Token  AccountID
+	Amount amount.Amount
+}
+
+// rlpTokenAmount is the canonical layout of the data of a transfer or burn
+// transaction.
+type rlpTokenAmount struct {
+	Token  []byte
+	Amount *big.Int
+}
+
+// Encode returns the canonical bytes of the amount, which are the data of
+// the transfer or burn transaction.
+func (ta TokenAmount) Encode() ([]byte, error) {
+	rta := rlpTokenAmount{
+		Token:  accountBytes(ta.Token),
+		Amount: ta.Amount.Big(),
+	}
+
+	return rlp.EncodeToBytes(rta)
+}
+
+// DecodeTokenAmount converts the data of a transfer or burn transaction
+// back into the amount and validates it.
+func DecodeTokenAmount(data []byte) (TokenAmount, error) {
+	var rta rlpTokenAmount
+	if err := rlp.DecodeBytes(data, &rta); err != nil {
+		return TokenAmount{}, fmt.Errorf("decoding token amount: %w", err)
+	}
+
+	if len(rta.Token) != common.AddressLength {
+		return TokenAmount{}, fmt.Errorf("token must be %d bytes", common.AddressLength)
+	}
+
+	amt, err := amount.FromBig(rta.Amount)
+	if err != nil {
+		return TokenAmount{}, fmt.Errorf("token amount: %w", err)
+	}
+
+	if amt.IsZero() {
+		return TokenAmount{}, errors.New("token amount must be more than zero")
+	}
+
+	if amt.Big().BitLen() > maxTokenBits {
+		return TokenAmount{}, fmt.Errorf("token amount must fit in %d bits", maxTokenBits)
+	}
+
+	ta := TokenAmount{
+		Token:  AccountID(common.BytesToAddress(rta.Token).Hex()),
+		Amount: amt,
+	}
+
+	return ta, nil
+}
+
+// =============================================================================
+
+// tokenIssueHandler creates a new token and credits its supply to the
+// issuer.
+type tokenIssueHandler struct{}
+
+// Name implements the TxHandler interface.
+func (tokenIssueHandler) Name() string {
+	return "tokenIssue"
+}
+
+// Fork implements the TxHandler interface.
+func (tokenIssueHandler) Fork() string {
+	return genesis.ForkTokens
+}
+
+// Validate implements the TxHandler interface.
+func (tokenIssueHandler) Validate(rules genesis.Rules, tx Tx) error {
+	if tx.ToID != "" {
+		return errors.New("token issue transactions can't have a to account")
+	}
+
+	if tx.Value != 0 {
+		return errors.New("token transactions can't send a value")
+	}
+
+	_, err := DecodeTokenIssue(tx.Data)
+	return err
+}
+
+// Apply implements the TxHandler interface.
+func (tokenIssueHandler) Apply(tc *TxContext) error {
+	ti, err := DecodeTokenIssue(tc.Tx.Data)
+	if err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+
+	tokenID := TokenAccountID(tc.From.AccountID, tc.Tx.Nonce)
+
+	account := tc.Account(tokenID)
+	if account.registered() {
+		return fmt.Errorf("transaction invalid, account %s already exists", tokenID)
+	}
+
+	token := Token{
+		Issuer:   tc.From.AccountID,
+		Symbol:   ti.Symbol,
+		Decimals: ti.Decimals,
+		Supply:   ti.Supply,
+	}
+	if err := account.setTokenInfo(token); err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+
+	from := tc.From
+	if err := from.setTokenBalance(tokenID, ti.Supply); err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+
+	tc.From = from
+	tc.SetAccount(account)
+
+	return nil
+}
+
+// =============================================================================
+
+// tokenTransferHandler moves tokens from the sender to the to account.
+type tokenTransferHandler struct{}
+
+// Name implements the TxHandler interface.
+func (tokenTransferHandler) Name() string {
+	return "tokenTransfer"
+}
+
+// Fork implements the TxHandler interface.
+func (tokenTransferHandler) Fork() string {
+	return genesis.ForkTokens
+}
+
+// Validate implements the TxHandler interface.
+func (tokenTransferHandler) Validate(rules genesis.Rules, tx Tx) error {
+	if !tx.ToID.IsAccountID() {
+		return errors.New("to account is not properly formatted")
+	}
+
+	if tx.FromID == tx.ToID {
+		return fmt.Errorf("sending tokens to yourself, from %s, to %s", tx.FromID, tx.ToID)
+	}
+
+	if tx.Value != 0 {
+		return errors.New("token transactions can't send a value")
+	}
+
+	_, err := DecodeTokenAmount(tx.Data)
+	return err
+}
+
+// Apply implements the TxHandler interface.
+func (tokenTransferHandler) Apply(tc *TxContext) error {
+	ta, err := DecodeTokenAmount(tc.Tx.Data)
+	if err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+
+	if _, isToken := tc.Account(ta.Token).TokenInfo(); !isToken {
+		return fmt.Errorf("transaction invalid, account %s is not a token", ta.Token)
+	}
+
+	from := tc.From
+	to := tc.Account(tc.Tx.ToID)
+
+	fromBalance, err := from.TokenBalance(ta.Token)
+	if err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+
+	if fromBalance, err = fromBalance.Sub(ta.Amount); err != nil {
+		return fmt.Errorf("transaction invalid, insufficient %s token balance", ta.Token)
+	}
+
+	toBalance, err := to.TokenBalance(ta.Token)
+	if err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+
+	if err := from.setTokenBalance(ta.Token, fromBalance); err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+	if err := to.setTokenBalance(ta.Token, toBalance.Add(ta.Amount)); err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+
+	tc.From = from
+	tc.SetAccount(to)
+
+	return nil
+}
+
+// =============================================================================
+
+// tokenBurnHandler destroys tokens held by the sender and lowers the supply
+// of the token.
+type tokenBurnHandler struct{}
+
+// Name implements the TxHandler interface.
+func (tokenBurnHandler) Name() string {
+	return "tokenBurn"
+}
+
+// Fork implements the TxHandler interface.
+func (tokenBurnHandler) Fork() string {
+	return genesis.ForkTokens
+}
+
+// Validate implements the TxHandler interface.
+func (tokenBurnHandler) Validate(rules genesis.Rules, tx Tx) error {
+	if tx.ToID != "" {
+		return errors.New("token burn transactions can't have a to account")
+	}
+
+	if tx.Value != 0 {
+		return errors.New("token transactions can't send a value")
+	}
+
+	_, err := DecodeTokenAmount(tx.Data)
+	return err
+}
+
+// Apply implements the TxHandler interface.
+func (tokenBurnHandler) Apply(tc *TxContext) error {
+	ta, err := DecodeTokenAmount(tc.Tx.Data)
+	if err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+
+	account := tc.Account(ta.Token)
+	token, isToken := account.TokenInfo()
+	if !isToken {
+		return fmt.Errorf("transaction invalid, account %s is not a token", ta.Token)
+	}
+
+	from := tc.From
+
+	balance, err := from.TokenBalance(ta.Token)
+	if err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+
+	if balance, err = balance.Sub(ta.Amount); err != nil {
+		return fmt.Errorf("transaction invalid, insufficient %s token balance", ta.Token)
+	}
+
+	// The supply is the sum of every balance, so it can't be lower than
+	// what the sender holds.
+	if token.Supply, err = token.Supply.Sub(ta.Amount); err != nil {
+		return fmt.Errorf("transaction invalid, token supply: %w", err)
+	}
+
+	if err := from.setTokenBalance(ta.Token, balance); err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+	if err := account.setTokenInfo(token); err != nil {
+		return fmt.Errorf("transaction invalid, %w", err)
+	}
+
+	tc.From = from
+	tc.SetAccount(account)
+
+	return nil
+}
@@ -3,26 +3,38 @@ package database
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/big"
 	"time"
 
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/amount"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/merkle"
 	"github.com/PhyoYazar/blockchain/foundation/blockchain/signature"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
 // =============================================================================
 
 // Tx is the transactional information between two parties.
 type Tx struct {
-	ChainID uint16    `json:"chain_id"` // Ethereum: The chain id that is listed in the genesis file.
-	Nonce   uint64    `json:"nonce"`    // Ethereum: Unique id for the transaction supplied by the user.
-	FromID  AccountID `json:"from"`     // Ethereum: Account sending the transaction. Will be checked against signature.
-	ToID    AccountID `json:"to"`       // Ethereum: Account receiving the benefit of the transaction.
-	Value   uint64    `json:"value"`    // Ethereum: Monetary value received from this transaction.
-	Tip     uint64    `json:"tip"`      // Ethereum: Tip offered by the sender as an incentive to mine this transaction.
-	Data    []byte    `json:"data"`     // Ethereum: Extra data related to the transaction.
+	ChainID    uint16    `json:"chain_id"`              // Ethereum: The chain id that is listed in the genesis file.
+	Nonce      uint64    `json:"nonce"`                 // Ethereum: Unique id for the transaction supplied by the user.
+	FromID     AccountID `json:"from"`                  // Ethereum: Account sending the transaction. Will be checked against signature.
+	ToID       AccountID `json:"to"`                    // Ethereum: Account receiving the benefit of the transaction.
+	Value      uint64    `json:"value"`                 // Ethereum: Monetary value received from this transaction.
+	Tip        uint64    `json:"tip"`                   // Ethereum: Tip offered by the sender as an incentive to mine this transaction.
+	Data       []byte    `json:"data"`                  // Ethereum: Extra data related to the transaction.
+	Encoding   Encoding  `json:"encoding,omitempty"`    // Ardan: How the transaction is encoded for signing and hashing.
+	GasLimit   uint64    `json:"gas_limit,omitempty"`   // Ethereum: Maximum units of gas the sender agrees to pay for.
+	MaxFee     uint64    `json:"max_fee,omitempty"`     // Ethereum: Maximum base fee per unit of gas the sender agrees to pay.
+	Type       TxType    `json:"type,omitempty"`        // Ethereum: Type of transaction, which selects how the payload is applied.
+	FeePayerID AccountID `json:"fee_payer,omitempty"`   // Ardan: Account paying the gas and tip instead of the sender.
+	ValidAfter uint64    `json:"valid_after,omitempty"` // Ardan: Block number, not a timestamp, the transaction can only be mined after.
+	ValidUntil uint64    `json:"valid_until,omitempty"` // Ardan: Number of the last block, not a timestamp, the transaction can be mined in.
 }
 
 // NewTx constructs a new transaction.
@@ -50,8 +62,14 @@ func NewTx(chainID uint16, nonce uint64, fromID AccountID, toID AccountID, value
 // Sign uses the specified private key to sign the transaction.
 func (tx Tx) Sign(privateKey *ecdsa.PrivateKey) (SignedTx, error) {
 
+	// Encode the transaction based on the selected encoding.
+	data, err := tx.Encode()
+	if err != nil {
+		return SignedTx{}, err
+	}
+
 	// Sign the transaction with the private key to produce a signature.
-	v, r, s, err := signature.Sign(tx, privateKey)
+	v, r, s, err := signature.SignBytes(data, privateKey)
 	if err != nil {
 		return SignedTx{}, err
 	}
@@ -68,59 +86,177 @@ func (tx Tx) Sign(privateKey *ecdsa.PrivateKey) (SignedTx, error) {
 	return signedTx, nil
 }
 
+// HasAccount reports whether the specified account sends, receives or pays
+// for the transaction, including the accounts paid by a batch transfer.
+func (tx Tx) HasAccount(accountID AccountID) bool {
+	id := accountBytes(accountID)
+	if tx.FeePayerID != "" && bytes.Equal(accountBytes(tx.FeePayerID), id) {
+		return true
+	}
+
+	for _, recipient := range tx.batchRecipients() {
+		if bytes.Equal(accountBytes(recipient), id) {
+			return true
+		}
+	}
+
+	return bytes.Equal(accountBytes(tx.FromID), id) || bytes.Equal(accountBytes(tx.ToID), id)
+}
+
 // =============================================================================
 
 // SignedTx is a signed version of the transaction. This is how clients like
 // a wallet provide transactions for inclusion into the blockchain.
 type SignedTx struct {
 	Tx
-	V *big.Int `json:"v"` // Ethereum: Recovery identifier, either 29 or 30 with ardanID.
-	R *big.Int `json:"r"` // Ethereum: First coordinate of the ECDSA signature.
-	S *big.Int `json:"s"` // Ethereum: Second coordinate of the ECDSA signature.
+	V          *big.Int    `json:"v"`                             // Ethereum: Recovery identifier, either 29 or 30 with ardanID.
+	R          *big.Int    `json:"r"`                             // Ethereum: First coordinate of the ECDSA signature.
+	S          *big.Int    `json:"s"`                             // Ethereum: Second coordinate of the ECDSA signature.
+	Signatures []Signature `json:"signatures,omitempty"`          // Ardan: Signatures of the other signers of a multisig account.
+	FeePayer   *Signature  `json:"fee_payer_signature,omitempty"` // Ardan: Signature of the fee payer.
 }
 
 // Validate verifies the transaction has a proper signature that conforms to our
 // standards. It also checks the from field matches the account that signed the
-// transaction. Last it checks the format of the from and to fields.
-func (tx SignedTx) Validate(chainID uint16) error {
-	if tx.ChainID != chainID {
-		return fmt.Errorf("invalid chain id, got[%d] exp[%d]", tx.ChainID, chainID)
+// transaction, unless the transaction is from a multisig account, where every
+// signature is checked and the signers are checked against the state later.
+// A fee payer named by the transaction must have signed it as well. Last it
+// checks the format of the from field, the payload of the type of
+// transaction and that the transaction only uses features the specified
+// rules allow.
+func (tx SignedTx) Validate(rules genesis.Rules) error {
+	if tx.ChainID != rules.ChainID {
+		return fmt.Errorf("invalid chain id, got[%d] exp[%d]", tx.ChainID, rules.ChainID)
+	}
+
+	if err := tx.validateEncoding(rules); err != nil {
+		return err
 	}
 
 	if !tx.FromID.IsAccountID() {
 		return errors.New("from account is not properly formatted")
 	}
 
-	if !tx.ToID.IsAccountID() {
-		return errors.New("to account is not properly formatted")
+	if err := tx.validateChecksums(rules); err != nil {
+		return err
+	}
+
+	if err := tx.validateType(rules); err != nil {
+		return err
+	}
+
+	if _, err := amount.Add(tx.Value, tx.Tip); err != nil {
+		return fmt.Errorf("transaction invalid, value plus tip: %w", err)
+	}
+
+	if err := tx.validateGas(rules); err != nil {
+		return err
 	}
 
-	if tx.FromID == tx.ToID {
-		return fmt.Errorf("transaction invalid, sending money to yourself, from %s, to %s", tx.FromID, tx.ToID)
+	if !rules.IsActive(genesis.ForkBaseFee) && tx.MaxFee != 0 {
+		return fmt.Errorf("max fee is not allowed before the %s fork", genesis.ForkBaseFee)
+	}
+
+	if err := tx.validateFeePayer(rules); err != nil {
+		return err
+	}
+
+	if err := tx.validateWindow(rules); err != nil {
+		return err
 	}
 
 	if err := signature.VerifySignature(tx.V, tx.R, tx.S); err != nil {
 		return err
 	}
 
-	address, err := signature.FromAddress(tx.Tx, tx.V, tx.R, tx.S)
+	address, err := tx.FromAccount()
 	if err != nil {
 		return err
 	}
 
-	if address != string(tx.FromID) {
-		return errors.New("signature address doesn't match from address")
+	if !rules.IsActive(genesis.ForkMultisig) {
+		if len(tx.Signatures) > 0 {
+			return fmt.Errorf("multiple signatures are not allowed before the %s fork", genesis.ForkMultisig)
+		}
+
+		if address != tx.FromID {
+			return errors.New("signature address doesn't match from address")
+		}
+
+		return nil
+	}
+
+	// A transaction from a multisig account isn't signed by the from
+	// account. The signatures are checked here and the signers are checked
+	// against the account when the state is available.
+	if address == tx.FromID {
+		if len(tx.Signatures) > 0 {
+			return errors.New("only transactions from a multisig account can carry more than one signature")
+		}
+		return nil
 	}
 
-	return nil
+	_, err = tx.Signers()
+	return err
 }
 
 // FromAccount extracts the account id that signed the transaction.
 func (tx SignedTx) FromAccount() (AccountID, error) {
-	address, err := signature.FromAddress(tx.Tx, tx.V, tx.R, tx.S)
+	data, err := tx.Tx.Encode()
+	if err != nil {
+		return "", err
+	}
+
+	address, err := signature.FromAddressBytes(data, tx.V, tx.R, tx.S)
 	return AccountID(address), err
 }
 
+// validateEncoding checks the encoding used to sign the transaction is
+// allowed by the specified rules.
+func (tx SignedTx) validateEncoding(rules genesis.Rules) error {
+	switch tx.Encoding {
+	case EncodingJSON:
+		return nil
+
+	case EncodingCanonical:
+		if !rules.IsActive(genesis.ForkCanonicalEncoding) {
+			return fmt.Errorf("canonical encoding is not active until the %s fork", genesis.ForkCanonicalEncoding)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown encoding %d", tx.Encoding)
+}
+
+// validateChecksums checks the accounts named by the transaction are in
+// checksum form once the stateTrie fork is active. The trie keys accounts by
+// their bytes, so a recased account would be a second entry in the state
+// writing to the same leaf. A canonical transaction is always checked, since
+// its encoding only covers the bytes of the accounts and anyone relaying it
+// could recase an account without breaking the signature.
+func (tx SignedTx) validateChecksums(rules genesis.Rules) error {
+	if !rules.IsActive(genesis.ForkStateTrie) && tx.Encoding != EncodingCanonical {
+		return nil
+	}
+
+	accounts := []struct {
+		name string
+		id   AccountID
+	}{
+		{"from", tx.FromID},
+		{"to", tx.ToID},
+		{"fee payer", tx.FeePayerID},
+	}
+
+	for _, account := range accounts {
+		if account.id != "" && account.id.IsAccountID() && !account.id.IsChecksummed() {
+			return fmt.Errorf("%s account %s is not in checksum form", account.name, account.id)
+		}
+	}
+
+	return nil
+}
+
 // SignatureString returns the signature as a string.
 func (tx SignedTx) SignatureString() string {
 	return signature.SignatureString(tx.V, tx.R, tx.S)
@@ -137,9 +273,10 @@ func (tx SignedTx) String() string {
 // includes a timestamp and gas fees.
 type BlockTx struct {
 	SignedTx
-	TimeStamp uint64 `json:"timestamp"` // Ethereum: The time the transaction was received.
-	GasPrice  uint64 `json:"gas_price"` // Ethereum: The price of one unit of gas to be paid for fees.
-	GasUnits  uint64 `json:"gas_units"` // Ethereum: The number of units of gas used for this transaction.
+	TimeStamp         uint64 `json:"timestamp"`                     // Ethereum: The time the transaction was received.
+	GasPrice          uint64 `json:"gas_price"`                     // Ethereum: The price of one unit of gas to be paid for fees.
+	GasUnits          uint64 `json:"gas_units"`                     // Ethereum: The number of units of gas used for this transaction.
+	EffectiveGasPrice uint64 `json:"effective_gas_price,omitempty"` // Ethereum: The price of one unit of gas paid in the block, a receipt that isn't hashed.
 }
 
 // NewBlockTx constructs a new block transaction.
@@ -155,7 +292,12 @@ func NewBlockTx(signedTx SignedTx, gasPrice uint64, unitsOfGas uint64) BlockTx {
 // Hash implements the merkle Hashable interface for providing a hash
 // of a block transaction.
 func (tx BlockTx) Hash() ([]byte, error) {
-	str := signature.Hash(tx)
+	data, err := tx.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	str := signature.HashBytes(data)
 
 	// Need to remove the 0x prefix from the hash.
 	return hex.DecodeString(str[2:])
@@ -170,3 +312,108 @@ func (tx BlockTx) Equals(otherTx BlockTx) bool {
 
 	return tx.Nonce == otherTx.Nonce && bytes.Equal(txSig, otherTxSig)
 }
+
+// =============================================================================
+
+// TxProof represents a transaction along with the proof it's included in
+// the block with the specified header.
+type TxProof struct {
+	Tx         BlockTx
+	LeafHash   []byte
+	BlockHash  string
+	Header     BlockHeader
+	Root       []byte
+	Proof      [][]byte
+	ProofOrder []int64
+	Hardened   bool
+}
+
+// Verify checks the proof shows the transaction is part of the block. Only
+// the values in the proof and the genesis are used, so this can be run by a
+// wallet or light client that doesn't have the block. The hardened mode comes
+// from the fork rules of the block, never from the proof.
+func (tp TxProof) Verify(genesis genesis.Genesis) error {
+	hash, err := tp.Tx.Hash()
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(hash, tp.LeafHash) {
+		return errors.New("transaction doesn't match the leaf hash")
+	}
+
+	hardened, err := verifyProofBlock(genesis, tp.BlockHash, tp.Header, tp.Root, tp.Hardened)
+	if err != nil {
+		return err
+	}
+
+	if hardened {
+		return merkle.VerifyHardenedProof(tp.LeafHash, tp.Proof, tp.ProofOrder, tp.Root, sha256.New)
+	}
+
+	return merkle.VerifyProof(tp.LeafHash, tp.Proof, tp.ProofOrder, tp.Root, sha256.New)
+}
+
+// TxMultiProof represents a set of transactions from the same block along
+// with a single proof they are all included in that block.
+type TxMultiProof struct {
+	Trans      []BlockTx
+	LeafHashes [][]byte
+	BlockHash  string
+	Header     BlockHeader
+	Root       []byte
+	Proof      merkle.MultiProof
+	Hardened   bool
+}
+
+// Verify checks the proof shows every transaction is part of the block. Only
+// the values in the proof and the genesis are used, so this can be run by a
+// wallet or light client that doesn't have the block. The hardened mode comes
+// from the fork rules of the block, never from the proof.
+func (tmp TxMultiProof) Verify(genesis genesis.Genesis) error {
+	if len(tmp.Trans) != len(tmp.LeafHashes) {
+		return errors.New("number of transactions doesn't match the number of leaf hashes")
+	}
+
+	for i, tx := range tmp.Trans {
+		hash, err := tx.Hash()
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(hash, tmp.LeafHashes[i]) {
+			return fmt.Errorf("transaction %d doesn't match the leaf hash", i)
+		}
+	}
+
+	hardened, err := verifyProofBlock(genesis, tmp.BlockHash, tmp.Header, tmp.Root, tmp.Hardened)
+	if err != nil {
+		return err
+	}
+
+	if hardened {
+		return merkle.VerifyHardenedMultiProof(tmp.LeafHashes, tmp.Proof, tmp.Root, sha256.New)
+	}
+
+	return merkle.VerifyMultiProof(tmp.LeafHashes, tmp.Proof, tmp.Root, sha256.New)
+}
+
+// verifyProofBlock checks the header of a proof is the block with the
+// specified hash and commits to the merkle root, then returns if the block's
+// merkle tree is hardened under the fork rules for its number.
+func verifyProofBlock(genesis genesis.Genesis, blockHash string, header BlockHeader, root []byte, hardened bool) (bool, error) {
+	if header.Hash() != blockHash {
+		return false, errors.New("block header doesn't match the block hash")
+	}
+
+	if hexutil.Encode(root) != header.TransRoot {
+		return false, errors.New("merkle root doesn't match the block header")
+	}
+
+	rulesHardened := hardenedMerkle(genesis.Rules(header.Number))
+	if hardened != rulesHardened {
+		return false, fmt.Errorf("proof hardened mode %t doesn't match the fork rules", hardened)
+	}
+
+	return rulesHardened, nil
+}
@@ -0,0 +1,179 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+)
+
+// CORE NOTE: Every transaction carries a type that selects the handler used
+// to validate its payload and apply its effects to the state. The parts every
+// transaction shares, like the signature, nonce, gas and tip, are handled the
+// same way for every type by ApplyTransaction. The handler only deals with
+// what makes the type different. A transfer is type 0, so transactions signed
+// before types existed keep their meaning, encoding and hash. A new type is
+// added by implementing TxHandler and registering it in txHandlers, together
+// with the fork that activates it.
+
+// TxType represents the type of a transaction.
+type TxType uint8
+
+// Set of transaction types.
+const (
+	TxTypeTransfer TxType = 0
+)
+
+// String implements the Stringer interface for logging.
+func (tt TxType) String() string {
+	if handler, exists := txHandlers[tt]; exists {
+		return handler.Name()
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint8(tt))
+}
+
+// TxHandler represents the behavior of a type of transaction.
+type TxHandler interface {
+
+	// Name returns the name of the type, which is also the key of its extra
+	// cost in the per type costs of the gas schedule.
+	Name() string
+
+	// Fork returns the fork that activates the type, empty when the type is
+	// always active.
+	Fork() string
+
+	// Validate checks the payload of the transaction without the state.
+	Validate(rules genesis.Rules, tx Tx) error
+
+	// Apply checks the transaction against the state and applies its
+	// effects. No changes can be made when an error is returned.
+	Apply(tc *TxContext) error
+}
+
+// txHandlers is the registry of the transaction types this version of the
+// software knows how to apply.
+var txHandlers = map[TxType]TxHandler{
+	TxTypeTransfer:      transferHandler{},
+	TxTypeCreate:        createHandler{},
+	TxTypeCall:          callHandler{},
+	TxTypeMultisig:      multisigHandler{},
+	TxTypeTokenIssue:    tokenIssueHandler{},
+	TxTypeTokenTransfer: tokenTransferHandler{},
+	TxTypeTokenBurn:     tokenBurnHandler{},
+	TxTypeHTLCLock:      htlcLockHandler{},
+	TxTypeHTLCClaim:     htlcClaimHandler{},
+	TxTypeHTLCRefund:    htlcRefundHandler{},
+	TxTypeBatchTransfer: batchTransferHandler{},
+}
+
+// txHandler returns the handler for the type of transaction if the type is
+// active under the rules.
+func txHandler(rules genesis.Rules, txType TxType) (TxHandler, error) {
+	handler, exists := txHandlers[txType]
+	if !exists {
+		return nil, fmt.Errorf("unknown transaction type %d", uint8(txType))
+	}
+
+	if fork := handler.Fork(); fork != "" && !rules.IsActive(fork) {
+		return nil, fmt.Errorf("%s transactions are not active until the %s fork", handler.Name(), fork)
+	}
+
+	return handler, nil
+}
+
+// validateType checks the type of the transaction is active under the rules
+// and its payload is valid for that type.
+func (tx Tx) validateType(rules genesis.Rules) error {
+	handler, err := txHandler(rules, tx.Type)
+	if err != nil {
+		return err
+	}
+
+	return handler.Validate(rules, tx)
+}
+
+// =============================================================================
+
+// TxContext provides a handler with the transaction being applied and access
+// to the state. The database write lock is held while the handler runs.
+type TxContext struct {
+	Rules  genesis.Rules
+	Header BlockHeader
+	Tx     BlockTx
+
+	// From is the sender after gas was charged. The handler changes this
+	// value instead of storing the sender, ApplyTransaction stores it once
+	// the tip and nonce are applied.
+	From Account
+
+	// GasUsed starts as the gas the transaction was charged. The handlers of
+	// transactions that run contract code lower it to the gas the code used
+	// and the rest is refunded.
+	GasUsed uint64
+
+	db *Database
+}
+
+// Account returns the current state of the specified account. Use From
+// for the sender.
+func (tc *TxContext) Account(accountID AccountID) Account {
+	return tc.db.account(accountID)
+}
+
+// SetAccount stores the account in the state.
+func (tc *TxContext) SetAccount(account Account) {
+	tc.db.setAccount(account.AccountID, account)
+}
+
+// =============================================================================
+
+// transferHandler moves value from the sender to the account receiving the
+// transaction.
+type transferHandler struct{}
+
+// Name implements the TxHandler interface.
+func (transferHandler) Name() string {
+	return "transfer"
+}
+
+// Fork implements the TxHandler interface.
+func (transferHandler) Fork() string {
+	return ""
+}
+
+// Validate implements the TxHandler interface.
+func (transferHandler) Validate(rules genesis.Rules, tx Tx) error {
+	if !tx.ToID.IsAccountID() {
+		return errors.New("to account is not properly formatted")
+	}
+
+	if tx.FromID == tx.ToID {
+		return fmt.Errorf("sending money to yourself, from %s, to %s", tx.FromID, tx.ToID)
+	}
+
+	return nil
+}
+
+// Apply implements the TxHandler interface.
+func (transferHandler) Apply(tc *TxContext) error {
+	to := tc.Account(tc.Tx.ToID)
+
+	if to.CodeHash != "" {
+		return fmt.Errorf("transaction invalid, account %s is a contract and needs a call transaction", to.AccountID)
+	}
+
+	toBalance, err := addBalance(tc.Rules, to.Balance, tc.Tx.Value)
+	if err != nil {
+		return fmt.Errorf("transaction invalid, to balance: %w", err)
+	}
+
+	// Update the balances between the two parties.
+	tc.From.Balance -= tc.Tx.Value
+	to.Balance = toBalance
+
+	tc.SetAccount(to)
+
+	return nil
+}
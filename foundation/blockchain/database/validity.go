@@ -0,0 +1,79 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+)
+
+// CORE NOTE: Once the validityWindows fork is active a transaction can limit
+// the blocks it can be mined in. It can only be mined in a block after its
+// valid after block and not in a block after its valid until block, zero
+// leaves that side open. Both bounds are block numbers, not timestamps, since
+// the block number is the only clock every node agrees on before the block
+// is mined. Without a window a signed transaction stays valid forever, so one
+// stuck in a mempool can be mined weeks later. The window is checked when the
+// transaction is accepted into the mempool, when the transactions for a new
+// block are picked and when a block is validated. A transaction outside its
+// window is rejected before any gas is charged, and the expired transactions
+// are dropped from the mempool after every block.
+
+// ValidAt reports whether the transaction can be mined in the block with the
+// specified number.
+func (tx Tx) ValidAt(blockNumber uint64) bool {
+	return tx.checkWindow(blockNumber) == nil
+}
+
+// Expired reports whether the transaction can't be mined in the block with
+// the specified number or any block after it.
+func (tx Tx) Expired(blockNumber uint64) bool {
+	return tx.ValidUntil != 0 && blockNumber > tx.ValidUntil
+}
+
+// checkWindow checks the transaction can be mined in the specified block.
+func (tx Tx) checkWindow(blockNumber uint64) error {
+	if blockNumber <= tx.ValidAfter {
+		return fmt.Errorf("transaction is only valid after block %d, block %d", tx.ValidAfter, blockNumber)
+	}
+
+	if tx.Expired(blockNumber) {
+		return fmt.Errorf("transaction expired after block %d, block %d", tx.ValidUntil, blockNumber)
+	}
+
+	return nil
+}
+
+// validateWindow checks the validity window of the transaction is allowed by
+// the rules and leaves at least one block the transaction can be mined in.
+func (tx Tx) validateWindow(rules genesis.Rules) error {
+	if tx.ValidAfter == 0 && tx.ValidUntil == 0 {
+		return nil
+	}
+
+	if !rules.IsActive(genesis.ForkValidityWindows) {
+		return fmt.Errorf("validity windows are not allowed before the %s fork", genesis.ForkValidityWindows)
+	}
+
+	if tx.ValidUntil != 0 && tx.ValidUntil <= tx.ValidAfter {
+		return errors.New("valid until must be after valid after")
+	}
+
+	return nil
+}
+
+// validateBlockWindows checks the transactions in a block can be mined in
+// the block.
+func validateBlockWindows(rules genesis.Rules, trans []BlockTx) error {
+	if !rules.IsActive(genesis.ForkValidityWindows) {
+		return nil
+	}
+
+	for _, tx := range trans {
+		if err := tx.checkWindow(rules.Number); err != nil {
+			return fmt.Errorf("tx[%s]: %w", tx, err)
+		}
+	}
+
+	return nil
+}
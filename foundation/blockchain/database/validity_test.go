@@ -0,0 +1,103 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+)
+
+func TestValidityWindow(t *testing.T) {
+	tt := []struct {
+		name    string
+		after   uint64
+		until   uint64
+		number  uint64
+		validAt bool
+		expired bool
+	}{
+		{name: "no window", number: 1, validAt: true},
+		{name: "the valid after block", after: 5, number: 5},
+		{name: "the block after the valid after block", after: 5, number: 6, validAt: true},
+		{name: "the valid until block", until: 10, number: 10, validAt: true},
+		{name: "the block after the valid until block", until: 10, number: 11, expired: true},
+		{name: "the first block of a window", after: 5, until: 10, number: 6, validAt: true},
+		{name: "the last block of a window", after: 5, until: 10, number: 10, validAt: true},
+		{name: "a block before a window", after: 5, until: 10, number: 4},
+		{name: "a block after a window", after: 5, until: 10, number: 11, expired: true},
+	}
+
+	t.Log("Given the need to only mine a transaction in the blocks of its validity window.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen checking %s.", testID, test.name)
+			{
+				tx := database.Tx{ValidAfter: test.after, ValidUntil: test.until}
+
+				if got := tx.ValidAt(test.number); got != test.validAt {
+					t.Fatalf("\t%s\tTest %d:\tShould be valid at block %d is %v : got %v", failed, testID, test.number, test.validAt, got)
+				}
+				if got := tx.Expired(test.number); got != test.expired {
+					t.Fatalf("\t%s\tTest %d:\tShould be expired at block %d is %v : got %v", failed, testID, test.number, test.expired, got)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be valid at block %d is %v.", success, testID, test.number, test.validAt)
+			}
+		}
+	}
+}
+
+func TestApplyValidityWindow(t *testing.T) {
+	tt := []struct {
+		name    string
+		forks   map[string]uint64
+		after   uint64
+		until   uint64
+		number  uint64
+		valid   bool
+		balance uint64
+	}{
+		{name: "a block in the window", forks: map[string]uint64{genesis.ForkValidityWindows: 0}, after: 5, until: 10, number: 10, valid: true, balance: 885},
+		{name: "a block before the window", forks: map[string]uint64{genesis.ForkValidityWindows: 0}, after: 5, until: 10, number: 5, balance: 1000},
+		{name: "a block after the window", forks: map[string]uint64{genesis.ForkValidityWindows: 0}, after: 5, until: 10, number: 11, balance: 1000},
+		{name: "a window before the validityWindows fork", forks: map[string]uint64{genesis.ForkValidityWindows: 20}, after: 5, until: 10, number: 10, balance: 1000},
+		{name: "an empty window", forks: map[string]uint64{genesis.ForkValidityWindows: 0}, after: 10, until: 10, number: 10, balance: 1000},
+	}
+
+	t.Log("Given the need to reject a transaction outside its validity window without charging it.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen applying a transaction in %s.", testID, test.name)
+			{
+				gen := genesis.Genesis{
+					Date:          time.Date(2021, 12, 17, 0, 0, 0, 0, time.UTC),
+					ChainID:       1,
+					TransPerBlock: 10,
+					Difficulty:    1,
+					MiningReward:  700,
+					GasPrice:      15,
+					Balances:      map[string]uint64{pavel: 1000},
+					Forks:         test.forks,
+				}
+				db := newTestDatabase(t, gen)
+
+				tx := database.Tx{ChainID: 1, Nonce: 1, FromID: pavel, ToID: kennedy, Value: 100, ValidAfter: test.after, ValidUntil: test.until}
+				block := database.Block{Header: database.BlockHeader{Number: test.number, BeneficiaryID: kennedy}}
+
+				err := db.ApplyTransaction(block, database.NewBlockTx(signTx(t, tx), 15, 1))
+				switch {
+				case test.valid && err != nil:
+					t.Fatalf("\t%s\tTest %d:\tShould be able to apply the transaction : %s", failed, testID, err)
+				case !test.valid && err == nil:
+					t.Fatalf("\t%s\tTest %d:\tShould fail the transaction.", failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould apply the transaction is %v.", success, testID, test.valid)
+
+				if got := db.CopyAccounts()[pavel].Balance; got != test.balance {
+					t.Fatalf("\t%s\tTest %d:\tShould leave the expected balance : got %d, exp %d", failed, testID, got, test.balance)
+				}
+				t.Logf("\t%s\tTest %d:\tShould leave the expected balance.", success, testID)
+			}
+		}
+	}
+}
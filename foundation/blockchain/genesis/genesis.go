@@ -2,37 +2,288 @@
 package genesis
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"time"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/signature"
+	"github.com/ethereum/go-ethereum/common"
 )
 
+// maxDifficulty represents the largest number of leading zeros the POW
+// rules can check for in a block hash.
+const maxDifficulty = 17
+
 // Genesis represents the genesis file.
 type Genesis struct {
 	Date          time.Time         `json:"date"`
-	ChainID       uint16            `json:"chain_id"`        // The chain id represents an unique id for this running instance.
-	TransPerBlock uint16            `json:"trans_per_block"` // The maximum number of transactions that can be in a block.
-	Difficulty    uint16            `json:"difficulty"`      // How difficult it needs to be to solve the work problem.
-	MiningReward  uint64            `json:"mining_reward"`   // Reward for mining a block.
-	GasPrice      uint64            `json:"gas_price"`       // Fee paid for each transaction mined into a block.
+	ChainID       uint16            `json:"chain_id"`           // The chain id represents an unique id for this running instance.
+	TransPerBlock uint16            `json:"trans_per_block"`    // The maximum number of transactions that can be in a block before the gasMetering fork.
+	Difficulty    uint16            `json:"difficulty"`         // How difficult it needs to be to solve the work problem.
+	MiningReward  uint64            `json:"mining_reward"`      // Reward for mining a block.
+	Maturity      uint64            `json:"maturity,omitempty"` // Blocks the mining reward stays locked for once the lockedBalances fork is active.
+	GasPrice      uint64            `json:"gas_price"`          // Fee paid for each transaction mined into a block.
+	Gas           *GasSchedule      `json:"gas,omitempty"`      // Units of gas transactions use once the gasMetering fork is active.
+	Fees          *FeeSchedule      `json:"fees,omitempty"`     // How the base fee is set once the baseFee fork is active.
+	Rewards       *RewardSchedule   `json:"rewards,omitempty"`  // How the mining reward changes once the rewardSchedule fork is active.
 	Balances      map[string]uint64 `json:"balances"`
+	Vesting       []Vesting         `json:"vesting,omitempty"` // Balances that stay locked until a block number.
+	Forks         map[string]uint64 `json:"forks,omitempty"`   // Block number each named protocol change activates at.
+
+	hash  string
+	spans []scheduleSpan
+}
+
+// GasSchedule represents the units of gas a transaction uses and the amount
+// of gas a block can hold.
+type GasSchedule struct {
+	Base       uint64            `json:"base"`               // Gas used by every transaction.
+	PerByte    uint64            `json:"per_byte"`           // Gas used for each byte of transaction data.
+	PerType    map[string]uint64 `json:"per_type,omitempty"` // Extra gas used based on the type of transaction.
+	BlockLimit uint64            `json:"block_limit"`        // Maximum gas the transactions in a block can use together.
+}
+
+// FeeSchedule represents how the base fee per unit of gas adjusts from block
+// to block and who receives it.
+type FeeSchedule struct {
+	InitialBaseFee    uint64 `json:"initial_base_fee"`   // Base fee of the first block the baseFee fork is active for.
+	ChangeDenominator uint64 `json:"change_denominator"` // Limits how much the base fee changes per block, 8 allows 12.5%.
+	Elasticity        uint64 `json:"elasticity"`         // The block gas limit divided by this is the gas a block targets.
+	Treasury          string `json:"treasury,omitempty"` // Account that receives the base fee, the base fee is burned when empty.
+}
+
+// Vesting represents an amount given to an account at genesis that can't be
+// spent until the specified block has been mined. An account can have more
+// than one entry to build a schedule that unlocks over time.
+type Vesting struct {
+	Account  string `json:"account"`
+	Amount   uint64 `json:"amount"`
+	UnlockAt uint64 `json:"unlock_at"` // The amount is spendable once this block has been mined.
 }
 
 // =============================================================================
 
-// Load opens and consumes the genesis file.
-func Load() (Genesis, error) {
-	path := "zblock/genesis.json"
+// Load opens and consumes the genesis file at the specified path. The
+// contents are validated before the genesis value is returned.
+func Load(path string) (Genesis, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
-		return Genesis{}, err
+		return Genesis{}, fmt.Errorf("reading genesis file: %w", err)
 	}
 
+	// Unknown fields are rejected so a misspelled setting doesn't silently
+	// fall back to a zero value.
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+
 	var genesis Genesis
-	err = json.Unmarshal(content, &genesis)
-	if err != nil {
-		return Genesis{}, err
+	if err := decoder.Decode(&genesis); err != nil {
+		return Genesis{}, fmt.Errorf("decoding genesis file %q: %w", path, err)
+	}
+
+	if err := genesis.Validate(); err != nil {
+		return Genesis{}, fmt.Errorf("validating genesis file %q: %w", path, err)
 	}
 
+	genesis.hash = signature.Hash(genesis)
+
+	// The reward schedule is worked out once, so the rules of a block only
+	// need a lookup.
+	genesis.spans = genesis.rewardSpans()
+
 	return genesis, nil
 }
+
+// Validate checks the genesis values are sane enough to start a chain.
+func (g Genesis) Validate() error {
+	if g.Date.IsZero() {
+		return errors.New("date is required")
+	}
+
+	if g.ChainID == 0 {
+		return errors.New("chain_id must be greater than 0")
+	}
+
+	// The block gas limit replaces the number of transactions per block once
+	// the gasMetering fork is active.
+	if activation, exists := g.Forks[ForkGasMetering]; !exists || activation > 0 {
+		if g.TransPerBlock == 0 {
+			return errors.New("trans_per_block must be greater than 0")
+		}
+	}
+
+	if _, exists := g.Forks[ForkGasMetering]; exists {
+		if g.Gas == nil {
+			return fmt.Errorf("gas is required by the %s fork", ForkGasMetering)
+		}
+
+		if g.Gas.Base == 0 {
+			return errors.New("gas: base must be greater than 0")
+		}
+
+		if g.Gas.BlockLimit < g.Gas.Base {
+			return fmt.Errorf("gas: block_limit must be at least the base gas of %d", g.Gas.Base)
+		}
+	}
+
+	if activation, exists := g.Forks[ForkBaseFee]; exists {
+		if gasActivation, exists := g.Forks[ForkGasMetering]; !exists || gasActivation > activation {
+			return fmt.Errorf("the %s fork requires the %s fork to be active", ForkBaseFee, ForkGasMetering)
+		}
+
+		if g.Fees == nil {
+			return fmt.Errorf("fees is required by the %s fork", ForkBaseFee)
+		}
+
+		if g.Fees.ChangeDenominator == 0 {
+			return errors.New("fees: change_denominator must be greater than 0")
+		}
+
+		if g.Fees.Elasticity == 0 || g.Gas.BlockLimit/g.Fees.Elasticity == 0 {
+			return errors.New("fees: elasticity must be greater than 0 and leave a target gas greater than 0")
+		}
+
+		if g.Fees.Treasury != "" {
+			if !common.IsHexAddress(g.Fees.Treasury) {
+				return fmt.Errorf("fees: treasury %q is not properly formatted", g.Fees.Treasury)
+			}
+
+			if err := g.checkChecksum(g.Fees.Treasury); err != nil {
+				return fmt.Errorf("fees: treasury: %w", err)
+			}
+		}
+	}
+
+	// Contracts use their gas limit to run and their storage roots are only
+	// part of the state trie. The gas they don't use is refunded after they
+	// ran, which needs the beneficiary to be reloaded.
+	if activation, exists := g.Forks[ForkContracts]; exists {
+		for _, required := range []string{ForkGasMetering, ForkStateTrie, ForkAccountReload} {
+			if requiredActivation, exists := g.Forks[required]; !exists || requiredActivation > activation {
+				return fmt.Errorf("the %s fork requires the %s fork to be active", ForkContracts, required)
+			}
+		}
+	}
+
+	if g.Difficulty == 0 || g.Difficulty > maxDifficulty {
+		return fmt.Errorf("difficulty must be between 1 and %d, got %d", maxDifficulty, g.Difficulty)
+	}
+
+	for account := range g.Balances {
+		if !common.IsHexAddress(account) {
+			return fmt.Errorf("balances: account %q is not properly formatted", account)
+		}
+
+		if err := g.checkChecksum(account); err != nil {
+			return fmt.Errorf("balances: %w", err)
+		}
+	}
+
+	// The vesting balances are locked in the genesis state, so the fork that
+	// releases them needs to be active from the start.
+	if activation, exists := g.Forks[ForkLockedBalances]; !exists || activation > 0 {
+		if len(g.Vesting) > 0 {
+			return fmt.Errorf("vesting requires the %s fork to be active at block 0", ForkLockedBalances)
+		}
+	}
+
+	if _, exists := g.Forks[ForkLockedBalances]; !exists && g.Maturity > 0 {
+		return fmt.Errorf("maturity requires the %s fork", ForkLockedBalances)
+	}
+
+	if _, exists := g.Forks[ForkRewardSchedule]; exists {
+		if g.Rewards == nil {
+			return fmt.Errorf("rewards is required by the %s fork", ForkRewardSchedule)
+		}
+
+		if err := g.Rewards.validate(g); err != nil {
+			return err
+		}
+	}
+
+	if _, exists := g.Forks[ForkRewardSchedule]; !exists && g.Rewards != nil {
+		return fmt.Errorf("rewards requires the %s fork", ForkRewardSchedule)
+	}
+
+	for i, vesting := range g.Vesting {
+		if !common.IsHexAddress(vesting.Account) {
+			return fmt.Errorf("vesting[%d]: account %q is not properly formatted", i, vesting.Account)
+		}
+
+		if err := g.checkChecksum(vesting.Account); err != nil {
+			return fmt.Errorf("vesting[%d]: %w", i, err)
+		}
+
+		if vesting.Amount == 0 {
+			return fmt.Errorf("vesting[%d]: amount must be greater than 0", i)
+		}
+
+		if vesting.UnlockAt == 0 {
+			return fmt.Errorf("vesting[%d]: unlock_at must be greater than 0", i)
+		}
+	}
+
+	for name := range g.Forks {
+		if _, exists := knownForks[name]; !exists {
+			return fmt.Errorf("forks: unknown fork %q", name)
+		}
+	}
+
+	return nil
+}
+
+// checkChecksum checks the account is in its EIP-55 checksum form when the
+// stateTrie fork is scheduled. The trie keys accounts by their bytes, so two
+// casings of one address would otherwise be two accounts writing one leaf.
+func (g Genesis) checkChecksum(account string) error {
+	if _, exists := g.Forks[ForkStateTrie]; !exists {
+		return nil
+	}
+
+	if checksummed := common.HexToAddress(account).Hex(); account != checksummed {
+		return fmt.Errorf("account %q is not in checksum form, use %q", account, checksummed)
+	}
+
+	return nil
+}
+
+// Rules returns the set of consensus rules that apply to the specified
+// block number.
+func (g Genesis) Rules(blockNumber uint64) Rules {
+	var gas GasSchedule
+	if g.Gas != nil {
+		gas = *g.Gas
+	}
+
+	var fees FeeSchedule
+	if g.Fees != nil {
+		fees = *g.Fees
+	}
+
+	return Rules{
+		Number:        blockNumber,
+		ChainID:       g.ChainID,
+		TransPerBlock: g.TransPerBlock,
+		Difficulty:    g.Difficulty,
+		MiningReward:  g.Reward(blockNumber),
+		Maturity:      g.Maturity,
+		GasPrice:      g.GasPrice,
+		Gas:           gas,
+		Fees:          fees,
+		forks:         g.Forks,
+	}
+}
+
+// Hash returns the canonical hash of the genesis values. Once the
+// genesisParent fork is active, block 1 uses this hash as its parent hash so
+// chains built from different genesis files can never be mixed.
+func (g Genesis) Hash() string {
+	if g.hash != "" {
+		return g.hash
+	}
+
+	return signature.Hash(g)
+}
@@ -0,0 +1,223 @@
+package genesis
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// RewardSchedule represents how the mining reward changes with the block
+// number once the rewardSchedule fork is active. The reward either halves
+// every interval of blocks or follows a table of rewards, and the optional
+// max supply stops the reward once it has been issued.
+type RewardSchedule struct {
+	HalvingInterval uint64       `json:"halving_interval,omitempty"` // Number of blocks after which the mining reward is halved, counted from the fork block.
+	Table           []RewardStep `json:"table,omitempty"`            // Rewards by block number, the mining reward applies before the first step.
+	MaxSupply       uint64       `json:"max_supply,omitempty"`       // Maximum amount the genesis and the rewards can issue together.
+}
+
+// RewardStep represents the mining reward from a block number on.
+type RewardStep struct {
+	From   uint64 `json:"from"`
+	Reward uint64 `json:"reward"`
+}
+
+// validate checks the reward schedule is sane.
+func (rs RewardSchedule) validate(g Genesis) error {
+	if rs.HalvingInterval > 0 && len(rs.Table) > 0 {
+		return errors.New("rewards: only one of halving_interval and table can be set")
+	}
+
+	for i := 1; i < len(rs.Table); i++ {
+		if rs.Table[i].From <= rs.Table[i-1].From {
+			return fmt.Errorf("rewards: table[%d]: from must be greater than the from of the step before", i)
+		}
+	}
+
+	if rs.MaxSupply > 0 {
+		allocated, err := g.allocated()
+		if err != nil {
+			return err
+		}
+
+		if allocated > rs.MaxSupply {
+			return fmt.Errorf("rewards: max_supply %d is less than the %d the genesis allocates", rs.MaxSupply, allocated)
+		}
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// Reward returns the mining reward of the specified block. Before the
+// rewardSchedule fork it's the mining reward of the genesis.
+func (g Genesis) Reward(blockNumber uint64) uint64 {
+	activation, exists := g.Forks[ForkRewardSchedule]
+	if !exists || blockNumber < activation || blockNumber == 0 {
+		return g.MiningReward
+	}
+
+	return g.Issued(blockNumber) - g.Issued(blockNumber-1)
+}
+
+// Issued returns the amount the genesis and the mining rewards have issued
+// once the specified block has been mined.
+func (g Genesis) Issued(blockNumber uint64) uint64 {
+	issued := g.scheduled(blockNumber)
+
+	activation, exists := g.Forks[ForkRewardSchedule]
+	if !exists || blockNumber < activation || g.Rewards == nil || g.Rewards.MaxSupply == 0 {
+		return issued
+	}
+
+	// The rewards of the blocks before the fork are never taken back, even
+	// when they issued more than the max supply.
+	var beforeFork uint64
+	if activation > 0 {
+		beforeFork = g.scheduled(activation - 1)
+	}
+
+	if issued > g.Rewards.MaxSupply {
+		issued = g.Rewards.MaxSupply
+	}
+	if issued < beforeFork {
+		issued = beforeFork
+	}
+
+	return issued
+}
+
+// scheduled returns the amount the genesis allocates plus the rewards of the
+// blocks up to the specified block without the max supply. The amount stops
+// at the largest value that can be held instead of overflowing.
+func (g Genesis) scheduled(blockNumber uint64) uint64 {
+	spans := g.spans
+	if spans == nil {
+		spans = g.rewardSpans()
+	}
+
+	i := sort.Search(len(spans), func(i int) bool { return spans[i].to >= blockNumber })
+	span := spans[i]
+	if blockNumber < span.from {
+		return span.issued
+	}
+
+	return addSpan(span.issued, span.reward, blockNumber-span.from+1)
+}
+
+// scheduleSpan represents a run of blocks the schedule gives the same reward
+// along with the amount issued before the first of them.
+type scheduleSpan struct {
+	from   uint64
+	to     uint64
+	reward uint64
+	issued uint64
+}
+
+// rewardSpans returns the runs of blocks with the same reward from block 1
+// on, the last one ends at the largest block number. A halving schedule ends
+// with a reward of zero after at most 64 halvings, so there are only a few.
+func (g Genesis) rewardSpans() []scheduleSpan {
+	total, err := g.allocated()
+	if err != nil {
+		total = math.MaxUint64
+	}
+
+	var spans []scheduleSpan
+	for from := uint64(1); ; {
+		reward, to := g.rewardSpan(from)
+		spans = append(spans, scheduleSpan{from: from, to: to, reward: reward, issued: total})
+
+		if to == math.MaxUint64 {
+			return spans
+		}
+
+		total = addSpan(total, reward, to-from+1)
+		from = to + 1
+	}
+}
+
+// addSpan adds the reward of the number of blocks to the total. The amount
+// stops at the largest value that can be held instead of overflowing.
+func addSpan(total uint64, reward uint64, blocks uint64) uint64 {
+	hi, lo := bits.Mul64(reward, blocks)
+	if hi > 0 {
+		return math.MaxUint64
+	}
+
+	total, carry := bits.Add64(total, lo, 0)
+	if carry > 0 {
+		return math.MaxUint64
+	}
+
+	return total
+}
+
+// rewardSpan returns the reward the schedule gives the specified block and
+// the last block that gets the same reward.
+func (g Genesis) rewardSpan(blockNumber uint64) (reward uint64, to uint64) {
+	activation, exists := g.Forks[ForkRewardSchedule]
+	switch {
+	case !exists:
+		return g.MiningReward, math.MaxUint64
+
+	case blockNumber < activation:
+		return g.MiningReward, activation - 1
+
+	case g.Rewards == nil:
+		return g.MiningReward, math.MaxUint64
+
+	case g.Rewards.HalvingInterval > 0:
+
+		// The halvings are counted from the block the fork activates at, so
+		// a chain that schedules the fork later starts from the full reward.
+		interval := g.Rewards.HalvingInterval
+		halvings := (blockNumber - activation) / interval
+		if halvings >= 64 || g.MiningReward>>halvings == 0 {
+			return 0, math.MaxUint64
+		}
+
+		to = math.MaxUint64
+		if halvings+1 <= math.MaxUint64/interval {
+			if end := (halvings + 1) * interval; end <= math.MaxUint64-activation {
+				to = activation + end - 1
+			}
+		}
+		return g.MiningReward >> halvings, to
+
+	default:
+		reward, to = g.MiningReward, math.MaxUint64
+		for _, step := range g.Rewards.Table {
+			if step.From > blockNumber {
+				to = step.From - 1
+				break
+			}
+			reward = step.Reward
+		}
+		return reward, to
+	}
+}
+
+// allocated returns the amount the genesis gives to the accounts, including
+// the vesting balances.
+func (g Genesis) allocated() (uint64, error) {
+	var total uint64
+	var carry uint64
+
+	for _, balance := range g.Balances {
+		if total, carry = bits.Add64(total, balance, 0); carry > 0 {
+			return 0, errors.New("genesis balances overflow")
+		}
+	}
+
+	for _, vesting := range g.Vesting {
+		if total, carry = bits.Add64(total, vesting.Amount, 0); carry > 0 {
+			return 0, errors.New("genesis balances overflow")
+		}
+	}
+
+	return total, nil
+}
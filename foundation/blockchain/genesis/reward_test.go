@@ -0,0 +1,192 @@
+package genesis_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// rewardGenesis returns a genesis allocating 1000 with a mining reward of
+// 700, the rewardSchedule fork active from the specified block when rewards
+// are set.
+func rewardGenesis(activation uint64, rewards *genesis.RewardSchedule) genesis.Genesis {
+	gen := genesis.Genesis{
+		Date:          time.Date(2021, 12, 17, 0, 0, 0, 0, time.UTC),
+		ChainID:       1,
+		TransPerBlock: 10,
+		Difficulty:    1,
+		MiningReward:  700,
+		GasPrice:      15,
+		Balances:      map[string]uint64{"0xdd6B972ffcc631a62CAE1BB9d80b7ff429c8ebA4": 1000},
+		Rewards:       rewards,
+	}
+	if rewards != nil {
+		gen.Forks = map[string]uint64{genesis.ForkRewardSchedule: activation}
+	}
+
+	return gen
+}
+
+func TestRewardSchedule(t *testing.T) {
+	type block struct {
+		number uint64
+		reward uint64
+		issued uint64
+	}
+
+	tt := []struct {
+		name   string
+		gen    genesis.Genesis
+		blocks []block
+	}{
+		{
+			name: "no reward schedule",
+			gen:  rewardGenesis(0, nil),
+			blocks: []block{
+				{number: 0, reward: 700, issued: 1000},
+				{number: 1, reward: 700, issued: 1700},
+				{number: 3, reward: 700, issued: 3100},
+			},
+		},
+		{
+			name: "a reward that halves every 2 blocks from block 0",
+			gen:  rewardGenesis(0, &genesis.RewardSchedule{HalvingInterval: 2}),
+			blocks: []block{
+				{number: 1, reward: 700, issued: 1700},
+				{number: 2, reward: 350, issued: 2050},
+				{number: 3, reward: 350, issued: 2400},
+				{number: 4, reward: 175, issued: 2575},
+			},
+		},
+		{
+			name: "a reward that halves every 2 blocks from block 10",
+			gen:  rewardGenesis(10, &genesis.RewardSchedule{HalvingInterval: 2}),
+			blocks: []block{
+				{number: 9, reward: 700, issued: 7300},
+				{number: 10, reward: 700, issued: 8000},
+				{number: 11, reward: 700, issued: 8700},
+				{number: 12, reward: 350, issued: 9050},
+				{number: 14, reward: 175, issued: 9575},
+			},
+		},
+		{
+			name: "a reward that halves until nothing is left",
+			gen:  rewardGenesis(0, &genesis.RewardSchedule{HalvingInterval: 1}),
+			blocks: []block{
+				{number: 9, reward: 1, issued: 1000 + 694},
+				{number: 10, reward: 0, issued: 1000 + 694},
+				{number: 1_000, reward: 0, issued: 1000 + 694},
+			},
+		},
+		{
+			name: "a table of rewards",
+			gen: rewardGenesis(0, &genesis.RewardSchedule{Table: []genesis.RewardStep{
+				{From: 3, Reward: 100},
+				{From: 5, Reward: 0},
+			}}),
+			blocks: []block{
+				{number: 2, reward: 700, issued: 2400},
+				{number: 3, reward: 100, issued: 2500},
+				{number: 4, reward: 100, issued: 2600},
+				{number: 5, reward: 0, issued: 2600},
+			},
+		},
+		{
+			name: "a max supply the rewards reach",
+			gen:  rewardGenesis(0, &genesis.RewardSchedule{MaxSupply: 2500}),
+			blocks: []block{
+				{number: 2, reward: 700, issued: 2400},
+				{number: 3, reward: 100, issued: 2500},
+				{number: 4, reward: 0, issued: 2500},
+			},
+		},
+		{
+			name: "a max supply the rewards passed before the fork",
+			gen:  rewardGenesis(3, &genesis.RewardSchedule{MaxSupply: 2000}),
+			blocks: []block{
+				{number: 2, reward: 700, issued: 2400},
+				{number: 3, reward: 0, issued: 2400},
+				{number: 4, reward: 0, issued: 2400},
+			},
+		},
+	}
+
+	t.Log("Given the need to derive the mining reward and the issued amount from the block number.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen using %s.", testID, test.name)
+			{
+				if err := test.gen.Validate(); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to validate the genesis : %s", failed, testID, err)
+				}
+
+				for _, b := range test.blocks {
+					if got := test.gen.Rules(b.number).MiningReward; got != b.reward {
+						t.Fatalf("\t%s\tTest %d:\tShould give block %d the expected reward : got %d, exp %d", failed, testID, b.number, got, b.reward)
+					}
+					if got := test.gen.Issued(b.number); got != b.issued {
+						t.Fatalf("\t%s\tTest %d:\tShould have issued the expected amount by block %d : got %d, exp %d", failed, testID, b.number, got, b.issued)
+					}
+				}
+				t.Logf("\t%s\tTest %d:\tShould give every block the expected reward and issued amount.", success, testID)
+			}
+		}
+
+		testID := len(tt)
+		t.Logf("\tTest %d:\tWhen the max supply is below what the genesis allocates.", testID)
+		{
+			gen := rewardGenesis(0, &genesis.RewardSchedule{MaxSupply: 999})
+			if err := gen.Validate(); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould reject the genesis.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject the genesis.", success, testID)
+		}
+	}
+}
+
+func TestLoadedRewardSchedule(t *testing.T) {
+	gen := rewardGenesis(10, &genesis.RewardSchedule{HalvingInterval: 3, MaxSupply: 12_000})
+	gen.Forks[genesis.ForkLockedBalances] = 5
+
+	t.Log("Given the need to look up the rules of a block from a loaded genesis.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen loading the genesis from a file.", testID)
+		{
+			data, err := json.Marshal(gen)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to marshal the genesis : %s", failed, testID, err)
+			}
+			path := filepath.Join(t.TempDir(), "genesis.json")
+			if err := os.WriteFile(path, data, 0600); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to write the genesis : %s", failed, testID, err)
+			}
+
+			loaded, err := genesis.Load(path)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to load the genesis : %s", failed, testID, err)
+			}
+
+			for number := uint64(0); number <= 40; number++ {
+				exp, got := gen.Rules(number), loaded.Rules(number)
+				if got.MiningReward != exp.MiningReward || loaded.Issued(number) != gen.Issued(number) {
+					t.Fatalf("\t%s\tTest %d:\tShould give block %d the same reward and issued amount : got %d and %d, exp %d and %d", failed, testID, number, got.MiningReward, loaded.Issued(number), exp.MiningReward, gen.Issued(number))
+				}
+				if got.IsActive(genesis.ForkLockedBalances) != (number >= 5) || got.IsActive(genesis.ForkBaseFee) {
+					t.Fatalf("\t%s\tTest %d:\tShould activate the forks at their blocks : block %d", failed, testID, number)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould give every block the same rules.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package genesis
+
+// CORE NOTE: Every change to the consensus rules needs to be scheduled as a
+// named fork in the genesis file. Blocks below the activation height keep
+// being validated with the old behavior, so the existing chain never has to
+// be thrown away when the protocol evolves. Once a fork is added to the
+// knownForks set it can be activated like this.
+//
+//	"forks": {
+//	  "forkName": 500
+//	}
+
+// Set of forks this version of the software knows how to apply.
+const (
+
+	// ForkGenesisParent makes the first block of the chain reference the
+	// hash of the genesis file as its parent instead of the zero hash, so
+	// chains created from different genesis files are incompatible. It only
+	// has an effect when it's active at block 1.
+	ForkGenesisParent = "genesisParent"
+
+	// ForkMinimumDifficulty rejects blocks mined with a difficulty below the
+	// difficulty of the genesis file.
+	ForkMinimumDifficulty = "minimumDifficulty"
+
+	// ForkCanonicalEncoding switches block headers to the canonical binary
+	// encoding for hashing and allows transactions signed with it.
+	ForkCanonicalEncoding = "canonicalEncoding"
+
+	// ForkStateTrie replaces the hash of the sorted account list with the
+	// root of the sparse merkle state trie as the block state root.
+	ForkStateTrie = "stateTrie"
+
+	// ForkHardenedMerkle builds the transaction merkle tree with domain
+	// separated hashes, rejects duplicate transactions and stops duplicating
+	// the last node of odd levels.
+	ForkHardenedMerkle = "hardenedMerkle"
+
+	// ForkHeaderBloom adds a bloom filter of the from and to accounts of the
+	// transactions in a block to the block header.
+	ForkHeaderBloom = "headerBloom"
+
+	// ForkCheckedBalances rejects transactions and rewards whose balance
+	// changes would overflow instead of letting the values wrap around.
+	ForkCheckedBalances = "checkedBalances"
+
+	// ForkGasMetering charges gas based on the size and type of a
+	// transaction, adds a gas limit to the signed transaction and replaces
+	// the number of transactions per block with a block gas limit.
+	ForkGasMetering = "gasMetering"
+
+	// ForkBaseFee adds a base fee per unit of gas to the block header that
+	// adjusts with how full the parent block was. The base fee is burned or
+	// paid to a treasury and the tip is paid to the beneficiary.
+	ForkBaseFee = "baseFee"
+
+	// ForkContracts adds create and call transactions that deploy and run
+	// contract code, with the storage root of each contract in its account
+	// in the state trie. A transaction that fails after it was charged for
+	// gas still uses its nonce, and one whose nonce was used isn't charged.
+	ForkContracts = "contracts"
+
+	// ForkMultisig adds a transaction that registers an account controlled
+	// by a set of signers and allows transactions from it to carry the
+	// signatures of several signers.
+	ForkMultisig = "multisig"
+
+	// ForkTokens adds transactions that issue, transfer and burn fungible
+	// tokens, with the token balances of each account in its account in the
+	// state trie.
+	ForkTokens = "tokens"
+
+	// ForkHTLC adds hash time-locked transactions that lock value until it's
+	// claimed with the preimage of a hash or the lock expires, and refunds
+	// the expired locks at the end of every block.
+	ForkHTLC = "htlc"
+
+	// ForkHTLCCleanup removes a settled lock from the state once the
+	// retention number of blocks after its expiry have been mined, so the
+	// state doesn't keep every lock forever.
+	ForkHTLCCleanup = "htlcCleanup"
+
+	// ForkFeePayer allows a transaction to name a fee payer that co-signs
+	// it and pays its gas and tip instead of the sender.
+	ForkFeePayer = "feePayer"
+
+	// ForkLockedBalances locks the mining reward of a block for the maturity
+	// number of blocks and releases the vesting balances of the genesis.
+	ForkLockedBalances = "lockedBalances"
+
+	// ForkRewardSchedule derives the mining reward from the block number
+	// using the reward schedule and rejects blocks claiming another reward.
+	ForkRewardSchedule = "rewardSchedule"
+
+	// ForkBatchTransfers adds a transaction that pays a list of accounts
+	// with one signature.
+	ForkBatchTransfers = "batchTransfers"
+
+	// ForkValidityWindows lets a transaction limit the blocks it can be mined
+	// in with the block numbers it's valid after and until.
+	ForkValidityWindows = "validityWindows"
+
+	// ForkAccountReload reloads the sender, the fee payer and the beneficiary
+	// between the steps of applying a transaction, so a beneficiary that also
+	// sends, pays for or receives the transaction keeps every change. Before
+	// it the beneficiary is written from a copy taken before the transaction
+	// ran, over the changes made to it in its other roles.
+	ForkAccountReload = "accountReload"
+)
+
+// knownForks is the set of fork names this version of the software knows how
+// to apply. A genesis file that schedules any other fork is rejected.
+var knownForks = map[string]struct{}{
+	ForkGenesisParent:     {},
+	ForkMinimumDifficulty: {},
+	ForkCanonicalEncoding: {},
+	ForkStateTrie:         {},
+	ForkHardenedMerkle:    {},
+	ForkHeaderBloom:       {},
+	ForkCheckedBalances:   {},
+	ForkGasMetering:       {},
+	ForkBaseFee:           {},
+	ForkContracts:         {},
+	ForkMultisig:          {},
+	ForkTokens:            {},
+	ForkHTLC:              {},
+	ForkHTLCCleanup:       {},
+	ForkFeePayer:          {},
+	ForkLockedBalances:    {},
+	ForkRewardSchedule:    {},
+	ForkBatchTransfers:    {},
+	ForkValidityWindows:   {},
+	ForkAccountReload:     {},
+}
+
+// =============================================================================
+
+// Rules represents the consensus rules that apply to a specific block
+// number. Code that validates, mines or applies a block should ask the rules
+// which behavior to use instead of reading the genesis values directly.
+type Rules struct {
+	Number        uint64
+	ChainID       uint16
+	TransPerBlock uint16
+	Difficulty    uint16
+	MiningReward  uint64
+	Maturity      uint64
+	GasPrice      uint64
+	Gas           GasSchedule
+	Fees          FeeSchedule
+	forks         map[string]uint64
+}
+
+// IsActive reports whether the named fork has activated at this block number.
+func (r Rules) IsActive(fork string) bool {
+	activation, exists := r.forks[fork]
+	return exists && r.Number >= activation
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sort"
 	"strings"
 	"sync"
 
@@ -78,19 +79,116 @@ func (mp *Mempool) Delete(tx database.BlockTx) error {
 	return nil
 }
 
-// PickBest uses the configured sort strategy to return a set of transactions.
-// If 0 is passed, all transactions in the mempool will be returned.
-func (mp *Mempool) PickBest(howMany ...uint16) []database.BlockTx {
+// PickBest returns up to the specified number of transactions in the order
+// they should be mined into the specified block. If 0 is passed, all the
+// transactions that can be mined into the block will be returned.
+func (mp *Mempool) PickBest(blockNumber uint64, howMany ...uint16) []database.BlockTx {
+	number := 0
+	if len(howMany) > 0 {
+		number = int(howMany[0])
+	}
+
+	var count int
+	return mp.pick(func(tx database.BlockTx) bool {
+		if !tx.ValidAt(blockNumber) || (number > 0 && count == number) {
+			return false
+		}
+		count++
+		return true
+	})
+}
+
+// PickBestGas returns the transactions in the order they should be mined
+// into the specified block, as long as their gas units fit within the
+// specified gas limit and their max fee covers the specified base fee.
+func (mp *Mempool) PickBestGas(blockNumber uint64, gasLimit uint64, baseFee uint64) []database.BlockTx {
+	var used uint64
+	return mp.pick(func(tx database.BlockTx) bool {
+		if !tx.ValidAt(blockNumber) || tx.GasUnits > gasLimit-used || tx.MaxFee < baseFee {
+			return false
+		}
+		used += tx.GasUnits
+		return true
+	})
+}
+
+// All returns every transaction in the order they should be mined, including
+// the ones that can't be mined into the next block yet.
+func (mp *Mempool) All() []database.BlockTx {
+	return mp.pick(func(tx database.BlockTx) bool {
+		return true
+	})
+}
+
+// DropExpired removes the transactions that can't be mined into the specified
+// block or any block after it and returns how many were removed.
+func (mp *Mempool) DropExpired(blockNumber uint64) int {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	var dropped int
+	for key, tx := range mp.pool {
+		if tx.Expired(blockNumber) {
+			delete(mp.pool, key)
+			dropped++
+		}
+	}
+
+	return dropped
+}
+
+// pick walks the transactions in the order they should be mined and returns
+// the ones the take function accepts. The transactions of an account are
+// taken in nonce order and between accounts the highest tip goes first. Once
+// a transaction isn't taken, the later transactions of that account are
+// skipped since they can't be applied without it.
+func (mp *Mempool) pick(take func(tx database.BlockTx) bool) []database.BlockTx {
 	mp.mu.RLock()
 	defer mp.mu.RUnlock()
 
-	var txs []database.BlockTx
+	accounts := make(map[database.AccountID][]database.BlockTx)
+	for key, tx := range mp.pool {
+		account := accountFromMapKey(key)
+		accounts[account] = append(accounts[account], tx)
+	}
 
-	for _, tx := range mp.pool {
-		txs = append(txs, tx)
+	for _, txs := range accounts {
+		sort.Slice(txs, func(i, j int) bool {
+			return txs[i].Nonce < txs[j].Nonce
+		})
+	}
+
+	var picked []database.BlockTx
+	for len(accounts) > 0 {
+
+		// Find the account whose next transaction has the highest tip. The
+		// account id breaks a tie so the order doesn't depend on the map.
+		var best database.AccountID
+		for account, txs := range accounts {
+			if best == "" {
+				best = account
+				continue
+			}
+
+			tip, bestTip := txs[0].Tip, accounts[best][0].Tip
+			if tip > bestTip || (tip == bestTip && account < best) {
+				best = account
+			}
+		}
+
+		tx := accounts[best][0]
+		if !take(tx) {
+			delete(accounts, best)
+			continue
+		}
+		picked = append(picked, tx)
+
+		if accounts[best] = accounts[best][1:]; len(accounts[best]) == 0 {
+			delete(accounts, best)
+		}
 	}
 
-	return txs
+	return picked
 }
 
 // =============================================================================
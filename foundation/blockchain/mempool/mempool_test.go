@@ -0,0 +1,159 @@
+package mempool_test
+
+import (
+	"testing"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/mempool"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// Accounts used by the tests.
+const (
+	kennedy = "0xF01813E4B85e178A83e29B8E7bF26BD830a25f32"
+	pavel   = "0xdd6B972ffcc631a62CAE1BB9d80b7ff429c8ebA4"
+	miner   = "0xFef311483Cc040e1A89fb9bb469eeB8A70935EF8"
+)
+
+// newTx returns a transaction for the mempool, which doesn't check the
+// signature.
+func newTx(from database.AccountID, nonce uint64, tip uint64, after uint64, until uint64) database.BlockTx {
+	tx := database.Tx{ChainID: 1, Nonce: nonce, FromID: from, Tip: tip, ValidAfter: after, ValidUntil: until}
+	return database.NewBlockTx(database.SignedTx{Tx: tx}, 15, 1)
+}
+
+// keys returns the account and nonce of the transactions in order.
+func keys(txs []database.BlockTx) []string {
+	var ks []string
+	for _, tx := range txs {
+		ks = append(ks, tx.String())
+	}
+	return ks
+}
+
+func TestDropExpired(t *testing.T) {
+	mp, err := mempool.New()
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to construct the mempool : %s", failed, err)
+	}
+
+	for _, tx := range []database.BlockTx{
+		newTx(pavel, 1, 0, 0, 0),
+		newTx(pavel, 2, 0, 0, 10),
+		newTx(kennedy, 1, 0, 0, 11),
+		newTx(kennedy, 2, 0, 12, 0),
+	} {
+		if err := mp.Upsert(tx); err != nil {
+			t.Fatalf("\t%s\tShould be able to add the transaction : %s", failed, err)
+		}
+	}
+
+	tt := []struct {
+		number  uint64
+		dropped int
+		left    int
+		picked  []string
+	}{
+		{number: 10, dropped: 0, left: 4, picked: []string{kennedy + ":1", pavel + ":1", pavel + ":2"}},
+		{number: 11, dropped: 1, left: 3, picked: []string{kennedy + ":1", pavel + ":1"}},
+		{number: 12, dropped: 1, left: 2, picked: []string{pavel + ":1"}},
+		{number: 13, dropped: 0, left: 2, picked: []string{kennedy + ":2", pavel + ":1"}},
+	}
+
+	t.Log("Given the need to drop the transactions whose validity window has passed.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen block %d is next.", testID, test.number)
+			{
+				if dropped := mp.DropExpired(test.number); dropped != test.dropped || mp.Count() != test.left {
+					t.Fatalf("\t%s\tTest %d:\tShould drop the expired transactions : got %d dropped and %d left, exp %d and %d", failed, testID, dropped, mp.Count(), test.dropped, test.left)
+				}
+				t.Logf("\t%s\tTest %d:\tShould drop the expired transactions.", success, testID)
+
+				picked := keys(mp.PickBest(test.number))
+				if len(picked) != len(test.picked) {
+					t.Fatalf("\t%s\tTest %d:\tShould pick the transactions valid in the block : got %v, exp %v", failed, testID, picked, test.picked)
+				}
+				for i := range picked {
+					if picked[i] != test.picked[i] {
+						t.Fatalf("\t%s\tTest %d:\tShould pick the transactions valid in the block : got %v, exp %v", failed, testID, picked, test.picked)
+					}
+				}
+				t.Logf("\t%s\tTest %d:\tShould pick the transactions valid in the block.", success, testID)
+			}
+		}
+	}
+}
+
+func TestPickBest(t *testing.T) {
+	// gasTx returns a transaction using the specified units of gas.
+	gasTx := func(from database.AccountID, nonce uint64, tip uint64, gasUnits uint64) database.BlockTx {
+		tx := newTx(from, nonce, tip, 0, 0)
+		tx.GasUnits = gasUnits
+		return tx
+	}
+
+	mp, err := mempool.New()
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to construct the mempool : %s", failed, err)
+	}
+
+	for _, tx := range []database.BlockTx{
+		gasTx(pavel, 3, 1, 1),
+		gasTx(pavel, 1, 5, 1),
+		gasTx(pavel, 2, 50, 1),
+		gasTx(kennedy, 2, 2, 1),
+		gasTx(kennedy, 1, 10, 3),
+		gasTx(miner, 1, 10, 2),
+	} {
+		if err := mp.Upsert(tx); err != nil {
+			t.Fatalf("\t%s\tShould be able to add the transaction : %s", failed, err)
+		}
+	}
+
+	tt := []struct {
+		name string
+		pick func() []database.BlockTx
+		exp  []string
+	}{
+		{
+			name: "picking every transaction",
+			pick: func() []database.BlockTx { return mp.PickBest(1) },
+			exp:  []string{kennedy + ":1", miner + ":1", pavel + ":1", pavel + ":2", kennedy + ":2", pavel + ":3"},
+		},
+		{
+			name: "picking a number of transactions",
+			pick: func() []database.BlockTx { return mp.PickBest(1, 3) },
+			exp:  []string{kennedy + ":1", miner + ":1", pavel + ":1"},
+		},
+		{
+			name: "picking the transactions that fit a gas limit",
+			pick: func() []database.BlockTx { return mp.PickBestGas(1, 4, 0) },
+			exp:  []string{kennedy + ":1", pavel + ":1"},
+		},
+	}
+
+	t.Log("Given the need to pick transactions in nonce order for an account and by tip between accounts.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen %s.", testID, test.name)
+			{
+				picked := keys(test.pick())
+				if len(picked) != len(test.exp) {
+					t.Fatalf("\t%s\tTest %d:\tShould pick the transactions in order : got %v, exp %v", failed, testID, picked, test.exp)
+				}
+				for i := range picked {
+					if picked[i] != test.exp[i] {
+						t.Fatalf("\t%s\tTest %d:\tShould pick the transactions in order : got %v, exp %v", failed, testID, picked, test.exp)
+					}
+				}
+				t.Logf("\t%s\tTest %d:\tShould pick the transactions in order.", success, testID)
+			}
+		}
+	}
+}
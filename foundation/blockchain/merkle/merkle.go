@@ -17,6 +17,13 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
+// Domain separation prefixes used by a hardened tree so a leaf can never be
+// confused with an intermediate node.
+var (
+	leafPrefix = []byte{0x00}
+	nodePrefix = []byte{0x01}
+)
+
 // Hashable represents the behavior concrete data must exhibit to be used in
 // the merkle tree.
 type Hashable[T any] interface {
@@ -33,6 +40,7 @@ type Tree[T Hashable[T]] struct {
 	Leafs        []*Node[T]
 	MerkleRoot   []byte
 	hashStrategy func() hash.Hash
+	hardened     bool
 }
 
 // WithHashStrategy is used to change the default hash strategy of using sha256
@@ -43,6 +51,14 @@ func WithHashStrategy[T Hashable[T]](hashStrategy func() hash.Hash) func(t *Tree
 	}
 }
 
+// WithHardened is used to construct a tree that is not vulnerable to the
+// duplicate leaf and second preimage attacks the default tree is open to.
+func WithHardened[T Hashable[T]]() func(t *Tree[T]) {
+	return func(t *Tree[T]) {
+		t.hardened = true
+	}
+}
+
 // NewTree constructs a new merkle tree that uses data of some type T that
 // exhibits the behavior defined by the Hashable interface.
 func NewTree[T Hashable[T]](values []T, options ...func(t *Tree[T])) (*Tree[T], error) {
@@ -71,9 +87,30 @@ func (t *Tree[T]) Generate(values []T) error {
 		return errors.New("cannot construct tree with no content")
 	}
 
+	// CORE NOTE: The default tree duplicates the last node of any level with
+	// an odd number of nodes, so a list of values with the last value repeated
+	// produces the same root as the list without it (CVE-2012-2459). Leafs and
+	// nodes are also hashed the same way, so an intermediate node can be passed
+	// off as a leaf. A hardened tree rejects duplicate values, prefixes leaf
+	// and node hashes differently and promotes an odd node to the next level
+	// instead of duplicating it.
+
 	var leafs []*Node[T]
-	for _, value := range values {
-		hash, err := value.Hash()
+	seen := make(map[string]struct{})
+	for i, value := range values {
+		if t.hardened {
+			hash, err := value.Hash()
+			if err != nil {
+				return err
+			}
+
+			if _, exists := seen[string(hash)]; exists {
+				return fmt.Errorf("duplicate value at index %d", i)
+			}
+			seen[string(hash)] = struct{}{}
+		}
+
+		hash, err := t.leafHash(value)
 		if err != nil {
 			return err
 		}
@@ -86,7 +123,7 @@ func (t *Tree[T]) Generate(values []T) error {
 		})
 	}
 
-	if len(leafs)%2 == 1 {
+	if !t.hardened && len(leafs)%2 == 1 {
 		duplicate := &Node[T]{
 			Hash:  leafs[len(leafs)-1].Hash,
 			Value: leafs[len(leafs)-1].Value,
@@ -166,7 +203,7 @@ func (t *Tree[T]) Proof(data T) ([][]byte, []int64, error) {
 		nodeParent := node.Parent
 
 		for nodeParent != nil {
-			if bytes.Equal(nodeParent.Left.Hash, node.Hash) {
+			if nodeParent.Left == node {
 				merkleProof = append(merkleProof, nodeParent.Right.Hash)
 				order = append(order, 1) // right leaf, concat second.
 			} else {
@@ -183,6 +220,57 @@ func (t *Tree[T]) Proof(data T) ([][]byte, []int64, error) {
 	return nil, nil, errors.New("unable to find data in tree")
 }
 
+// VerifyProof validates the proof for a leaf hash against a merkle root
+// without needing access to the tree. The proof and order are the values
+// returned by the Proof method and the hash function must be the hash
+// strategy the tree was constructed with. This allows a wallet or light
+// client to check a transaction is part of a block using just the block
+// header and the proof.
+func VerifyProof(leafHash []byte, proof [][]byte, order []int64, root []byte, hashFn func() hash.Hash) error {
+	return verifyProof(leafHash, proof, order, root, hashFn, false)
+}
+
+// VerifyHardenedProof validates a proof produced by a tree constructed with
+// the WithHardened option. The leaf hash is the hash of the value, before
+// the leaf prefix is applied.
+func VerifyHardenedProof(leafHash []byte, proof [][]byte, order []int64, root []byte, hashFn func() hash.Hash) error {
+	return verifyProof(leafHash, proof, order, root, hashFn, true)
+}
+
+// verifyProof performs the proof validation for both tree modes.
+func verifyProof(leafHash []byte, proof [][]byte, order []int64, root []byte, hashFn func() hash.Hash, hardened bool) error {
+	if len(proof) != len(order) {
+		return fmt.Errorf("proof has %d hashes but order has %d entries", len(proof), len(order))
+	}
+
+	calculated := leafHash
+	if hardened {
+		calculated = prefixHash(hashFn, leafPrefix, leafHash)
+	}
+
+	for i, p := range proof {
+		var prefix []byte
+		if hardened {
+			prefix = nodePrefix
+		}
+
+		switch order[i] {
+		case 0:
+			calculated = prefixHash(hashFn, prefix, p, calculated)
+		case 1:
+			calculated = prefixHash(hashFn, prefix, calculated, p)
+		default:
+			return fmt.Errorf("invalid proof order %d at position %d", order[i], i)
+		}
+	}
+
+	if !bytes.Equal(calculated, root) {
+		return errors.New("merkle root is not equivalent to the merkle root calculated from the proof")
+	}
+
+	return nil
+}
+
 // Verify validates the hashes at each level of the tree and returns true
 // if the resulting hash at the root of the tree matches the resulting root hash.
 func (t *Tree[T]) Verify() error {
@@ -221,12 +309,7 @@ func (t *Tree[T]) VerifyData(data T) error {
 				return err
 			}
 
-			h := t.hashStrategy()
-			if _, err := h.Write(append(leftBytes, rightBytes...)); err != nil {
-				return err
-			}
-
-			if !bytes.Equal(h.Sum(nil), currentParent.Hash) {
+			if !bytes.Equal(t.nodeHash(leftBytes, rightBytes), currentParent.Hash) {
 				return errors.New("merkle root is not equivalent to the merkle root calculated on the critical path")
 			}
 
@@ -247,13 +330,19 @@ func (t *Tree[T]) Values() []T {
 	}
 
 	l := len(t.Leafs)
-	if bytes.Equal(t.Leafs[l-1].Hash, t.Leafs[l-2].Hash) {
+	if t.Leafs[l-1].dup {
 		return values[:l-1]
 	}
 
 	return values
 }
 
+// Hardened returns true when the tree was constructed with the WithHardened
+// option.
+func (t *Tree[T]) Hardened() bool {
+	return t.hardened
+}
+
 // RootHex converts the merkle root byte hash to a hex encoded string.
 func (t *Tree[T]) RootHex() string {
 	return hexutil.Encode(t.MerkleRoot)
@@ -279,6 +368,31 @@ func (t *Tree[T]) MarshalText() (text []byte, err error) {
 	panic("do not marshal the merkle tree, use Values")
 }
 
+// leafHash calculates the hash of the leaf node for the value.
+func (t *Tree[T]) leafHash(value T) ([]byte, error) {
+	hash, err := value.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	if !t.hardened {
+		return hash, nil
+	}
+
+	return prefixHash(t.hashStrategy, leafPrefix, hash), nil
+}
+
+// nodeHash calculates the hash of an intermediate node from the hashes
+// of its children.
+func (t *Tree[T]) nodeHash(left []byte, right []byte) []byte {
+	var prefix []byte
+	if t.hardened {
+		prefix = nodePrefix
+	}
+
+	return prefixHash(t.hashStrategy, prefix, left, right)
+}
+
 // =============================================================================
 
 // Node represents a node, root, or leaf in the tree. It stores pointers to its
@@ -298,7 +412,7 @@ type Node[T Hashable[T]] struct {
 // each level and returning the resulting hash of the node.
 func (n *Node[T]) verify() ([]byte, error) {
 	if n.leaf {
-		return n.Value.Hash()
+		return n.Tree.leafHash(n.Value)
 	}
 
 	rightBytes, err := n.Right.verify()
@@ -311,26 +425,16 @@ func (n *Node[T]) verify() ([]byte, error) {
 		return nil, err
 	}
 
-	h := n.Tree.hashStrategy()
-	if _, err := h.Write(append(leftBytes, rightBytes...)); err != nil {
-		return nil, err
-	}
-
-	return h.Sum(nil), nil
+	return n.Tree.nodeHash(leftBytes, rightBytes), nil
 }
 
 // CalculateHash is a helper function that calculates the hash of the node.
 func (n *Node[T]) CalculateHash() ([]byte, error) {
 	if n.leaf {
-		return n.Value.Hash()
-	}
-
-	h := n.Tree.hashStrategy()
-	if _, err := h.Write(append(n.Left.Hash, n.Right.Hash...)); err != nil {
-		return nil, err
+		return n.Tree.leafHash(n.Value)
 	}
 
-	return h.Sum(nil), nil
+	return n.Tree.nodeHash(n.Left.Hash, n.Right.Hash), nil
 }
 
 // String returns a string representation of the node.
@@ -344,24 +448,32 @@ func (n *Node[T]) String() string {
 // constructs the intermediate and root levels of the tree. Returns the resulting
 // root node of the tree.
 func buildIntermediate[T Hashable[T]](nl []*Node[T], t *Tree[T]) (*Node[T], error) {
+
+	// A hardened tree can end up with a single node at any level, which
+	// is the root of the tree.
+	if len(nl) == 1 {
+		return nl[0], nil
+	}
+
 	var nodes []*Node[T]
 
 	for i := 0; i < len(nl); i += 2 {
 		left, right := i, i+1
 		if i+1 == len(nl) {
-			right = i
-		}
 
-		h := t.hashStrategy()
-		chash := append(nl[left].Hash, nl[right].Hash...)
-		if _, err := h.Write(chash); err != nil {
-			return nil, err
+			// A hardened tree promotes the odd node to the next level.
+			if t.hardened {
+				nodes = append(nodes, nl[i])
+				continue
+			}
+
+			right = i
 		}
 
 		n := Node[T]{
 			Left:  nl[left],
 			Right: nl[right],
-			Hash:  h.Sum(nil),
+			Hash:  t.nodeHash(nl[left].Hash, nl[right].Hash),
 			Tree:  t,
 		}
 
@@ -376,3 +488,14 @@ func buildIntermediate[T Hashable[T]](nl []*Node[T], t *Tree[T]) (*Node[T], erro
 
 	return buildIntermediate(nodes, t)
 }
+
+// prefixHash hashes the prefix followed by the data using the specified hash
+// strategy. Writing to a hash.Hash never returns an error.
+func prefixHash(hashFn func() hash.Hash, prefix []byte, data ...[]byte) []byte {
+	h := hashFn()
+	h.Write(prefix)
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
@@ -0,0 +1,136 @@
+package merkle_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/merkle"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// data represents a value stored in the trees of the tests.
+type data string
+
+// Hash implements the merkle Hashable interface.
+func (d data) Hash() ([]byte, error) {
+	return sha([]byte(d)), nil
+}
+
+// Equals implements the merkle Hashable interface.
+func (d data) Equals(other data) bool {
+	return d == other
+}
+
+func sha(parts ...[]byte) []byte {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+// leaf and node hash the way a hardened tree does, without using the package.
+func leaf(d data) []byte             { return sha([]byte{0x00}, sha([]byte(d))) }
+func node(left, right []byte) []byte { return sha([]byte{0x01}, left, right) }
+
+func TestHardenedTree(t *testing.T) {
+	a, b, c := data("a"), data("b"), data("c")
+
+	tt := []struct {
+		name string
+		vs   []data
+		exp  []byte
+	}{
+		{name: "a single value", vs: []data{a}, exp: leaf(a)},
+		{name: "two values", vs: []data{a, b}, exp: node(leaf(a), leaf(b))},
+		{name: "an odd number of values", vs: []data{a, b, c}, exp: node(node(leaf(a), leaf(b)), leaf(c))},
+	}
+
+	t.Log("Given the need to hash leafs and nodes of a hardened tree in separate domains.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen building a tree with %s.", testID, test.name)
+			{
+				tree, err := merkle.NewTree(test.vs, merkle.WithHardened[data]())
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to build the tree : %s", failed, testID, err)
+				}
+				if !bytes.Equal(tree.MerkleRoot, test.exp) {
+					t.Fatalf("\t%s\tTest %d:\tShould prefix the leafs and nodes and promote odd nodes : got %x, exp %x", failed, testID, tree.MerkleRoot, test.exp)
+				}
+				t.Logf("\t%s\tTest %d:\tShould prefix the leafs and nodes and promote odd nodes.", success, testID)
+
+				for _, v := range test.vs {
+					proof, order, err := tree.Proof(v)
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to prove %s : %s", failed, testID, v, err)
+					}
+					h, _ := v.Hash()
+					if err := merkle.VerifyHardenedProof(h, proof, order, tree.MerkleRoot, sha256.New); err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould verify the proof of %s : %s", failed, testID, v, err)
+					}
+				}
+				t.Logf("\t%s\tTest %d:\tShould verify the proof of every value.", success, testID)
+			}
+		}
+
+		testID := len(tt)
+		t.Logf("\tTest %d:\tWhen the last value is repeated.", testID)
+		{
+			plain, err := merkle.NewTree([]data{a, b, c})
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to build the tree : %s", failed, testID, err)
+			}
+			repeated, err := merkle.NewTree([]data{a, b, c, c})
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to build the tree : %s", failed, testID, err)
+			}
+			if !bytes.Equal(plain.MerkleRoot, repeated.MerkleRoot) {
+				t.Fatalf("\t%s\tTest %d:\tShould produce the same root in the default tree.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould produce the same root in the default tree.", success, testID)
+
+			if _, err := merkle.NewTree([]data{a, b, c, c}, merkle.WithHardened[data]()); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould reject the duplicate value in a hardened tree.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject the duplicate value in a hardened tree.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen an intermediate node is passed off as a leaf.", testID)
+		{
+			vs := []data{a, b, c, "d"}
+
+			plain, err := merkle.NewTree(vs)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to build the tree : %s", failed, testID, err)
+			}
+			ab := sha(sha([]byte(a)), sha([]byte(b)))
+			cd := sha(sha([]byte(c)), sha([]byte("d")))
+			if err := merkle.VerifyProof(ab, [][]byte{cd}, []int64{1}, plain.MerkleRoot, sha256.New); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould accept the node as a leaf in the default tree : %s", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept the node as a leaf in the default tree.", success, testID)
+
+			hardened, err := merkle.NewTree(vs, merkle.WithHardened[data]())
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to build the tree : %s", failed, testID, err)
+			}
+			hab := node(leaf(a), leaf(b))
+			hcd := node(leaf(c), leaf("d"))
+			if !bytes.Equal(node(hab, hcd), hardened.MerkleRoot) {
+				t.Fatalf("\t%s\tTest %d:\tShould calculate the hardened root : got %x", failed, testID, hardened.MerkleRoot)
+			}
+			if err := merkle.VerifyHardenedProof(hab, [][]byte{hcd}, []int64{1}, hardened.MerkleRoot, sha256.New); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould reject the node as a leaf in a hardened tree.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject the node as a leaf in a hardened tree.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+)
+
+// CORE NOTE: Proving a set of values with one proof per value repeats most of
+// the hashes near the root, and any hash that can be calculated from the
+// values being proven doesn't need to be sent at all. A multiproof walks the
+// tree one level at a time from the leafs, keeping track of which nodes can
+// be calculated. Only the sibling hashes that can't be calculated are added
+// to the proof, in the order the verifier will need them. The shape of the
+// tree is fully determined by the number of values and the tree mode, so
+// the verifier only needs the leaf count and the positions of the values.
+
+// MultiProof represents the information required to prove a set of values
+// are in a tree.
+type MultiProof struct {
+	LeafCount int      // Number of values in the tree.
+	Indices   []int    // Positions of the values being proven in ascending order.
+	Hashes    [][]byte // Sibling hashes in the order they are consumed.
+}
+
+// MultiProof returns a compact proof for the specified set of values. The
+// indices in the proof are sorted, so use them to match values to the leaf
+// hashes provided to the verify function.
+func (t *Tree[T]) MultiProof(values []T) (MultiProof, error) {
+	if len(values) == 0 {
+		return MultiProof{}, errors.New("no values to prove")
+	}
+
+	leafs := t.Leafs
+	if leafs[len(leafs)-1].dup {
+		leafs = leafs[:len(leafs)-1]
+	}
+
+	known := make(map[int]bool)
+	for _, value := range values {
+		index := -1
+		for i, leaf := range leafs {
+			if leaf.Value.Equals(value) {
+				index = i
+				break
+			}
+		}
+
+		if index == -1 {
+			return MultiProof{}, errors.New("unable to find data in tree")
+		}
+
+		known[index] = true
+	}
+
+	mp := MultiProof{
+		LeafCount: len(leafs),
+	}
+	for index := range known {
+		mp.Indices = append(mp.Indices, index)
+	}
+	sort.Ints(mp.Indices)
+
+	hashes := make([][]byte, len(leafs))
+	for i, leaf := range leafs {
+		hashes[i] = leaf.Hash
+	}
+
+	// Walk the levels using the same pairing rules as buildIntermediate.
+	for first := true; len(hashes) > 1 || (first && !t.hardened); first = false {
+		var next [][]byte
+		nextKnown := make(map[int]bool)
+
+		for i := 0; i < len(hashes); i += 2 {
+			j := i + 1
+			if j == len(hashes) {
+				if t.hardened {
+					nextKnown[len(next)] = known[i]
+					next = append(next, hashes[i])
+					continue
+				}
+				j = i
+			}
+
+			if known[i] || known[j] {
+				if !known[i] {
+					mp.Hashes = append(mp.Hashes, hashes[i])
+				}
+				if !known[j] {
+					mp.Hashes = append(mp.Hashes, hashes[j])
+				}
+				nextKnown[len(next)] = true
+			}
+
+			next = append(next, t.nodeHash(hashes[i], hashes[j]))
+		}
+
+		hashes, known = next, nextKnown
+	}
+
+	return mp, nil
+}
+
+// VerifyMultiProof validates a multiproof for the set of leaf hashes against
+// a merkle root without needing access to the tree. The leaf hashes must be
+// in the same order as the indices in the proof.
+func VerifyMultiProof(leafHashes [][]byte, proof MultiProof, root []byte, hashFn func() hash.Hash) error {
+	return verifyMultiProof(leafHashes, proof, root, hashFn, false)
+}
+
+// VerifyHardenedMultiProof validates a multiproof produced by a tree
+// constructed with the WithHardened option. The leaf hashes are the hashes
+// of the values, before the leaf prefix is applied.
+func VerifyHardenedMultiProof(leafHashes [][]byte, proof MultiProof, root []byte, hashFn func() hash.Hash) error {
+	return verifyMultiProof(leafHashes, proof, root, hashFn, true)
+}
+
+// verifyMultiProof performs the multiproof validation for both tree modes.
+func verifyMultiProof(leafHashes [][]byte, proof MultiProof, root []byte, hashFn func() hash.Hash, hardened bool) error {
+	if len(leafHashes) == 0 || len(leafHashes) != len(proof.Indices) {
+		return fmt.Errorf("proof has %d indices but %d leaf hashes were provided", len(proof.Indices), len(leafHashes))
+	}
+
+	var prefix []byte
+	if hardened {
+		prefix = nodePrefix
+	}
+
+	known := make(map[int][]byte)
+	for i, index := range proof.Indices {
+		if index < 0 || index >= proof.LeafCount {
+			return fmt.Errorf("index %d is out of range", index)
+		}
+		if i > 0 && index <= proof.Indices[i-1] {
+			return errors.New("indices must be unique and in ascending order")
+		}
+
+		known[index] = leafHashes[i]
+		if hardened {
+			known[index] = prefixHash(hashFn, leafPrefix, leafHashes[i])
+		}
+	}
+
+	// take returns the next hash from the proof.
+	var next int
+	take := func() ([]byte, error) {
+		if next == len(proof.Hashes) {
+			return nil, errors.New("proof is missing sibling hashes")
+		}
+		next++
+		return proof.Hashes[next-1], nil
+	}
+
+	// Walk the levels using the same pairing rules as buildIntermediate.
+	count := proof.LeafCount
+	for first := true; count > 1 || (first && !hardened); first = false {
+		nextKnown := make(map[int][]byte)
+		var n int
+
+		for i := 0; i < count; i, n = i+2, n+1 {
+			j := i + 1
+			if j == count {
+				if hardened {
+					if h, exists := known[i]; exists {
+						nextKnown[n] = h
+					}
+					continue
+				}
+				j = i
+			}
+
+			left, leftExists := known[i]
+			right, rightExists := known[j]
+			if !leftExists && !rightExists {
+				continue
+			}
+
+			var err error
+			if !leftExists {
+				if left, err = take(); err != nil {
+					return err
+				}
+			}
+			if !rightExists {
+				if right, err = take(); err != nil {
+					return err
+				}
+			}
+
+			nextKnown[n] = prefixHash(hashFn, prefix, left, right)
+		}
+
+		count, known = n, nextKnown
+	}
+
+	if next != len(proof.Hashes) {
+		return fmt.Errorf("proof has %d unused sibling hashes", len(proof.Hashes)-next)
+	}
+
+	if !bytes.Equal(known[0], root) {
+		return errors.New("merkle root is not equivalent to the merkle root calculated from the proof")
+	}
+
+	return nil
+}
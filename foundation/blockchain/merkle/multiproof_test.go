@@ -0,0 +1,190 @@
+package merkle_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"testing"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/merkle"
+)
+
+// values returns n distinct values.
+func values(n int) []data {
+	vs := make([]data, n)
+	for i := range vs {
+		vs[i] = data(fmt.Sprintf("tx %d", i))
+	}
+	return vs
+}
+
+func TestMultiProof(t *testing.T) {
+	tt := []struct {
+		name    string
+		count   int
+		prove   []int
+		indices []int
+	}{
+		{name: "a single leaf tree", count: 1, prove: []int{0}, indices: []int{0}},
+		{name: "one leaf of an odd tree", count: 5, prove: []int{4}, indices: []int{4}},
+		{name: "all leafs of an odd tree", count: 7, prove: []int{0, 1, 2, 3, 4, 5, 6}, indices: []int{0, 1, 2, 3, 4, 5, 6}},
+		{name: "all leafs of an even tree", count: 8, prove: []int{0, 1, 2, 3, 4, 5, 6, 7}, indices: []int{0, 1, 2, 3, 4, 5, 6, 7}},
+		{name: "unsorted leafs", count: 6, prove: []int{5, 0, 3}, indices: []int{0, 3, 5}},
+		{name: "duplicate leafs", count: 5, prove: []int{2, 2, 1}, indices: []int{1, 2}},
+	}
+
+	modes := []struct {
+		name    string
+		options []func(*merkle.Tree[data])
+		verify  func([][]byte, merkle.MultiProof, []byte, func() hash.Hash) error
+	}{
+		{name: "default", verify: merkle.VerifyMultiProof},
+		{name: "hardened", options: []func(*merkle.Tree[data]){merkle.WithHardened[data]()}, verify: merkle.VerifyHardenedMultiProof},
+	}
+
+	t.Log("Given the need to prove a set of values with one proof.")
+	{
+		testID := 0
+		for _, mode := range modes {
+			for _, test := range tt {
+				t.Logf("\tTest %d:\tWhen proving %s in a %s tree.", testID, test.name, mode.name)
+				{
+					vs := values(test.count)
+					tree, err := merkle.NewTree(vs, mode.options...)
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to build the tree : %s", failed, testID, err)
+					}
+
+					var prove []data
+					for _, i := range test.prove {
+						prove = append(prove, vs[i])
+					}
+
+					mp, err := tree.MultiProof(prove)
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to build the proof : %s", failed, testID, err)
+					}
+					if fmt.Sprint(mp.Indices) != fmt.Sprint(test.indices) || mp.LeafCount != test.count {
+						t.Fatalf("\t%s\tTest %d:\tShould prove the sorted unique indices : got %v of %d, exp %v of %d", failed, testID, mp.Indices, mp.LeafCount, test.indices, test.count)
+					}
+					t.Logf("\t%s\tTest %d:\tShould prove the sorted unique indices.", success, testID)
+
+					var hashes [][]byte
+					for _, i := range mp.Indices {
+						h, _ := vs[i].Hash()
+						hashes = append(hashes, h)
+					}
+					if err := mode.verify(hashes, mp, tree.MerkleRoot, sha256.New); err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould verify the proof : %s", failed, testID, err)
+					}
+					t.Logf("\t%s\tTest %d:\tShould verify the proof.", success, testID)
+				}
+				testID++
+			}
+		}
+	}
+}
+
+func TestForgedMultiProof(t *testing.T) {
+	vs := values(7)
+	tree, err := merkle.NewTree(vs, merkle.WithHardened[data]())
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to build the tree : %s", failed, err)
+	}
+
+	mp, err := tree.MultiProof([]data{vs[1], vs[4]})
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to build the proof : %s", failed, err)
+	}
+	h1, _ := vs[1].Hash()
+	h4, _ := vs[4].Hash()
+	h5, _ := vs[5].Hash()
+
+	// forge copies the proof so every case starts from the valid one.
+	forge := func(change func(mp *merkle.MultiProof)) merkle.MultiProof {
+		forged := merkle.MultiProof{
+			LeafCount: mp.LeafCount,
+			Indices:   append([]int(nil), mp.Indices...),
+		}
+		for _, h := range mp.Hashes {
+			forged.Hashes = append(forged.Hashes, append([]byte(nil), h...))
+		}
+		change(&forged)
+		return forged
+	}
+
+	tt := []struct {
+		name   string
+		hashes [][]byte
+		proof  merkle.MultiProof
+	}{
+		{
+			name:   "a sibling hash is changed",
+			hashes: [][]byte{h1, h4},
+			proof:  forge(func(mp *merkle.MultiProof) { mp.Hashes[0][0] ^= 0xff }),
+		},
+		{
+			name:   "a sibling hash is removed",
+			hashes: [][]byte{h1, h4},
+			proof:  forge(func(mp *merkle.MultiProof) { mp.Hashes = mp.Hashes[1:] }),
+		},
+		{
+			name:   "a sibling hash is added",
+			hashes: [][]byte{h1, h4},
+			proof:  forge(func(mp *merkle.MultiProof) { mp.Hashes = append(mp.Hashes, h5) }),
+		},
+		{
+			name:   "a leaf hash is swapped for another value",
+			hashes: [][]byte{h1, h5},
+			proof:  forge(func(mp *merkle.MultiProof) {}),
+		},
+		{
+			name:   "the indices are unsorted",
+			hashes: [][]byte{h4, h1},
+			proof:  forge(func(mp *merkle.MultiProof) { mp.Indices = []int{4, 1} }),
+		},
+		{
+			name:   "an index is duplicated",
+			hashes: [][]byte{h1, h1},
+			proof:  forge(func(mp *merkle.MultiProof) { mp.Indices = []int{1, 1} }),
+		},
+		{
+			name:   "an index is out of range",
+			hashes: [][]byte{h1, h4},
+			proof:  forge(func(mp *merkle.MultiProof) { mp.Indices = []int{1, 7} }),
+		},
+		{
+			name:   "the leaf count is lowered",
+			hashes: [][]byte{h1, h4},
+			proof:  forge(func(mp *merkle.MultiProof) { mp.LeafCount = 6 }),
+		},
+	}
+
+	t.Log("Given the need to reject multiproofs that were tampered with.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen the proof is untouched.", testID)
+		{
+			if err := merkle.VerifyHardenedMultiProof([][]byte{h1, h4}, mp, tree.MerkleRoot, sha256.New); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould verify the proof : %s", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould verify the proof.", success, testID)
+
+			if err := merkle.VerifyMultiProof([][]byte{h1, h4}, mp, tree.MerkleRoot, sha256.New); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould fail to verify the proof as a default tree proof.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould fail to verify the proof as a default tree proof.", success, testID)
+		}
+
+		for i, test := range tt {
+			testID = i + 1
+			t.Logf("\tTest %d:\tWhen %s.", testID, test.name)
+			{
+				if err := merkle.VerifyHardenedMultiProof(test.hashes, test.proof, tree.MerkleRoot, sha256.New); err == nil {
+					t.Fatalf("\t%s\tTest %d:\tShould fail to verify the proof.", failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould fail to verify the proof.", success, testID)
+			}
+		}
+	}
+}
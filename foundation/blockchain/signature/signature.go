@@ -31,6 +31,11 @@ func Hash(value any) string {
 		return ZeroHash
 	}
 
+	return HashBytes(data)
+}
+
+// HashBytes returns a unique string for data that has already been encoded.
+func HashBytes(data []byte) string {
 	hash := sha256.Sum256(data)
 	return hexutil.Encode(hash[:])
 }
@@ -38,12 +43,22 @@ func Hash(value any) string {
 // Sign uses the specified private key to sign the data.
 func Sign(value any, privateKey *ecdsa.PrivateKey) (v, r, s *big.Int, err error) {
 
-	// Prepare the data for signing.
-	data, err := stamp(value)
+	// Marshal the data.
+	encoded, err := json.Marshal(value)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
+	return SignBytes(encoded, privateKey)
+}
+
+// SignBytes uses the specified private key to sign data that has already
+// been encoded.
+func SignBytes(encoded []byte, privateKey *ecdsa.PrivateKey) (v, r, s *big.Int, err error) {
+
+	// Prepare the data for signing.
+	data := stamp(encoded)
+
 	// Sign the hash with the private key to produce a signature.
 	sig, err := crypto.Sign(data, privateKey)
 	if err != nil {
@@ -90,12 +105,22 @@ func VerifySignature(v, r, s *big.Int) error {
 // FromAddress extracts the address for the account that signed the data.
 func FromAddress(value any, v, r, s *big.Int) (string, error) {
 
-	// Prepare the data for public key extraction.
-	data, err := stamp(value)
+	// Marshal the data.
+	encoded, err := json.Marshal(value)
 	if err != nil {
 		return "", err
 	}
 
+	return FromAddressBytes(encoded, v, r, s)
+}
+
+// FromAddressBytes extracts the address for the account that signed data
+// that has already been encoded.
+func FromAddressBytes(encoded []byte, v, r, s *big.Int) (string, error) {
+
+	// Prepare the data for public key extraction.
+	data := stamp(encoded)
+
 	// Convert the [R|S|V] format into the original 65 bytes.
 	sig := ToSignatureBytes(v, r, s)
 
@@ -145,23 +170,15 @@ func ToSignatureBytesWithArdanID(v, r, s *big.Int) []byte {
 
 // stamp returns a hash of 32 bytes that represents this data with
 // the Ardan stamp embedded into the final hash.
-func stamp(value any) ([]byte, error) {
-
-	// Marshal the data.
-	v, err := json.Marshal(value)
-	if err != nil {
-		return nil, err
-	}
+func stamp(encoded []byte) []byte {
 
 	// This stamp is used so signatures we produce when signing data
 	// are always unique to the Ardan blockchain.
-	stamp := []byte(fmt.Sprintf("\x19Ardan Signed Message:\n%d", len(v)))
+	stamp := []byte(fmt.Sprintf("\x19Ardan Signed Message:\n%d", len(encoded)))
 
 	// Hash the stamp and txHash together in a final 32 byte array
 	// that represents the data.
-	data := crypto.Keccak256(stamp, v)
-
-	return data, nil
+	return crypto.Keccak256(stamp, encoded)
 }
 
 // toSignatureValues converts the signature into the r, s, v values.
@@ -0,0 +1,259 @@
+// Package smt provides an implementation of a sparse merkle tree that is used
+// to authenticate the account state of the blockchain.
+package smt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// CORE NOTE: A sparse merkle tree has a leaf for every possible 256 bit key,
+// almost all of them empty. The hash of an empty subtree at any height is a
+// known constant, so only the nodes on the path of keys that have a value are
+// stored. Updating a key rehashes the 256 nodes on its path, no matter how many
+// other keys exist. A proof for a key is the 256 sibling hashes on the path,
+// where empty siblings are left out and marked in a bitmap. The same proof
+// shows a key has no value when the leaf is empty. Nodes are never changed,
+// an update creates the nodes on the path of the key again and shares the
+// rest with the previous version of the tree. That makes a copy of the tree
+// free, so the state a block commits to can be kept next to the current one.
+
+// Depth represents the number of levels below the root of the tree.
+const Depth = 256
+
+// KeySize represents the number of bytes in a key.
+const KeySize = Depth / 8
+
+// Domain separation prefixes so a leaf can never be confused with a node.
+var (
+	leafPrefix = []byte{0x00}
+	nodePrefix = []byte{0x01}
+)
+
+// defaults holds the hash of an empty subtree at each height.
+var defaults = func() [Depth + 1][]byte {
+	var d [Depth + 1][]byte
+	d[0] = make([]byte, sha256.Size)
+	for h := 0; h < Depth; h++ {
+		d[h+1] = hashNode(d[h], d[h])
+	}
+	return d
+}()
+
+// =============================================================================
+
+// Key represents the location of a leaf in the tree.
+type Key [KeySize]byte
+
+// NewKey constructs a key by hashing the specified data so keys are spread
+// evenly across the tree.
+func NewKey(data []byte) Key {
+	return sha256.Sum256(data)
+}
+
+// bit returns the bit at the specified index starting from the most
+// significant bit.
+func (k Key) bit(i int) byte {
+	return (k[i/8] >> (7 - uint(i%8))) & 1
+}
+
+// =============================================================================
+
+// node represents a subtree that is different from the empty subtree. A nil
+// node is an empty subtree. Nodes are never changed once created.
+type node struct {
+	hash  []byte
+	left  *node
+	right *node
+}
+
+// hashOf returns the hash of the node at the specified height.
+func hashOf(n *node, height int) []byte {
+	if n == nil {
+		return defaults[height]
+	}
+	return n.hash
+}
+
+// update returns the node at the specified height on the path of the key with
+// the hash of the leaf replaced. The nodes on the path are created again and
+// the rest are shared with the node being replaced.
+func update(n *node, height int, key Key, leaf []byte) *node {
+	if height == 0 {
+		if bytes.Equal(leaf, defaults[0]) {
+			return nil
+		}
+		return &node{hash: leaf}
+	}
+
+	var left, right *node
+	if n != nil {
+		left, right = n.left, n.right
+	}
+
+	switch key.bit(Depth - height) {
+	case 0:
+		left = update(left, height-1, key, leaf)
+	default:
+		right = update(right, height-1, key, leaf)
+	}
+
+	if left == nil && right == nil {
+		return nil
+	}
+
+	return &node{
+		hash:  hashNode(hashOf(left, height-1), hashOf(right, height-1)),
+		left:  left,
+		right: right,
+	}
+}
+
+// =============================================================================
+
+// Tree represents a sparse merkle tree. Only nodes that are different from
+// the empty subtree hash are stored.
+type Tree struct {
+	root *node
+}
+
+// NewTree constructs an empty tree.
+func NewTree() *Tree {
+	return &Tree{}
+}
+
+// Root returns the root hash of the tree.
+func (t *Tree) Root() []byte {
+	return hashOf(t.root, Depth)
+}
+
+// RootHex converts the root hash to a hex encoded string.
+func (t *Tree) RootHex() string {
+	return hexutil.Encode(t.Root())
+}
+
+// Copy returns a copy of the tree that isn't affected by later updates. The
+// nodes are shared, so the cost doesn't depend on the size of the tree.
+func (t *Tree) Copy() *Tree {
+	return &Tree{
+		root: t.root,
+	}
+}
+
+// Update sets the value for the specified key and rehashes the path to the
+// root. A nil value removes the key from the tree.
+func (t *Tree) Update(key Key, value []byte) {
+	leaf := defaults[0]
+	if value != nil {
+		leaf = hashLeaf(key, value)
+	}
+
+	t.root = update(t.root, Depth, key, leaf)
+}
+
+// Proof returns the proof for the specified key against the current root.
+// The proof is valid for a key with no value as well.
+func (t *Tree) Proof(key Key) Proof {
+
+	// Walk the path of the key from the root down, keeping the hash of the
+	// sibling at each height.
+	var siblings [Depth][]byte
+	n := t.root
+	for height := Depth; height > 0; height-- {
+		var sibling *node
+		if n != nil {
+			switch key.bit(Depth - height) {
+			case 0:
+				sibling, n = n.right, n.left
+			default:
+				sibling, n = n.left, n.right
+			}
+		}
+		siblings[height-1] = hashOf(sibling, height-1)
+	}
+
+	var proof Proof
+	for h := 0; h < Depth; h++ {
+		if bytes.Equal(siblings[h], defaults[h]) {
+			continue
+		}
+
+		proof.Bitmap[h/8] |= 1 << uint(h%8)
+		proof.Siblings = append(proof.Siblings, siblings[h])
+	}
+
+	return proof
+}
+
+// =============================================================================
+
+// Proof represents the sibling hashes required to recalculate the root hash
+// from a leaf. Siblings are ordered from the leaf up and only include the
+// siblings whose bit is set in the bitmap, the rest are empty subtrees.
+type Proof struct {
+	Bitmap   [KeySize]byte
+	Siblings [][]byte
+}
+
+// VerifyProof checks the proof shows the key holds the value in the tree with
+// the specified root. A nil value checks the key has no value. This function
+// doesn't need access to the tree so it can be used by light clients.
+func VerifyProof(root []byte, key Key, value []byte, proof Proof) error {
+	hash := defaults[0]
+	if value != nil {
+		hash = hashLeaf(key, value)
+	}
+
+	next := 0
+	for h := 0; h < Depth; h++ {
+		sibling := defaults[h]
+		if proof.Bitmap[h/8]&(1<<uint(h%8)) != 0 {
+			if next == len(proof.Siblings) {
+				return errors.New("proof is missing sibling hashes")
+			}
+			sibling = proof.Siblings[next]
+			next++
+		}
+
+		switch key.bit(Depth - 1 - h) {
+		case 0:
+			hash = hashNode(hash, sibling)
+		default:
+			hash = hashNode(sibling, hash)
+		}
+	}
+
+	if next != len(proof.Siblings) {
+		return fmt.Errorf("proof has %d unused sibling hashes", len(proof.Siblings)-next)
+	}
+
+	if !bytes.Equal(hash, root) {
+		return errors.New("calculated root does not match the state root")
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// hashLeaf calculates the hash of a leaf holding the value for the key.
+func hashLeaf(key Key, value []byte) []byte {
+	h := sha256.New()
+	h.Write(leafPrefix)
+	h.Write(key[:])
+	h.Write(value)
+	return h.Sum(nil)
+}
+
+// hashNode calculates the hash of a node from its children.
+func hashNode(left []byte, right []byte) []byte {
+	h := sha256.New()
+	h.Write(nodePrefix)
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
@@ -0,0 +1,278 @@
+package smt_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/smt"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// emptyRoots calculates the hash of an empty subtree at every height
+// without using the package.
+func emptyRoots() [smt.Depth + 1][]byte {
+	var d [smt.Depth + 1][]byte
+	d[0] = make([]byte, sha256.Size)
+	for h := 0; h < smt.Depth; h++ {
+		d[h+1] = sha(append(append([]byte{0x01}, d[h]...), d[h]...))
+	}
+	return d
+}
+
+// oneLeafRoot calculates the root of a tree holding one key without using
+// the package. The bits of the key pick the path from the root down.
+func oneLeafRoot(key smt.Key, value []byte) []byte {
+	empty := emptyRoots()
+
+	hash := sha(append(append([]byte{0x00}, key[:]...), value...))
+	for h := 0; h < smt.Depth; h++ {
+		bit := (key[(smt.Depth-1-h)/8] >> (7 - uint((smt.Depth-1-h)%8))) & 1
+		switch bit {
+		case 0:
+			hash = sha(append(append([]byte{0x01}, hash...), empty[h]...))
+		default:
+			hash = sha(append(append([]byte{0x01}, empty[h]...), hash...))
+		}
+	}
+
+	return hash
+}
+
+func sha(data []byte) []byte {
+	h := sha256.Sum256(data)
+	return h[:]
+}
+
+func TestRoots(t *testing.T) {
+	keyA := smt.NewKey([]byte("account a"))
+	keyB := smt.NewKey([]byte("account b"))
+	empty := emptyRoots()[smt.Depth]
+
+	t.Log("Given the need to calculate the root of the tree as keys change.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen the tree is empty.", testID)
+		{
+			if got := smt.NewTree().Root(); !bytes.Equal(got, empty) {
+				t.Fatalf("\t%s\tTest %d:\tShould have the empty subtree hash as the root : got %x, exp %x", failed, testID, got, empty)
+			}
+			t.Logf("\t%s\tTest %d:\tShould have the empty subtree hash as the root.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen inserting one key.", testID)
+		{
+			tree := smt.NewTree()
+			tree.Update(keyA, []byte("100"))
+
+			if exp := oneLeafRoot(keyA, []byte("100")); !bytes.Equal(tree.Root(), exp) {
+				t.Fatalf("\t%s\tTest %d:\tShould hash the leaf up the path of the key : got %x, exp %x", failed, testID, tree.Root(), exp)
+			}
+			t.Logf("\t%s\tTest %d:\tShould hash the leaf up the path of the key.", success, testID)
+		}
+
+		testID = 2
+		t.Logf("\tTest %d:\tWhen inserting keys in a different order.", testID)
+		{
+			ab := smt.NewTree()
+			ab.Update(keyA, []byte("100"))
+			ab.Update(keyB, []byte("200"))
+
+			ba := smt.NewTree()
+			ba.Update(keyB, []byte("200"))
+			ba.Update(keyA, []byte("100"))
+
+			if !bytes.Equal(ab.Root(), ba.Root()) {
+				t.Fatalf("\t%s\tTest %d:\tShould calculate the same root : got %x and %x", failed, testID, ab.Root(), ba.Root())
+			}
+			t.Logf("\t%s\tTest %d:\tShould calculate the same root.", success, testID)
+		}
+
+		testID = 3
+		t.Logf("\tTest %d:\tWhen updating and deleting keys.", testID)
+		{
+			tree := smt.NewTree()
+			tree.Update(keyA, []byte("100"))
+			onlyA := tree.Root()
+
+			tree.Update(keyB, []byte("200"))
+			both := tree.Root()
+
+			tree.Update(keyB, []byte("250"))
+			if bytes.Equal(tree.Root(), both) {
+				t.Fatalf("\t%s\tTest %d:\tShould change the root when a value changes.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould change the root when a value changes.", success, testID)
+
+			tree.Update(keyB, []byte("200"))
+			if !bytes.Equal(tree.Root(), both) {
+				t.Fatalf("\t%s\tTest %d:\tShould return to the root when the value is set back : got %x, exp %x", failed, testID, tree.Root(), both)
+			}
+			t.Logf("\t%s\tTest %d:\tShould return to the root when the value is set back.", success, testID)
+
+			tree.Update(keyB, nil)
+			if !bytes.Equal(tree.Root(), onlyA) {
+				t.Fatalf("\t%s\tTest %d:\tShould return to the root without the key once it's deleted : got %x, exp %x", failed, testID, tree.Root(), onlyA)
+			}
+			t.Logf("\t%s\tTest %d:\tShould return to the root without the key once it's deleted.", success, testID)
+
+			tree.Update(keyA, nil)
+			if !bytes.Equal(tree.Root(), empty) {
+				t.Fatalf("\t%s\tTest %d:\tShould return to the empty root once every key is deleted : got %x, exp %x", failed, testID, tree.Root(), empty)
+			}
+			t.Logf("\t%s\tTest %d:\tShould return to the empty root once every key is deleted.", success, testID)
+		}
+
+		testID = 4
+		t.Logf("\tTest %d:\tWhen updating a tree after it was copied.", testID)
+		{
+			tree := smt.NewTree()
+			tree.Update(keyA, []byte("100"))
+			copied := tree.Copy()
+			root := copied.Root()
+
+			tree.Update(keyA, []byte("150"))
+			tree.Update(keyB, []byte("200"))
+
+			if !bytes.Equal(copied.Root(), root) {
+				t.Fatalf("\t%s\tTest %d:\tShould leave the copy unchanged : got %x, exp %x", failed, testID, copied.Root(), root)
+			}
+			t.Logf("\t%s\tTest %d:\tShould leave the copy unchanged.", success, testID)
+		}
+	}
+}
+
+func TestProofs(t *testing.T) {
+	keyA := smt.NewKey([]byte("account a"))
+	keyB := smt.NewKey([]byte("account b"))
+	keyC := smt.NewKey([]byte("account c"))
+
+	tree := smt.NewTree()
+	tree.Update(keyA, []byte("100"))
+	tree.Update(keyB, []byte("200"))
+	root := tree.Root()
+
+	tt := []struct {
+		name  string
+		key   smt.Key
+		value []byte
+		valid bool
+	}{
+		{name: "proving the value of a key", key: keyA, value: []byte("100"), valid: true},
+		{name: "proving the value of another key", key: keyB, value: []byte("200"), valid: true},
+		{name: "proving a key has no value", key: keyC, value: nil, valid: true},
+		{name: "proving the wrong value of a key", key: keyA, value: []byte("101"), valid: false},
+		{name: "proving a key with a value has none", key: keyA, value: nil, valid: false},
+		{name: "proving a value for a key that has none", key: keyC, value: []byte("100"), valid: false},
+	}
+
+	t.Log("Given the need to prove the value of a key against the root.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen %s.", testID, test.name)
+			{
+				err := smt.VerifyProof(root, test.key, test.value, tree.Proof(test.key))
+				switch {
+				case test.valid && err != nil:
+					t.Fatalf("\t%s\tTest %d:\tShould verify the proof : %s", failed, testID, err)
+				case !test.valid && err == nil:
+					t.Fatalf("\t%s\tTest %d:\tShould fail to verify the proof.", failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould verify the proof is %v.", success, testID, test.valid)
+			}
+		}
+	}
+}
+
+func TestTamperedProofs(t *testing.T) {
+	keyA := smt.NewKey([]byte("account a"))
+	keyB := smt.NewKey([]byte("account b"))
+	keyC := smt.NewKey([]byte("account c"))
+
+	tree := smt.NewTree()
+	tree.Update(keyA, []byte("100"))
+	tree.Update(keyB, []byte("200"))
+	tree.Update(keyC, []byte("300"))
+	root := tree.Root()
+
+	// firstBit returns the index of the lowest bit set in the bitmap.
+	firstBit := func(p smt.Proof) int {
+		for h := 0; h < smt.Depth; h++ {
+			if p.Bitmap[h/8]&(1<<uint(h%8)) != 0 {
+				return h
+			}
+		}
+		return -1
+	}
+
+	tt := []struct {
+		name   string
+		tamper func(p smt.Proof) smt.Proof
+	}{
+		{
+			name: "a sibling hash is changed",
+			tamper: func(p smt.Proof) smt.Proof {
+				sibling := append([]byte(nil), p.Siblings[0]...)
+				sibling[0] ^= 0xff
+				p.Siblings = append([][]byte{sibling}, p.Siblings[1:]...)
+				return p
+			},
+		},
+		{
+			name: "a bit of the bitmap is cleared",
+			tamper: func(p smt.Proof) smt.Proof {
+				h := firstBit(p)
+				p.Bitmap[h/8] &^= 1 << uint(h%8)
+				return p
+			},
+		},
+		{
+			name: "a bit of the bitmap is set",
+			tamper: func(p smt.Proof) smt.Proof {
+				// The keys only share a few bits, so the sibling next to the
+				// leaf is empty and its bit is clear.
+				p.Bitmap[0] |= 1
+				p.Siblings = append([][]byte{sha([]byte("forged"))}, p.Siblings...)
+				return p
+			},
+		},
+		{
+			name: "a sibling hash is removed",
+			tamper: func(p smt.Proof) smt.Proof {
+				p.Siblings = p.Siblings[1:]
+				return p
+			},
+		},
+		{
+			name: "a sibling hash is added",
+			tamper: func(p smt.Proof) smt.Proof {
+				p.Siblings = append(p.Siblings, make([]byte, sha256.Size))
+				return p
+			},
+		},
+	}
+
+	t.Log("Given the need to reject proofs that were tampered with.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen %s.", testID, test.name)
+			{
+				proof := tree.Proof(keyA)
+				if err := smt.VerifyProof(root, keyA, []byte("100"), proof); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould verify the untouched proof : %s", failed, testID, err)
+				}
+
+				if err := smt.VerifyProof(root, keyA, []byte("100"), test.tamper(proof)); err == nil {
+					t.Fatalf("\t%s\tTest %d:\tShould fail to verify the proof.", failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould fail to verify the proof.", success, testID)
+			}
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 
 	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
 )
 
 // ErrNoTransactions is returned when a block is requested to be created
@@ -18,6 +19,10 @@ var ErrNoTransactions = errors.New("no transactions in mempool")
 func (s *State) MineNewBlock(ctx context.Context) (database.Block, error) {
 	defer s.evHandler("viewer: MineNewBlock: MINING: completed")
 
+	if s.light {
+		return database.Block{}, ErrLightMode
+	}
+
 	s.evHandler("state: MineNewBlock: MINING: check mempool count")
 
 	// Are there enough transactions in the pool.
@@ -25,8 +30,40 @@ func (s *State) MineNewBlock(ctx context.Context) (database.Block, error) {
 		return database.Block{}, ErrNoTransactions
 	}
 
-	// Pick the best transactions from the mempool.
-	trans := s.mempool.PickBest(s.genesis.TransPerBlock)
+	// Capture the consensus rules that apply to the block being mined.
+	prevBlock := s.db.LatestBlock()
+	rules := s.genesis.Rules(prevBlock.Header.Number + 1)
+
+	// Pick the best transactions from the mempool. Once the gasMetering fork
+	// is active the block is filled up to the block gas limit instead of a
+	// number of transactions.
+	baseFee := database.NextBaseFee(s.genesis, prevBlock.Header)
+
+	var trans []database.BlockTx
+	switch {
+	case rules.IsActive(genesis.ForkGasMetering):
+		trans = s.mempool.PickBestGas(rules.Number, rules.Gas.BlockLimit, baseFee)
+	default:
+		trans = s.mempool.PickBest(rules.Number, rules.TransPerBlock)
+	}
+
+	// Transactions accepted under different rules, like before a fork
+	// activated, can't be mined into this block and are dropped.
+	valid := trans[:0]
+	for _, tx := range trans {
+		if err := tx.ValidateGas(rules); err != nil {
+			s.evHandler("state: MineNewBlock: MINING: drop tx[%s]: %s", tx, err)
+			s.mempool.Delete(tx)
+			continue
+		}
+
+		valid = append(valid, tx)
+	}
+	trans = valid
+
+	if len(trans) == 0 {
+		return database.Block{}, ErrNoTransactions
+	}
 
 	// // If PoA is being used, drop the difficulty down to 1 to speed up
 	// // the mining operation.
@@ -37,11 +74,13 @@ func (s *State) MineNewBlock(ctx context.Context) (database.Block, error) {
 
 	// Attempt to create a new block by solving the POW puzzle. This can be cancelled.
 	block, err := database.POW(ctx, database.POWArgs{
+		GenesisHash:   s.genesis.Hash(),
+		Rules:         rules,
 		BeneficiaryID: s.beneficiaryID,
-		Difficulty:    s.genesis.Difficulty,
-		MiningReward:  s.genesis.MiningReward,
-		PrevBlock:     s.db.LatestBlock(),
-		StateRoot:     s.db.HashState(),
+		Difficulty:    rules.Difficulty,
+		BaseFee:       baseFee,
+		PrevBlock:     prevBlock,
+		StateRoot:     s.db.HashState(prevBlock.Header.Number + 1),
 		Trans:         trans,
 		EvHandler:     s.evHandler,
 	})
@@ -80,7 +119,7 @@ func (s *State) validateUpdateDatabase(block database.Block) error {
 	// me to this function for the same block number, I could replace the peer
 	// block with my own and attempt to have other peers accept my block instead.
 
-	if err := block.ValidateBlock(s.db.LatestBlock(), s.db.HashState(), s.evHandler); err != nil {
+	if err := block.ValidateBlock(s.genesis, s.db.LatestBlock(), s.db.HashState(block.Header.Number), s.evHandler); err != nil {
 		return err
 	}
 
@@ -111,7 +150,40 @@ func (s *State) validateUpdateDatabase(block database.Block) error {
 	s.evHandler("state: validateUpdateDatabase: apply mining reward")
 
 	// Apply the mining reward for this block.
-	s.db.ApplyMiningReward(block)
+	if err := s.db.ApplyMiningReward(block); err != nil {
+		s.evHandler("state: validateUpdateDatabase: WARNING : %s", err)
+	}
+
+	s.evHandler("state: validateUpdateDatabase: expire locks")
+
+	// Refund the locks that have expired by the end of this block.
+	if err := s.db.ExpireLocks(block); err != nil {
+		s.evHandler("state: validateUpdateDatabase: WARNING : %s", err)
+	}
+
+	s.evHandler("state: validateUpdateDatabase: unlock balances")
+
+	// Release the time locks that have matured by the end of this block.
+	if err := s.db.UnlockBalances(block); err != nil {
+		s.evHandler("state: validateUpdateDatabase: WARNING : %s", err)
+	}
+
+	s.evHandler("state: validateUpdateDatabase: drop expired transactions")
+
+	// Drop the transactions that can't be mined into any later block.
+	if dropped := s.mempool.DropExpired(block.Header.Number + 1); dropped > 0 {
+		s.evHandler("state: validateUpdateDatabase: dropped %d expired transactions", dropped)
+	}
+
+	// A pruned node deletes the transactions of blocks that are now deep
+	// enough in the chain. A failure here doesn't affect the new block.
+	if s.pruneDepth > 0 {
+		s.evHandler("state: validateUpdateDatabase: prune blocks older than depth[%d]", s.pruneDepth)
+
+		if err := s.db.Prune(s.pruneDepth); err != nil {
+			s.evHandler("state: validateUpdateDatabase: WARNING : %s", err)
+		}
+	}
 
 	// Send an event about this new block.
 	// s.blockEvent(block)
@@ -0,0 +1,97 @@
+package state
+
+import (
+	"sort"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
+)
+
+// Set of values used to suggest fees.
+const (
+	feeEstimateFullBlocks = 6  // Number of full blocks the suggested max fee covers.
+	feeEstimateTipBlocks  = 20 // Number of recent blocks the suggested tip is based on.
+)
+
+// FeeEstimate represents the suggested fees for a transaction to be mined
+// into one of the next blocks.
+type FeeEstimate struct {
+	BaseFee  uint64 // Base fee per unit of gas of the next block.
+	MaxFee   uint64 // Max fee per unit of gas that covers the base fee rising for several full blocks.
+	Tip      uint64 // Median tip of the transactions in the recent blocks.
+	GasPrice uint64 // Fixed gas price, used before the baseFee fork is active.
+	GasUnits uint64 // Gas used by a transfer without data.
+}
+
+// QueryFeeEstimate returns the suggested fees for a transaction to be mined
+// into one of the next blocks.
+func (s *State) QueryFeeEstimate() (FeeEstimate, error) {
+	latest := s.RetrieveLatestHeader()
+	rules := s.genesis.Rules(latest.Number + 1)
+
+	gasUnits, err := database.Tx{}.Gas(rules)
+	if err != nil {
+		return FeeEstimate{}, err
+	}
+
+	fe := FeeEstimate{
+		GasPrice: rules.GasPrice,
+		GasUnits: gasUnits,
+		Tip:      s.medianTip(latest.Number),
+	}
+
+	if !rules.IsActive(genesis.ForkBaseFee) {
+		return fe, nil
+	}
+
+	// Walk the base fee forward as if the next blocks are all full, which
+	// is the fastest the base fee can rise.
+	fe.BaseFee = database.NextBaseFee(s.genesis, latest)
+
+	header := database.BlockHeader{
+		Number:  latest.Number + 1,
+		GasUsed: rules.Gas.BlockLimit,
+		BaseFee: fe.BaseFee,
+	}
+	for i := 0; i < feeEstimateFullBlocks; i++ {
+		header.BaseFee = database.NextBaseFee(s.genesis, header)
+		header.Number++
+	}
+	fe.MaxFee = header.BaseFee
+
+	return fe, nil
+}
+
+// medianTip returns the median tip of the transactions in the recent blocks
+// up to the specified block. Nodes without the blocks, like light nodes,
+// return 0.
+func (s *State) medianTip(latest uint64) uint64 {
+	if s.light || latest == 0 {
+		return 0
+	}
+
+	var from uint64 = 1
+	if latest > feeEstimateTipBlocks {
+		from = latest - feeEstimateTipBlocks + 1
+	}
+
+	blocks, err := s.db.Blocks(from, latest)
+	if err != nil {
+		return 0
+	}
+
+	var tips []uint64
+	for _, block := range blocks {
+		for _, tx := range block.Trans {
+			tips = append(tips, tx.Tip)
+		}
+	}
+
+	if len(tips) == 0 {
+		return 0
+	}
+
+	sort.Slice(tips, func(i, j int) bool { return tips[i] < tips[j] })
+
+	return tips[len(tips)/2]
+}
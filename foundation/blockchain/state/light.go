@@ -0,0 +1,214 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/peer"
+)
+
+// CORE NOTE: A node running in light mode never downloads full blocks, so it
+// has no transactions and no account state of its own. It syncs the chain of
+// block headers from its peers and validates the hash chain, POW and
+// difficulty of each one. When asked about a transaction or an account, it
+// asks a peer for the data along with a merkle proof and only answers when
+// the proof checks out against a header it has already validated. A peer can
+// refuse to answer, but it can't lie.
+
+// ErrLightMode is returned when an operation needs the full blockchain and
+// the node is running in light mode.
+var ErrLightMode = errors.New("not supported by a node running in light mode")
+
+// IsLight returns true when the node is running in light mode.
+func (s *State) IsLight() bool {
+	return s.light
+}
+
+// RetrieveLatestHeader returns the header of the latest block this node
+// has validated.
+func (s *State) RetrieveLatestHeader() database.BlockHeader {
+	if s.light {
+		return s.headers.Latest()
+	}
+
+	return s.db.LatestBlock().Header
+}
+
+// RetrieveHeaders returns the block headers for the specified range of
+// block numbers.
+func (s *State) RetrieveHeaders(from uint64, to uint64) ([]database.BlockHeader, error) {
+	if s.light {
+		return s.headers.Range(from, to), nil
+	}
+
+	if from == 0 {
+		from = 1
+	}
+
+	return s.db.Headers(from, to)
+}
+
+// RetrieveTxProof returns the mined transaction with the specified hash along
+// with the merkle proof of its inclusion, for peers running in light mode.
+func (s *State) RetrieveTxProof(txHash string) (database.TxProof, error) {
+	if s.light {
+		return database.TxProof{}, ErrLightMode
+	}
+
+	return s.db.TxProof(txHash)
+}
+
+// RetrieveAccountProof returns the account along with a proof against the
+// state root of the latest block header, for peers running in light mode.
+func (s *State) RetrieveAccountProof(accountID database.AccountID) (database.AccountProof, error) {
+	if s.light {
+		return database.AccountProof{}, ErrLightMode
+	}
+
+	return s.db.CommittedAccountProof(accountID)
+}
+
+// =============================================================================
+
+// lightQueryTxProof asks the known peers for the transaction and its proof
+// until a peer provides one that verifies against the header chain.
+func (s *State) lightQueryTxProof(txHash string) (database.TxProof, error) {
+	err := errors.New("no known peers")
+
+	for _, pr := range s.RetrieveKnownPeers() {
+		var tp database.TxProof
+		tp, err = s.NetRequestPeerTxProof(pr, txHash)
+		if err != nil {
+			continue
+		}
+
+		if err = s.lightVerifyTxProof(pr, tp); err != nil {
+			s.evHandler("state: lightQueryTxProof: peer[%s]: WARNING: %s", pr, err)
+			continue
+		}
+
+		return tp, nil
+	}
+
+	return database.TxProof{}, err
+}
+
+// lightVerifyTxProof checks the transaction proof and that the block it's
+// for is part of the header chain.
+func (s *State) lightVerifyTxProof(pr peer.Peer, tp database.TxProof) error {
+	if err := tp.Verify(s.genesis); err != nil {
+		return err
+	}
+
+	header, err := s.lightHeader(pr, tp.Header.Number)
+	if err != nil {
+		return err
+	}
+
+	if hash := header.Hash(); hash != tp.Header.Hash() || hash != tp.BlockHash {
+		return fmt.Errorf("block %d doesn't match the header chain", header.Number)
+	}
+
+	return nil
+}
+
+// lightQueryAccountProof asks the known peers for the account and its proof
+// until a peer provides one that verifies against the header chain.
+func (s *State) lightQueryAccountProof(accountID database.AccountID) (database.AccountProof, error) {
+	err := errors.New("no known peers")
+
+	for _, pr := range s.RetrieveKnownPeers() {
+		var ap database.AccountProof
+		ap, err = s.NetRequestPeerAccountProof(pr, accountID)
+		if err != nil {
+			continue
+		}
+
+		var header database.BlockHeader
+		header, err = s.lightHeader(pr, ap.BlockNumber+1)
+		if err != nil {
+			continue
+		}
+
+		if err = ap.Verify(accountID, header); err != nil {
+			s.evHandler("state: lightQueryAccountProof: peer[%s]: WARNING: %s", pr, err)
+			continue
+		}
+
+		return ap, nil
+	}
+
+	return database.AccountProof{}, err
+}
+
+// lightQueryAccountBlocks uses the blooms in the header chain to find the
+// blocks in the specified range that may have a transaction for the account.
+// Only those blocks are downloaded from the known peers.
+func (s *State) lightQueryAccountBlocks(accountID database.AccountID, from uint64, to uint64) ([]database.BlockData, error) {
+	var blocks []database.BlockData
+	for _, header := range s.headers.Range(from, to) {
+		if !header.Bloom.Test(accountID) {
+			continue
+		}
+
+		blockData, err := s.lightBlock(header)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tx := range blockData.Trans {
+			if tx.HasAccount(accountID) {
+				blocks = append(blocks, blockData)
+				break
+			}
+		}
+	}
+
+	return blocks, nil
+}
+
+// lightBlock asks the known peers for the block with the specified header
+// until a peer provides one whose transactions match the header.
+func (s *State) lightBlock(header database.BlockHeader) (database.BlockData, error) {
+	err := errors.New("no known peers")
+
+	for _, pr := range s.RetrieveKnownPeers() {
+		var blockData database.BlockData
+		blockData, err = s.NetRequestPeerBlock(pr, header.Number)
+		if err != nil {
+			continue
+		}
+
+		var block database.Block
+		block, err = database.ToBlock(s.genesis, blockData)
+		if err != nil {
+			continue
+		}
+
+		if block.Hash() != header.Hash() || block.MerkleTree.RootHex() != header.TransRoot {
+			err = fmt.Errorf("block %d doesn't match the header chain", header.Number)
+			s.evHandler("state: lightBlock: peer[%s]: WARNING: %s", pr, err)
+			continue
+		}
+
+		return blockData, nil
+	}
+
+	return database.BlockData{}, err
+}
+
+// lightHeader returns the header for the specified block number. If the
+// header hasn't been synced yet, the headers are synced from the peer first.
+func (s *State) lightHeader(pr peer.Peer, number uint64) (database.BlockHeader, error) {
+	header, err := s.headers.Get(number)
+	if err == nil {
+		return header, nil
+	}
+
+	if err := s.NetRequestPeerHeaders(pr); err != nil {
+		return database.BlockHeader{}, err
+	}
+
+	return s.headers.Get(number)
+}
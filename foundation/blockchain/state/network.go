@@ -14,6 +14,10 @@ import (
 
 const baseURL = "http://%s/v1/node"
 
+// snapshotAttempts represents the number of times a snapshot download starts
+// over from the peer's latest block when the snapshot moved.
+const snapshotAttempts = 3
+
 // NetSendBlockToPeers takes the new mined block and sends it to all know peers.
 func (s *State) NetSendBlockToPeers(block database.Block) error {
 	s.evHandler("state: NetSendBlockToPeers: started")
@@ -139,20 +143,166 @@ func (s *State) NetRequestPeerBlocks(pr peer.Peer) error {
 
 	s.evHandler("state: NetRequestPeerBlocks: found blocks[%d]", len(blocksData))
 
-	// for _, blockData := range blocksData {
-	// 	block, err := database.ToBlock(blockData)
-	// 	if err != nil {
-	// 		return err
-	// 	}
+	for _, blockData := range blocksData {
+		block, err := database.ToBlock(s.genesis, blockData)
+		if err != nil {
+			return err
+		}
 
-	// 	if err := s.ProcessProposedBlock(block); err != nil {
-	// 		return err
-	// 	}
-	// }
+		if err := s.validateUpdateDatabase(block); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
+// NetRequestPeerBlock queries the specified node for a single block.
+func (s *State) NetRequestPeerBlock(pr peer.Peer, number uint64) (database.BlockData, error) {
+	url := fmt.Sprintf("%s/block/list/%d/%d", fmt.Sprintf(baseURL, pr.Host), number, number)
+
+	var blocksData []database.BlockData
+	if err := send(http.MethodGet, url, nil, &blocksData); err != nil {
+		return database.BlockData{}, err
+	}
+
+	if len(blocksData) != 1 {
+		return database.BlockData{}, fmt.Errorf("block %d: %w", number, database.ErrNotFound)
+	}
+
+	return blocksData[0], nil
+}
+
+// NetRequestPeerSnapshot downloads the chunks of the peer's state snapshot
+// for the specified block number.
+func (s *State) NetRequestPeerSnapshot(pr peer.Peer, blockNumber uint64) (database.Snapshot, error) {
+	s.evHandler("state: NetRequestPeerSnapshot: started: %s", pr)
+	defer s.evHandler("state: NetRequestPeerSnapshot: completed: %s", pr)
+
+	var sa database.SnapshotAssembler
+	for chunk := 0; !sa.Done(); chunk++ {
+		url := fmt.Sprintf("%s/state/snapshot/%d/%d", fmt.Sprintf(baseURL, pr.Host), blockNumber, chunk)
+
+		var sc database.SnapshotChunk
+		if err := send(http.MethodGet, url, nil, &sc); err != nil {
+			return database.Snapshot{}, err
+		}
+
+		if err := sa.Add(sc); err != nil {
+			return database.Snapshot{}, err
+		}
+
+		s.evHandler("state: NetRequestPeerSnapshot: chunk[%d of %d]: accounts[%d]", sc.Chunk+1, sc.Chunks, len(sc.Accounts))
+	}
+
+	return sa.Snapshot()
+}
+
+// NetSyncPeerSnapshot starts an empty node from the specified peer's state.
+// The peer's headers are downloaded and validated, the latest block is used
+// as the pivot, and the peer's state snapshot at the pivot is checked
+// against the pivot's state root. The blocks from the pivot forward still
+// need to be downloaded.
+func (s *State) NetSyncPeerSnapshot(pr peer.Peer) error {
+	s.evHandler("state: NetSyncPeerSnapshot: started: %s", pr)
+	defer s.evHandler("state: NetSyncPeerSnapshot: completed: %s", pr)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db.LatestBlock().Header.Number != 0 {
+		return errors.New("node already has blocks")
+	}
+
+	// The peer only keeps the snapshots of its recent blocks, so when the
+	// pivot is dropped during the download, start over from the peer's new
+	// latest block.
+	for attempt := 1; ; attempt++ {
+		url := fmt.Sprintf("%s/block/headers/1/latest", fmt.Sprintf(baseURL, pr.Host))
+
+		var headers []database.BlockHeader
+		if err := send(http.MethodGet, url, nil, &headers); err != nil {
+			return err
+		}
+
+		if len(headers) == 0 {
+			return errors.New("peer has no blocks")
+		}
+
+		pivot := headers[len(headers)-1].Number
+		s.evHandler("state: NetSyncPeerSnapshot: found headers[%d]: pivot[%d]", len(headers), pivot)
+
+		snapshot, err := s.NetRequestPeerSnapshot(pr, pivot)
+		if err != nil {
+			if errors.Is(err, database.ErrSnapshotMoved) && attempt < snapshotAttempts {
+				s.evHandler("state: NetSyncPeerSnapshot: snapshot moved: pivot[%d]: attempt[%d]", pivot, attempt)
+				continue
+			}
+			return err
+		}
+
+		return s.db.InstallSnapshot(headers, snapshot, s.evHandler)
+	}
+}
+
+// NetRequestPeerHeaders queries the specified node asking for block headers
+// this node does not have, then validates and adds them to the header chain.
+// This is how a node running in light mode follows the chain.
+func (s *State) NetRequestPeerHeaders(pr peer.Peer) error {
+	s.evHandler("state: NetRequestPeerHeaders: started: %s", pr)
+	defer s.evHandler("state: NetRequestPeerHeaders: completed: %s", pr)
+
+	from := s.headers.Latest().Number + 1
+	url := fmt.Sprintf("%s/block/headers/%d/latest", fmt.Sprintf(baseURL, pr.Host), from)
+
+	var headers []database.BlockHeader
+	if err := send(http.MethodGet, url, nil, &headers); err != nil {
+		return err
+	}
+
+	s.evHandler("state: NetRequestPeerHeaders: found headers[%d]", len(headers))
+
+	for _, header := range headers {
+		if err := s.headers.Append(header, s.evHandler); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NetRequestPeerTxProof asks the specified node for a mined transaction along
+// with the merkle proof of its inclusion.
+func (s *State) NetRequestPeerTxProof(pr peer.Peer, txHash string) (database.TxProof, error) {
+	s.evHandler("state: NetRequestPeerTxProof: started: %s", pr)
+	defer s.evHandler("state: NetRequestPeerTxProof: completed: %s", pr)
+
+	url := fmt.Sprintf("%s/tx/proof/%s", fmt.Sprintf(baseURL, pr.Host), txHash)
+
+	var tp database.TxProof
+	if err := send(http.MethodGet, url, nil, &tp); err != nil {
+		return database.TxProof{}, err
+	}
+
+	return tp, nil
+}
+
+// NetRequestPeerAccountProof asks the specified node for an account along
+// with the proof of its state against the node's latest block header.
+func (s *State) NetRequestPeerAccountProof(pr peer.Peer, accountID database.AccountID) (database.AccountProof, error) {
+	s.evHandler("state: NetRequestPeerAccountProof: started: %s", pr)
+	defer s.evHandler("state: NetRequestPeerAccountProof: completed: %s", pr)
+
+	url := fmt.Sprintf("%s/accounts/proof/%s", fmt.Sprintf(baseURL, pr.Host), accountID)
+
+	var ap database.AccountProof
+	if err := send(http.MethodGet, url, nil, &ap); err != nil {
+		return database.AccountProof{}, err
+	}
+
+	return ap, nil
+}
+
 // =============================================================================
 
 // send is a helper function to send an HTTP request to a node.
@@ -189,6 +339,14 @@ func send(method string, url string, dataSend any, dataRecv any) error {
 		return nil
 	}
 
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s: %w", url, database.ErrNotFound)
+	}
+
+	if resp.StatusCode == http.StatusGone {
+		return fmt.Errorf("%s: %w", url, database.ErrSnapshotMoved)
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		msg, err := io.ReadAll(resp.Body)
 		if err != nil {
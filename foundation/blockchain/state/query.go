@@ -10,6 +10,17 @@ import (
 
 // QueryAccounts returns a copy of the account from the database.
 func (s *State) QueryAccounts(account database.AccountID) (database.Account, error) {
+	if s.light {
+		ap, err := s.lightQueryAccountProof(account)
+		if err != nil {
+			return database.Account{}, err
+		}
+		if ap.Value == nil {
+			return database.Account{}, errors.New("not found")
+		}
+		return ap.Account, nil
+	}
+
 	accounts := s.db.CopyAccounts()
 
 	if info, exists := accounts[account]; exists {
@@ -19,6 +30,92 @@ func (s *State) QueryAccounts(account database.AccountID) (database.Account, err
 	return database.Account{}, errors.New("not found")
 }
 
+// QueryAccountProof returns the account along with a proof it's part of
+// the current state trie.
+func (s *State) QueryAccountProof(account database.AccountID) (database.AccountProof, error) {
+	if s.light {
+		return s.lightQueryAccountProof(account)
+	}
+
+	return s.db.AccountProof(account)
+}
+
+// QueryContract returns the code and storage of the specified contract
+// account.
+func (s *State) QueryContract(account database.AccountID) (database.Contract, error) {
+	if s.light {
+		return database.Contract{}, ErrLightMode
+	}
+
+	return s.db.Contract(account)
+}
+
+// QueryTokens returns the tokens issued on the chain.
+func (s *State) QueryTokens() (map[database.AccountID]database.Token, error) {
+	if s.light {
+		return nil, ErrLightMode
+	}
+
+	return s.db.Tokens(), nil
+}
+
+// QuerySupply returns the issued, circulating and locked supply.
+func (s *State) QuerySupply() (database.Supply, error) {
+	if s.light {
+		return database.Supply{}, ErrLightMode
+	}
+
+	return s.db.Supply()
+}
+
+// QueryToken returns the issuer, symbol, decimals and supply of the
+// specified token.
+func (s *State) QueryToken(tokenID database.AccountID) (database.Token, error) {
+	if s.light {
+		ap, err := s.lightQueryAccountProof(tokenID)
+		if err != nil {
+			return database.Token{}, err
+		}
+		token, isToken := ap.Account.TokenInfo()
+		if !isToken {
+			return database.Token{}, database.ErrNotFound
+		}
+		return token, nil
+	}
+
+	return s.db.Token(tokenID)
+}
+
+// QueryTxProof returns the mined transaction with the specified hash along
+// with the merkle proof of its inclusion in a block.
+func (s *State) QueryTxProof(txHash string) (database.TxProof, error) {
+	if s.light {
+		return s.lightQueryTxProof(txHash)
+	}
+
+	return s.db.TxProof(txHash)
+}
+
+// QueryTxMultiProof returns the mined transactions with the specified hashes
+// along with a single merkle proof of their inclusion in a block.
+func (s *State) QueryTxMultiProof(txHashes []string) (database.TxMultiProof, error) {
+	if s.light {
+		return database.TxMultiProof{}, ErrLightMode
+	}
+
+	return s.db.TxMultiProof(txHashes)
+}
+
+// QueryAccountBlocks returns the blocks in the specified range that have a
+// transaction from or to the specified account.
+func (s *State) QueryAccountBlocks(accountID database.AccountID, from uint64, to uint64) ([]database.BlockData, error) {
+	if s.light {
+		return s.lightQueryAccountBlocks(accountID, from, to)
+	}
+
+	return s.db.AccountBlocks(accountID, from, to)
+}
+
 // QueryMempoolLength returns the current length of the mempool.
 func (s *State) QueryMempoolLength() int {
 	return s.mempool.Count()
@@ -12,7 +12,7 @@ func (s *State) RetrieveHost() string {
 
 // RetrieveMempool returns a copy of the mempool.
 func (s *State) RetrieveMempool() []database.BlockTx {
-	return s.mempool.PickBest()
+	return s.mempool.All()
 }
 
 // RetrieveLatestBlock returns a copy the current latest block.
@@ -20,6 +20,30 @@ func (s *State) RetrieveLatestBlock() database.Block {
 	return s.db.LatestBlock()
 }
 
+// RetrieveBlocks returns the blocks for the specified range of block numbers
+// so they can be shared with peers.
+func (s *State) RetrieveBlocks(from uint64, to uint64) ([]database.BlockData, error) {
+	if s.light {
+		return nil, ErrLightMode
+	}
+
+	if from == 0 {
+		from = 1
+	}
+
+	return s.db.Blocks(from, to)
+}
+
+// RetrieveSnapshotChunk returns the specified chunk of the snapshot of the
+// state the latest block header commits to, so a new peer can start from it.
+func (s *State) RetrieveSnapshotChunk(blockNumber uint64, chunk int) (database.SnapshotChunk, error) {
+	if s.light {
+		return database.SnapshotChunk{}, ErrLightMode
+	}
+
+	return s.db.SnapshotChunk(blockNumber, chunk)
+}
+
 // RetrieveAccounts returns a copy of the database accounts.
 func (s *State) RetrieveAccounts() map[database.AccountID]database.Account {
 	return s.db.CopyAccounts()
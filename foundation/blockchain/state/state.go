@@ -3,6 +3,7 @@
 package state
 
 import (
+	"path"
 	"sync"
 
 	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
@@ -33,8 +34,12 @@ type Worker interface {
 type Config struct {
 	BeneficiaryID database.AccountID
 	Host          string
+	GenesisPath   string
 	DBPath        string
 	KnownPeers    *peer.PeerSet
+	Light         bool
+	PruneDepth    uint64
+	SnapSync      bool
 	EvHandler     EventHandler
 }
 
@@ -43,6 +48,9 @@ type State struct {
 	mu          sync.RWMutex
 	resyncWG    sync.WaitGroup
 	allowMining bool
+	light       bool
+	pruneDepth  uint64
+	snapSync    bool
 
 	beneficiaryID database.AccountID
 	host          string
@@ -53,6 +61,7 @@ type State struct {
 	genesis    genesis.Genesis
 	mempool    *mempool.Mempool
 	db         *database.Database
+	headers    *database.HeaderChain
 
 	Worker Worker
 }
@@ -69,7 +78,7 @@ func New(cfg Config) (*State, error) {
 
 	// Load the genesis file to get starting balances for
 	// founders of the blockchain
-	genesis, err := genesis.Load()
+	genesis, err := genesis.Load(cfg.GenesisPath)
 	if err != nil {
 		return nil, err
 	}
@@ -86,6 +95,20 @@ func New(cfg Config) (*State, error) {
 		return nil, err
 	}
 
+	// A node running in light mode keeps its header chain in a directory of
+	// its own, so the headers aren't read as blocks of the full chain.
+	var headers *database.HeaderChain
+	if cfg.Light {
+		headerStorage, err := disk.New(path.Join(cfg.DBPath, "headers"))
+		if err != nil {
+			return nil, err
+		}
+
+		if headers, err = database.NewHeaderChain(genesis, headerStorage, ev); err != nil {
+			return nil, err
+		}
+	}
+
 	// Construct a mempool with the specified sort strategy.
 	mempool, err := mempool.New()
 	if err != nil {
@@ -99,11 +122,15 @@ func New(cfg Config) (*State, error) {
 		dbPath:        cfg.DBPath,
 		evHandler:     ev,
 		allowMining:   true,
+		light:         cfg.Light,
+		pruneDepth:    cfg.PruneDepth,
+		snapSync:      cfg.SnapSync,
 
 		knownPeers: cfg.KnownPeers,
 		genesis:    genesis,
 		mempool:    mempool,
 		db:         db,
+		headers:    headers,
 	}
 
 	// The Worker is not set here. The call to worker.Run will assign itself
@@ -112,6 +139,12 @@ func New(cfg Config) (*State, error) {
 	return &state, nil
 }
 
+// IsSnapSync returns true when the node starts from a peer's state snapshot
+// instead of replaying every block.
+func (s *State) IsSnapSync() bool {
+	return s.snapSync
+}
+
 // Shutdown cleanly brings the node down.
 func (s *State) Shutdown() error {
 	s.evHandler("state: shutdown: started")
@@ -1,25 +1,64 @@
 package state
 
 import (
+	"fmt"
+
 	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/genesis"
 )
 
 // UpsertWalletTransaction accepts a transaction from a wallet for inclusion.
 func (s *State) UpsertWalletTransaction(signedTx database.SignedTx) error {
+	if s.light {
+		return ErrLightMode
+	}
 
 	// CORE NOTE: It's up to the wallet to make sure the account has a proper
 	// balance and this transaction has a proper nonce. Fees will be taken if
 	// this transaction is mined into a block it doesn't have enough money to
-	// pay or the nonce isn't the next expected nonce for the account.
+	// pay or the nonce isn't the next expected nonce for the account. The
+	// exception is a fee payer, which is checked to hold enough to pay for
+	// the gas and tip since it didn't create the transaction.
+
+	// Capture the consensus rules for the next block to be mined.
+	rules := s.genesis.Rules(s.db.LatestBlock().Header.Number + 1)
 
 	// Check the signed transaction has a proper signature, the from matches the
 	// signature, and the from and to fields are properly formatted.
-	if err := signedTx.Validate(s.genesis.ChainID); err != nil {
+	if err := signedTx.Validate(rules); err != nil {
+		return err
+	}
+
+	// A transaction that expired can't be mined into any later block.
+	if signedTx.Expired(rules.Number) {
+		return fmt.Errorf("transaction expired after block %d", signedTx.ValidUntil)
+	}
+
+	if err := s.validateSigners(signedTx); err != nil {
+		return err
+	}
+
+	// Charge the gas the transaction uses based on its size and type.
+	gasUnits, err := signedTx.Gas(rules)
+	if err != nil {
+		return err
+	}
+
+	// Once the baseFee fork is active the gas price is the base fee of the
+	// block the transaction is mined in, which the max fee needs to cover.
+	gasPrice := rules.GasPrice
+	if rules.IsActive(genesis.ForkBaseFee) {
+		gasPrice = database.NextBaseFee(s.genesis, s.db.LatestBlock().Header)
+		if signedTx.MaxFee < gasPrice {
+			return fmt.Errorf("max fee %d is below the base fee %d", signedTx.MaxFee, gasPrice)
+		}
+	}
+
+	tx := database.NewBlockTx(signedTx, gasPrice, gasUnits)
+	if err := s.db.ValidateFeePayer(tx); err != nil {
 		return err
 	}
 
-	const oneUnitOfGas = 1
-	tx := database.NewBlockTx(signedTx, s.genesis.GasPrice, oneUnitOfGas)
 	if err := s.mempool.Upsert(tx); err != nil {
 		return err
 	}
@@ -31,10 +70,34 @@ func (s *State) UpsertWalletTransaction(signedTx database.SignedTx) error {
 
 // UpsertNodeTransaction accepts a transaction from a node for inclusion.
 func (s *State) UpsertNodeTransaction(tx database.BlockTx) error {
+	if s.light {
+		return ErrLightMode
+	}
+
+	// Capture the consensus rules for the next block to be mined.
+	rules := s.genesis.Rules(s.db.LatestBlock().Header.Number + 1)
 
 	// Check the signed transaction has a proper signature, the from matches the
 	// signature, and the from and to fields are properly formatted.
-	if err := tx.Validate(s.genesis.ChainID); err != nil {
+	if err := tx.Validate(rules); err != nil {
+		return err
+	}
+
+	// A transaction that expired can't be mined into any later block.
+	if tx.Expired(rules.Number) {
+		return fmt.Errorf("transaction expired after block %d", tx.ValidUntil)
+	}
+
+	if err := s.validateSigners(tx.SignedTx); err != nil {
+		return err
+	}
+
+	// Check the node charged the gas the transaction uses.
+	if err := tx.ValidateGas(rules); err != nil {
+		return err
+	}
+
+	if err := s.db.ValidateFeePayer(tx); err != nil {
 		return err
 	}
 
@@ -44,3 +107,18 @@ func (s *State) UpsertNodeTransaction(tx database.BlockTx) error {
 
 	return nil
 }
+
+// validateSigners checks a transaction from a multisig account is signed by
+// enough of the account's signers.
+func (s *State) validateSigners(signedTx database.SignedTx) error {
+	address, err := signedTx.FromAccount()
+	if err != nil {
+		return err
+	}
+
+	if address == signedTx.FromID {
+		return nil
+	}
+
+	return s.db.ValidateSigners(signedTx)
+}
@@ -65,8 +65,15 @@ func (d *Disk) Write(blockData database.BlockData) error {
 // contents of the specified block by number.
 func (d *Disk) GetBlock(num uint64) (database.BlockData, error) {
 
-	// Open the block file for the specified number.
+	// Open the block file for the specified number. When the block has been
+	// pruned, only the header is available.
+	pruned := false
 	f, err := os.OpenFile(d.getPath(num), os.O_RDONLY, 0600)
+	if errors.Is(err, fs.ErrNotExist) {
+		if pf, perr := os.OpenFile(d.getPrunedPath(num), os.O_RDONLY, 0600); perr == nil {
+			f, err, pruned = pf, nil, true
+		}
+	}
 	if err != nil {
 		return database.BlockData{}, err
 	}
@@ -78,10 +85,82 @@ func (d *Disk) GetBlock(num uint64) (database.BlockData, error) {
 		return database.BlockData{}, err
 	}
 
+	// Blocks stored in an older format need to be migrated before the node
+	// can use them.
+	if blockData.Version < database.BlockDataVersion {
+		return database.BlockData{}, fmt.Errorf("block %d has format version %d, run the migration tool to upgrade to version %d", num, blockData.Version, database.BlockDataVersion)
+	}
+
+	if pruned {
+		return blockData, fmt.Errorf("block %d: %w", num, database.ErrPruned)
+	}
+
 	// Return the block as a database block.
 	return blockData, nil
 }
 
+// Prune replaces the file for the specified block with a file that only
+// holds the block header. Pruning a block that is already pruned does nothing.
+func (d *Disk) Prune(num uint64) error {
+	blockData, err := d.GetBlock(num)
+	if err != nil {
+		if errors.Is(err, database.ErrPruned) {
+			return nil
+		}
+		return err
+	}
+
+	blockData.Trans = nil
+
+	data, err := json.MarshalIndent(blockData, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write the header before removing the full block so the header is never lost.
+	if err := os.WriteFile(d.getPrunedPath(num), data, 0600); err != nil {
+		return err
+	}
+
+	return os.Remove(d.getPath(num))
+}
+
+// WriteSnapshot stores the account state snapshot, replacing the previous one.
+func (d *Disk) WriteSnapshot(snapshot database.Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a partially
+	// written snapshot behind.
+	tmp := d.getSnapshotPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, d.getSnapshotPath())
+}
+
+// GetSnapshot returns the stored account state snapshot. The database
+// ErrNotFound error is returned when there is no snapshot.
+func (d *Disk) GetSnapshot() (database.Snapshot, error) {
+	content, err := os.ReadFile(d.getSnapshotPath())
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return database.Snapshot{}, database.ErrNotFound
+		}
+		return database.Snapshot{}, err
+	}
+
+	var snapshot database.Snapshot
+	if err := json.Unmarshal(content, &snapshot); err != nil {
+		return database.Snapshot{}, fmt.Errorf("decoding snapshot: %w", err)
+	}
+
+	return snapshot, nil
+}
+
 // ForEach returns an iterator to walk through all the blocks
 // starting with block number 1.
 func (d *Disk) ForEach() database.Iterator {
@@ -96,6 +175,29 @@ func (d *Disk) getPath(blockNum uint64) string {
 	return path.Join(d.dbPath, fmt.Sprintf("%s.json", name))
 }
 
+// getPrunedPath forms the path to the specified block once it's pruned.
+func (d *Disk) getPrunedPath(blockNum uint64) string {
+	name := strconv.FormatUint(blockNum, 10)
+	return path.Join(d.dbPath, fmt.Sprintf("%s.pruned.json", name))
+}
+
+// findPath returns the path of the file that holds the specified block,
+// which is the pruned file once the block has been pruned.
+func (d *Disk) findPath(blockNum uint64) string {
+	if _, err := os.Stat(d.getPath(blockNum)); errors.Is(err, fs.ErrNotExist) {
+		if _, err := os.Stat(d.getPrunedPath(blockNum)); err == nil {
+			return d.getPrunedPath(blockNum)
+		}
+	}
+
+	return d.getPath(blockNum)
+}
+
+// getSnapshotPath forms the path to the account state snapshot.
+func (d *Disk) getSnapshotPath() string {
+	return path.Join(d.dbPath, "snapshot.json")
+}
+
 // =============================================================================
 
 // diskIterator represents the iteration implementation for walking
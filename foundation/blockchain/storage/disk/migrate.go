@@ -0,0 +1,212 @@
+package disk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/database"
+)
+
+// Migration represents a change to the format of the block data stored on
+// disk. A migration upgrades a block from the specified version to the next
+// version. The block is provided as a map of the top level JSON fields so
+// the migration doesn't depend on the current Go types.
+type Migration struct {
+	Version     uint16
+	Description string
+	Apply       func(block map[string]json.RawMessage) error
+}
+
+// migrations is the ordered set of migrations required to bring a block from
+// any older version to the current database.BlockDataVersion.
+var migrations = []Migration{
+	{
+		Version:     0,
+		Description: "stamp the format version on blocks written before versioning",
+		Apply: func(block map[string]json.RawMessage) error {
+			return nil
+		},
+	},
+}
+
+// Migrate upgrades every block stored under the specified path to the current
+// format version. A backup copy of the path is made before any block is
+// changed and the location of that backup is returned.
+func Migrate(dbPath string, evHandler func(v string, args ...any)) (string, error) {
+	d := Disk{dbPath: dbPath}
+
+	// Identify the blocks that need to be upgraded before touching anything.
+	var pending []uint64
+	for num := uint64(1); ; num++ {
+		version, err := d.readVersion(num)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				break
+			}
+			return "", err
+		}
+
+		if version > database.BlockDataVersion {
+			return "", fmt.Errorf("block %d has format version %d, this node only supports up to version %d", num, version, database.BlockDataVersion)
+		}
+
+		if version < database.BlockDataVersion {
+			pending = append(pending, num)
+		}
+	}
+
+	if len(pending) == 0 {
+		evHandler("disk: Migrate: blocks are at version %d, nothing to do", database.BlockDataVersion)
+		return "", nil
+	}
+
+	// Make a copy of the database before any changes take place.
+	backup := fmt.Sprintf("%s.backup-%s", filepath.Clean(dbPath), time.Now().UTC().Format("20060102T150405"))
+	if err := copyDir(dbPath, backup); err != nil {
+		return "", fmt.Errorf("backing up %s: %w", dbPath, err)
+	}
+
+	evHandler("disk: Migrate: backup created: %s", backup)
+
+	for _, num := range pending {
+		if err := d.migrateBlock(num, evHandler); err != nil {
+			return backup, fmt.Errorf("migrating block %d: %w", num, err)
+		}
+	}
+
+	evHandler("disk: Migrate: migrated blocks[%d] to version %d", len(pending), database.BlockDataVersion)
+
+	return backup, nil
+}
+
+// =============================================================================
+
+// readVersion returns the format version of the specified block.
+func (d *Disk) readVersion(num uint64) (uint16, error) {
+	content, err := os.ReadFile(d.findPath(num))
+	if err != nil {
+		return 0, err
+	}
+
+	var block struct {
+		Version uint16 `json:"version"`
+	}
+	if err := json.Unmarshal(content, &block); err != nil {
+		return 0, fmt.Errorf("decoding block %d: %w", num, err)
+	}
+
+	return block.Version, nil
+}
+
+// migrateBlock applies every migration required to bring the specified block
+// to the current version and writes the result back to disk.
+func (d *Disk) migrateBlock(num uint64, evHandler func(v string, args ...any)) error {
+	path := d.findPath(num)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var block map[string]json.RawMessage
+	if err := json.Unmarshal(content, &block); err != nil {
+		return err
+	}
+
+	var version uint16
+	if raw, exists := block["version"]; exists {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return err
+		}
+	}
+
+	for _, migration := range migrations {
+		if migration.Version != version {
+			continue
+		}
+
+		evHandler("disk: Migrate: blk[%d]: version %d: %s", num, version, migration.Description)
+
+		if err := migration.Apply(block); err != nil {
+			return err
+		}
+
+		version++
+		block["version"] = json.RawMessage(fmt.Sprint(version))
+	}
+
+	if version != database.BlockDataVersion {
+		return fmt.Errorf("no migration available from version %d", version)
+	}
+
+	// Decode the result into the current types to make sure the migrated
+	// block can be read by the node before it's written.
+	data, err := json.Marshal(block)
+	if err != nil {
+		return err
+	}
+
+	var blockData database.BlockData
+	if err := json.Unmarshal(data, &blockData); err != nil {
+		return err
+	}
+
+	data, err = json.MarshalIndent(blockData, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
+
+// copyDir copies the files in the source directory into a new destination
+// directory.
+func copyDir(src string, dst string) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		if err := copyFile(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyFile copies the contents of the source file to the destination file.
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
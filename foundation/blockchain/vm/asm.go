@@ -0,0 +1,126 @@
+package vm
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// labelSize is the number of bytes used to push the position of a label,
+// which covers the largest code that can be deployed.
+const labelSize = 2
+
+// Assemble converts the text form of a contract into code. Each line holds
+// one instruction, text after a ';' is a comment and a line like "loop:"
+// defines a label which becomes a JUMPDEST. PUSH picks the smallest PUSH
+// instruction that fits the decimal or 0x prefixed hex value, and
+// "PUSH @loop" pushes the position of a label.
+//
+//	CALLER
+//	PUSH 0
+//	SSTORE      ; storage[0] = caller
+func Assemble(src string) ([]byte, error) {
+	type labelRef struct {
+		label string
+		at    int
+		line  int
+	}
+
+	var code []byte
+	var refs []labelRef
+	labels := make(map[string]int)
+
+	for i, line := range strings.Split(src, "\n") {
+		lineNum := i + 1
+
+		if idx := strings.IndexByte(line, ';'); idx >= 0 {
+			line = line[:idx]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if label := strings.TrimSuffix(fields[0], ":"); len(fields) == 1 && label != fields[0] {
+			if _, exists := labels[label]; exists {
+				return nil, fmt.Errorf("line %d: label %q is defined more than once", lineNum, label)
+			}
+			labels[label] = len(code)
+			code = append(code, byte(JUMPDEST))
+			continue
+		}
+
+		name := strings.ToUpper(fields[0])
+		op, exists := opcodeByName[name]
+
+		switch {
+		case name == "PUSH" || (exists && op.pushSize() > 0):
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("line %d: %s needs one value", lineNum, name)
+			}
+
+			if strings.HasPrefix(fields[1], "@") {
+				if name != "PUSH" && op.pushSize() != labelSize {
+					return nil, fmt.Errorf("line %d: labels need PUSH or PUSH%d", lineNum, labelSize)
+				}
+				code = append(code, byte(PUSH1)+labelSize-1)
+				refs = append(refs, labelRef{label: fields[1][1:], at: len(code), line: lineNum})
+				code = append(code, make([]byte, labelSize)...)
+				continue
+			}
+
+			value, ok := new(big.Int).SetString(fields[1], 0)
+			if !ok || value.Sign() < 0 || value.BitLen() > 256 {
+				return nil, fmt.Errorf("line %d: invalid value %q", lineNum, fields[1])
+			}
+
+			size := (value.BitLen() + 7) / 8
+			if size == 0 {
+				size = 1
+			}
+			if name != "PUSH" {
+				if size > op.pushSize() {
+					return nil, fmt.Errorf("line %d: value %q doesn't fit %s", lineNum, fields[1], name)
+				}
+				size = op.pushSize()
+			}
+
+			code = append(code, byte(PUSH1)+byte(size-1))
+			code = append(code, value.FillBytes(make([]byte, size))...)
+
+		case exists:
+			if len(fields) != 1 {
+				return nil, fmt.Errorf("line %d: %s doesn't take a value", lineNum, name)
+			}
+			code = append(code, byte(op))
+
+		default:
+			return nil, fmt.Errorf("line %d: unknown instruction %q", lineNum, fields[0])
+		}
+	}
+
+	for _, ref := range refs {
+		pos, exists := labels[ref.label]
+		if !exists {
+			return nil, fmt.Errorf("line %d: unknown label %q", ref.line, ref.label)
+		}
+		code[ref.at] = byte(pos >> 8)
+		code[ref.at+1] = byte(pos)
+	}
+
+	if len(code) > MaxCodeSize {
+		return nil, fmt.Errorf("code is %d bytes, the limit is %d", len(code), MaxCodeSize)
+	}
+
+	return code, nil
+}
+
+// opcodeByName maps the name of each instruction to its opcode.
+var opcodeByName = func() map[string]Opcode {
+	names := make(map[string]Opcode, len(opcodes))
+	for op, info := range opcodes {
+		names[info.name] = op
+	}
+	return names
+}()
@@ -0,0 +1,158 @@
+package vm
+
+import "fmt"
+
+// Opcode represents an instruction of the machine.
+type Opcode byte
+
+// Set of instructions the machine knows how to run. The arguments of an
+// instruction are popped from the stack in the order listed.
+const (
+	STOP   Opcode = 0x00 // Stops the code successfully.
+	ADD    Opcode = 0x01 // a, b: pushes a + b.
+	SUB    Opcode = 0x02 // a, b: pushes a - b.
+	MUL    Opcode = 0x03 // a, b: pushes a * b.
+	DIV    Opcode = 0x04 // a, b: pushes a / b, or 0 when b is 0.
+	MOD    Opcode = 0x05 // a, b: pushes a % b, or 0 when b is 0.
+	LT     Opcode = 0x10 // a, b: pushes 1 if a < b.
+	GT     Opcode = 0x11 // a, b: pushes 1 if a > b.
+	EQ     Opcode = 0x12 // a, b: pushes 1 if a == b.
+	ISZERO Opcode = 0x13 // a: pushes 1 if a == 0.
+	AND    Opcode = 0x14 // a, b: pushes a & b.
+	OR     Opcode = 0x15 // a, b: pushes a | b.
+	NOT    Opcode = 0x16 // a: pushes ^a.
+
+	ADDRESS      Opcode = 0x30 // Pushes the account of the contract.
+	CALLER       Opcode = 0x31 // Pushes the account that sent the transaction.
+	CALLVALUE    Opcode = 0x32 // Pushes the value sent to the contract.
+	CALLDATALOAD Opcode = 0x33 // offset: pushes 32 bytes of call data.
+	CALLDATASIZE Opcode = 0x34 // Pushes the number of bytes of call data.
+	BALANCE      Opcode = 0x35 // Pushes the balance of the contract.
+	NUMBER       Opcode = 0x36 // Pushes the block number.
+	TIMESTAMP    Opcode = 0x37 // Pushes the block time.
+
+	POP      Opcode = 0x50 // a: drops a.
+	SLOAD    Opcode = 0x54 // key: pushes the value stored under key.
+	SSTORE   Opcode = 0x55 // key, value: stores value under key.
+	JUMP     Opcode = 0x56 // dest: continues at dest.
+	JUMPI    Opcode = 0x57 // dest, cond: continues at dest if cond != 0.
+	JUMPDEST Opcode = 0x5b // Marks a valid jump destination.
+
+	PUSH1  Opcode = 0x60 // Pushes the next byte of code.
+	PUSH32 Opcode = 0x7f // Pushes the next 32 bytes of code.
+	DUP1   Opcode = 0x80 // Pushes a copy of the top of the stack.
+	DUP16  Opcode = 0x8f // Pushes a copy of the 16th word on the stack.
+	SWAP1  Opcode = 0x90 // Swaps the top two words of the stack.
+	SWAP16 Opcode = 0x9f // Swaps the top word with the 17th word on the stack.
+
+	TRANSFER Opcode = 0xf0 // to, amount: moves amount from the contract to the account.
+	REVERT   Opcode = 0xfd // Stops the code and throws away its changes.
+)
+
+// Set of gas costs for the instructions.
+const (
+	gasZero     = 0
+	gasJumpDest = 1
+	gasVeryLow  = 3
+	gasLow      = 5
+	gasMid      = 8
+	gasSLoad    = 50
+	gasSStore   = 200
+	gasTransfer = 100
+)
+
+// opInfo describes how an instruction uses the stack and what it costs.
+type opInfo struct {
+	name   string
+	gas    uint64
+	pops   int
+	pushes int
+}
+
+// opcodes is the table of instructions the machine knows how to run.
+var opcodes = func() map[Opcode]opInfo {
+	ops := map[Opcode]opInfo{
+		STOP:   {"STOP", gasZero, 0, 0},
+		ADD:    {"ADD", gasVeryLow, 2, 1},
+		SUB:    {"SUB", gasVeryLow, 2, 1},
+		MUL:    {"MUL", gasLow, 2, 1},
+		DIV:    {"DIV", gasLow, 2, 1},
+		MOD:    {"MOD", gasLow, 2, 1},
+		LT:     {"LT", gasVeryLow, 2, 1},
+		GT:     {"GT", gasVeryLow, 2, 1},
+		EQ:     {"EQ", gasVeryLow, 2, 1},
+		ISZERO: {"ISZERO", gasVeryLow, 1, 1},
+		AND:    {"AND", gasVeryLow, 2, 1},
+		OR:     {"OR", gasVeryLow, 2, 1},
+		NOT:    {"NOT", gasVeryLow, 1, 1},
+
+		ADDRESS:      {"ADDRESS", gasVeryLow, 0, 1},
+		CALLER:       {"CALLER", gasVeryLow, 0, 1},
+		CALLVALUE:    {"CALLVALUE", gasVeryLow, 0, 1},
+		CALLDATALOAD: {"CALLDATALOAD", gasVeryLow, 1, 1},
+		CALLDATASIZE: {"CALLDATASIZE", gasVeryLow, 0, 1},
+		BALANCE:      {"BALANCE", gasLow, 0, 1},
+		NUMBER:       {"NUMBER", gasVeryLow, 0, 1},
+		TIMESTAMP:    {"TIMESTAMP", gasVeryLow, 0, 1},
+
+		POP:      {"POP", gasVeryLow, 1, 0},
+		SLOAD:    {"SLOAD", gasSLoad, 1, 1},
+		SSTORE:   {"SSTORE", gasSStore, 2, 0},
+		JUMP:     {"JUMP", gasMid, 1, 0},
+		JUMPI:    {"JUMPI", gasMid, 2, 0},
+		JUMPDEST: {"JUMPDEST", gasJumpDest, 0, 0},
+
+		TRANSFER: {"TRANSFER", gasTransfer, 2, 0},
+		REVERT:   {"REVERT", gasZero, 0, 0},
+	}
+
+	for i := 0; i < 32; i++ {
+		ops[PUSH1+Opcode(i)] = opInfo{fmt.Sprintf("PUSH%d", i+1), gasVeryLow, 0, 1}
+	}
+
+	for i := 0; i < 16; i++ {
+		ops[DUP1+Opcode(i)] = opInfo{fmt.Sprintf("DUP%d", i+1), gasVeryLow, i + 1, i + 2}
+		ops[SWAP1+Opcode(i)] = opInfo{fmt.Sprintf("SWAP%d", i+1), gasVeryLow, i + 2, i + 2}
+	}
+
+	return ops
+}()
+
+// String implements the Stringer interface for logging.
+func (op Opcode) String() string {
+	if info, exists := opcodes[op]; exists {
+		return info.name
+	}
+
+	return fmt.Sprintf("0x%02x", byte(op))
+}
+
+// pushSize returns the number of bytes a PUSH instruction pushes, 0 for
+// other instructions.
+func (op Opcode) pushSize() int {
+	if op < PUSH1 || op > PUSH32 {
+		return 0
+	}
+
+	return int(op-PUSH1) + 1
+}
+
+// dupSize returns the position on the stack a DUP instruction copies, 0 for
+// other instructions.
+func (op Opcode) dupSize() int {
+	if op < DUP1 || op > DUP16 {
+		return 0
+	}
+
+	return int(op-DUP1) + 1
+}
+
+// swapSize returns the position on the stack a SWAP instruction swaps the
+// top with, 0 for other instructions.
+func (op Opcode) swapSize() int {
+	if op < SWAP1 || op > SWAP16 {
+		return 0
+	}
+
+	return int(op-SWAP1) + 1
+}
@@ -0,0 +1,441 @@
+// Package vm provides a small deterministic stack machine that runs the code
+// of contract accounts.
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// CORE NOTE: Every node runs the code of a contract and must end up with the
+// exact same result, so the machine only has access to the values it's given.
+// There is no clock, randomness, floating point or map iteration to leak
+// differences between nodes. The machine works on a stack of 256 bit words,
+// which is large enough to hold an account address or a hash. Every
+// instruction costs gas and the machine stops when the gas runs out, so a
+// contract can't run forever. The machine doesn't change anything itself,
+// every read and write of storage and balances goes through the Host. The
+// caller decides if the changes are kept when the code finishes or thrown
+// away when it fails.
+
+// Set of limits the machine enforces.
+const (
+	MaxCodeSize  = 24 * 1024 // Largest contract code that can be deployed.
+	MaxStackSize = 1024      // Largest number of words on the stack.
+)
+
+// Set of errors the machine can stop with. The code of a contract failing
+// for any of these reasons causes its changes to be thrown away.
+var (
+	ErrOutOfGas       = errors.New("out of gas")
+	ErrStackUnderflow = errors.New("stack underflow")
+	ErrStackOverflow  = errors.New("stack overflow")
+	ErrInvalidJump    = errors.New("invalid jump destination")
+	ErrInvalidOpcode  = errors.New("invalid opcode")
+	ErrRevert         = errors.New("execution reverted")
+)
+
+// =============================================================================
+
+// Word represents a 256 bit value on the stack and in contract storage. The
+// bytes are big endian.
+type Word [32]byte
+
+// WordFromUint64 constructs a word from the value.
+func WordFromUint64(v uint64) Word {
+	var w Word
+	new(big.Int).SetUint64(v).FillBytes(w[:])
+	return w
+}
+
+// WordFromBytes constructs a word from up to 32 bytes, right aligned like a
+// number. An account address becomes a word this way.
+func WordFromBytes(b []byte) Word {
+	var w Word
+	if len(b) > len(w) {
+		b = b[len(b)-len(w):]
+	}
+	copy(w[len(w)-len(b):], b)
+	return w
+}
+
+// IsZero reports whether the word is zero.
+func (w Word) IsZero() bool {
+	return w == Word{}
+}
+
+// Uint64 returns the word as a uint64 and false if it doesn't fit.
+func (w Word) Uint64() (uint64, bool) {
+	v := w.big()
+	return v.Uint64(), v.IsUint64()
+}
+
+// Address returns the lowest 20 bytes of the word as an account address.
+func (w Word) Address() []byte {
+	return w[12:]
+}
+
+// String returns the word as a hex string.
+func (w Word) String() string {
+	return hexutil.Encode(w[:])
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (w Word) MarshalText() ([]byte, error) {
+	return []byte(w.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (w *Word) UnmarshalText(input []byte) error {
+	b, err := hexutil.Decode(string(input))
+	if err != nil {
+		return err
+	}
+
+	if len(b) != len(w) {
+		return fmt.Errorf("word must be %d bytes, got %d", len(w), len(b))
+	}
+
+	copy(w[:], b)
+	return nil
+}
+
+// big returns the word as a big integer.
+func (w Word) big() *big.Int {
+	return new(big.Int).SetBytes(w[:])
+}
+
+// wordFromBig constructs a word from the lowest 256 bits of the big integer,
+// so arithmetic wraps around the same way on every node.
+func wordFromBig(v *big.Int) Word {
+	var w Word
+	if v.Sign() < 0 {
+		v = new(big.Int).Add(v, modulus)
+	}
+	new(big.Int).Mod(v, modulus).FillBytes(w[:])
+	return w
+}
+
+// modulus is 2^256, the number of values a word can hold.
+var modulus = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// =============================================================================
+
+// Host represents the state the code of a contract can read and change.
+type Host interface {
+
+	// Load returns the value stored under the key in the contract's storage.
+	Load(key Word) Word
+
+	// Store sets the value under the key in the contract's storage. Storing
+	// zero removes the key.
+	Store(key Word, value Word)
+
+	// Balance returns the balance of the contract.
+	Balance() uint64
+
+	// Transfer moves the amount from the contract to the account.
+	Transfer(to []byte, amount uint64) error
+}
+
+// Context represents the values the code can read about the transaction
+// running it.
+type Context struct {
+	Address   Word   // Account of the contract.
+	Caller    Word   // Account that sent the transaction.
+	Value     uint64 // Value sent to the contract with the transaction.
+	Data      []byte // Call data of the transaction.
+	Number    uint64 // Number of the block the transaction is in.
+	TimeStamp uint64 // Time of the block the transaction is in.
+	Gas       uint64 // Gas available to run the code.
+}
+
+// Run executes the code and returns the gas it used. The code stops when it
+// runs past its end or reaches STOP. Any error means the changes made
+// through the host must be thrown away, the gas used is still returned.
+func Run(ctx Context, code []byte, host Host) (uint64, error) {
+	m := machine{
+		ctx:   ctx,
+		code:  code,
+		host:  host,
+		dests: jumpDests(code),
+		gas:   ctx.Gas,
+	}
+
+	err := m.run()
+	return ctx.Gas - m.gas, err
+}
+
+// =============================================================================
+
+// machine holds the state of a running contract.
+type machine struct {
+	ctx   Context
+	code  []byte
+	host  Host
+	dests map[uint64]bool
+	stack []Word
+	pc    uint64
+	gas   uint64
+}
+
+// run executes instructions until the code stops or fails.
+func (m *machine) run() error {
+	for m.pc < uint64(len(m.code)) {
+		op := Opcode(m.code[m.pc])
+
+		info, exists := opcodes[op]
+		if !exists {
+			return fmt.Errorf("%w 0x%02x at %d", ErrInvalidOpcode, byte(op), m.pc)
+		}
+
+		if m.gas < info.gas {
+			m.gas = 0
+			return ErrOutOfGas
+		}
+		m.gas -= info.gas
+
+		if len(m.stack) < info.pops {
+			return fmt.Errorf("%w: %s at %d", ErrStackUnderflow, info.name, m.pc)
+		}
+
+		if len(m.stack)-info.pops+info.pushes > MaxStackSize {
+			return fmt.Errorf("%w: %s at %d", ErrStackOverflow, info.name, m.pc)
+		}
+
+		next := m.pc + 1
+		switch {
+		case op.pushSize() > 0:
+			size := op.pushSize()
+			m.push(WordFromBytes(m.immediate(size)))
+			m.pc = next + uint64(size)
+			continue
+
+		case op.dupSize() > 0:
+			m.push(m.stack[len(m.stack)-op.dupSize()])
+			m.pc = next
+			continue
+
+		case op.swapSize() > 0:
+			top, other := len(m.stack)-1, len(m.stack)-1-op.swapSize()
+			m.stack[top], m.stack[other] = m.stack[other], m.stack[top]
+			m.pc = next
+			continue
+		}
+
+		switch op {
+		case STOP:
+			return nil
+
+		case REVERT:
+			return ErrRevert
+
+		case JUMP:
+			dest, err := m.jump(m.pop())
+			if err != nil {
+				return err
+			}
+			next = dest
+
+		case JUMPI:
+			dest, cond := m.pop(), m.pop()
+			if !cond.IsZero() {
+				pc, err := m.jump(dest)
+				if err != nil {
+					return err
+				}
+				next = pc
+			}
+
+		case TRANSFER:
+			to, amount := m.pop(), m.pop()
+			value, ok := amount.Uint64()
+			if !ok {
+				return fmt.Errorf("transfer amount %s is too large", amount)
+			}
+			if err := m.host.Transfer(to.Address(), value); err != nil {
+				return err
+			}
+
+		default:
+			m.exec(op)
+		}
+
+		m.pc = next
+	}
+
+	return nil
+}
+
+// exec runs the instructions that only work on the stack and the values
+// of the context.
+func (m *machine) exec(op Opcode) {
+	switch op {
+	case ADD:
+		a, b := m.pop(), m.pop()
+		m.push(wordFromBig(new(big.Int).Add(a.big(), b.big())))
+
+	case SUB:
+		a, b := m.pop(), m.pop()
+		m.push(wordFromBig(new(big.Int).Sub(a.big(), b.big())))
+
+	case MUL:
+		a, b := m.pop(), m.pop()
+		m.push(wordFromBig(new(big.Int).Mul(a.big(), b.big())))
+
+	case DIV:
+		a, b := m.pop(), m.pop()
+		if b.IsZero() {
+			m.push(Word{})
+			return
+		}
+		m.push(wordFromBig(new(big.Int).Div(a.big(), b.big())))
+
+	case MOD:
+		a, b := m.pop(), m.pop()
+		if b.IsZero() {
+			m.push(Word{})
+			return
+		}
+		m.push(wordFromBig(new(big.Int).Mod(a.big(), b.big())))
+
+	case LT:
+		a, b := m.pop(), m.pop()
+		m.push(boolWord(a.big().Cmp(b.big()) < 0))
+
+	case GT:
+		a, b := m.pop(), m.pop()
+		m.push(boolWord(a.big().Cmp(b.big()) > 0))
+
+	case EQ:
+		a, b := m.pop(), m.pop()
+		m.push(boolWord(a == b))
+
+	case ISZERO:
+		m.push(boolWord(m.pop().IsZero()))
+
+	case AND:
+		a, b := m.pop(), m.pop()
+		m.push(wordFromBig(new(big.Int).And(a.big(), b.big())))
+
+	case OR:
+		a, b := m.pop(), m.pop()
+		m.push(wordFromBig(new(big.Int).Or(a.big(), b.big())))
+
+	case NOT:
+		a := m.pop()
+		for i := range a {
+			a[i] = ^a[i]
+		}
+		m.push(a)
+
+	case ADDRESS:
+		m.push(m.ctx.Address)
+
+	case CALLER:
+		m.push(m.ctx.Caller)
+
+	case CALLVALUE:
+		m.push(WordFromUint64(m.ctx.Value))
+
+	case CALLDATALOAD:
+		m.push(m.callData(m.pop()))
+
+	case CALLDATASIZE:
+		m.push(WordFromUint64(uint64(len(m.ctx.Data))))
+
+	case BALANCE:
+		m.push(WordFromUint64(m.host.Balance()))
+
+	case NUMBER:
+		m.push(WordFromUint64(m.ctx.Number))
+
+	case TIMESTAMP:
+		m.push(WordFromUint64(m.ctx.TimeStamp))
+
+	case POP:
+		m.pop()
+
+	case SLOAD:
+		m.push(m.host.Load(m.pop()))
+
+	case SSTORE:
+		key, value := m.pop(), m.pop()
+		m.host.Store(key, value)
+
+	case JUMPDEST:
+	}
+}
+
+// jump returns the destination if it marks a JUMPDEST instruction.
+func (m *machine) jump(dest Word) (uint64, error) {
+	pc, ok := dest.Uint64()
+	if !ok || !m.dests[pc] {
+		return 0, fmt.Errorf("%w %s at %d", ErrInvalidJump, dest, m.pc)
+	}
+
+	return pc, nil
+}
+
+// immediate returns the bytes following the current instruction, padded
+// with zeros when the code ends early.
+func (m *machine) immediate(size int) []byte {
+	b := make([]byte, size)
+	start := m.pc + 1
+	if start < uint64(len(m.code)) {
+		copy(b, m.code[start:])
+	}
+	return b
+}
+
+// callData returns the 32 bytes of call data at the offset, padded with
+// zeros past the end of the data.
+func (m *machine) callData(offset Word) Word {
+	var w Word
+	off, ok := offset.Uint64()
+	if ok && off < uint64(len(m.ctx.Data)) {
+		copy(w[:], m.ctx.Data[off:])
+	}
+	return w
+}
+
+// push adds the word to the top of the stack.
+func (m *machine) push(w Word) {
+	m.stack = append(m.stack, w)
+}
+
+// pop removes the word from the top of the stack.
+func (m *machine) pop() Word {
+	w := m.stack[len(m.stack)-1]
+	m.stack = m.stack[:len(m.stack)-1]
+	return w
+}
+
+// =============================================================================
+
+// boolWord returns 1 for true and 0 for false.
+func boolWord(b bool) Word {
+	if b {
+		return WordFromUint64(1)
+	}
+	return Word{}
+}
+
+// jumpDests returns the positions of the JUMPDEST instructions, skipping
+// over the bytes pushed by PUSH instructions so data can't be jumped into.
+func jumpDests(code []byte) map[uint64]bool {
+	dests := make(map[uint64]bool)
+	for pc := uint64(0); pc < uint64(len(code)); pc++ {
+		op := Opcode(code[pc])
+		switch {
+		case op == JUMPDEST:
+			dests[pc] = true
+		case op.pushSize() > 0:
+			pc += uint64(op.pushSize())
+		}
+	}
+	return dests
+}
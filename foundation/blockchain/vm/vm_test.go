@@ -0,0 +1,166 @@
+package vm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/PhyoYazar/blockchain/foundation/blockchain/vm"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// host is a vm.Host that keeps the storage in memory.
+type host struct {
+	storage map[vm.Word]vm.Word
+	balance uint64
+}
+
+func (h *host) Load(key vm.Word) vm.Word {
+	return h.storage[key]
+}
+
+func (h *host) Store(key vm.Word, value vm.Word) {
+	if value.IsZero() {
+		delete(h.storage, key)
+		return
+	}
+	h.storage[key] = value
+}
+
+func (h *host) Balance() uint64 {
+	return h.balance
+}
+
+func (h *host) Transfer(to []byte, amount uint64) error {
+	if amount > h.balance {
+		return errors.New("insufficient balance")
+	}
+	h.balance -= amount
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	caller := vm.WordFromUint64(0xcafe)
+
+	tt := []struct {
+		name    string
+		src     string
+		code    []byte
+		gas     uint64
+		used    uint64
+		err     error
+		storage map[vm.Word]vm.Word
+	}{
+		{
+			name:    "adding two numbers into storage",
+			src:     "PUSH 2\nPUSH 3\nADD\nPUSH 0\nSSTORE",
+			gas:     1000,
+			used:    3 + 3 + 3 + 3 + 200,
+			storage: map[vm.Word]vm.Word{vm.WordFromUint64(0): vm.WordFromUint64(5)},
+		},
+		{
+			name:    "storing the value sent by the caller",
+			src:     "CALLVALUE\nCALLER\nSSTORE\nSTOP\nPUSH 1",
+			gas:     1000,
+			used:    3 + 3 + 200,
+			storage: map[vm.Word]vm.Word{caller: vm.WordFromUint64(40)},
+		},
+		{
+			name: "a loop that never stops",
+			src:  "loop:\nPUSH @loop\nJUMP",
+			gas:  100,
+			used: 100,
+			err:  vm.ErrOutOfGas,
+		},
+		{
+			name: "a store the gas doesn't cover",
+			src:  "PUSH 1\nPUSH 0\nSSTORE",
+			gas:  205,
+			used: 205,
+			err:  vm.ErrOutOfGas,
+		},
+		{
+			name: "an instruction without enough words on the stack",
+			src:  "PUSH 1\nADD",
+			gas:  100,
+			used: 3 + 3,
+			err:  vm.ErrStackUnderflow,
+		},
+		{
+			name: "a loop that keeps pushing words",
+			src:  "loop:\nPUSH 1\nPUSH @loop\nJUMP",
+			gas:  100_000,
+			used: (vm.MaxStackSize-1)*(1+3+3+8) + 1 + 3 + 3,
+			err:  vm.ErrStackOverflow,
+		},
+		{
+			name: "a jump to a position that isn't a JUMPDEST",
+			src:  "PUSH 1\nJUMP",
+			gas:  100,
+			used: 3 + 8,
+			err:  vm.ErrInvalidJump,
+		},
+		{
+			name: "an unknown instruction",
+			code: []byte{0xee},
+			gas:  100,
+			used: 0,
+			err:  vm.ErrInvalidOpcode,
+		},
+		{
+			name: "a revert after a store",
+			src:  "PUSH 1\nPUSH 0\nSSTORE\nREVERT",
+			gas:  1000,
+			used: 3 + 3 + 200,
+			err:  vm.ErrRevert,
+		},
+	}
+
+	t.Log("Given the need to run contract code and charge gas for every instruction.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen running %s.", testID, test.name)
+			{
+				code := test.code
+				if code == nil {
+					var err error
+					if code, err = vm.Assemble(test.src); err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to assemble the code : %s", failed, testID, err)
+					}
+				}
+
+				h := host{storage: make(map[vm.Word]vm.Word)}
+				ctx := vm.Context{Caller: caller, Value: 40, Gas: test.gas}
+
+				used, err := vm.Run(ctx, code, &h)
+				if !errors.Is(err, test.err) {
+					t.Fatalf("\t%s\tTest %d:\tShould stop with the expected error : got %v, exp %v", failed, testID, err, test.err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould stop with the expected error.", success, testID)
+
+				if used != test.used {
+					t.Fatalf("\t%s\tTest %d:\tShould use the expected gas : got %d, exp %d", failed, testID, used, test.used)
+				}
+				t.Logf("\t%s\tTest %d:\tShould use the expected gas.", success, testID)
+
+				if test.err != nil {
+					continue
+				}
+
+				if len(h.storage) != len(test.storage) {
+					t.Fatalf("\t%s\tTest %d:\tShould store the expected slots : got %v, exp %v", failed, testID, h.storage, test.storage)
+				}
+				for key, value := range test.storage {
+					if h.storage[key] != value {
+						t.Fatalf("\t%s\tTest %d:\tShould store the expected slots : got %v, exp %v", failed, testID, h.storage, test.storage)
+					}
+				}
+				t.Logf("\t%s\tTest %d:\tShould store the expected slots.", success, testID)
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package worker
+
+import "time"
+
+// headerSyncInterval represents the interval a node running in light mode
+// asks its peers for new block headers.
+const headerSyncInterval = 15 * time.Second
+
+// CORE NOTE: A node running in light mode doesn't mine and never receives
+// full blocks, so this goroutine replaces the mining operation. On an interval
+// the known peers are asked for any block headers this node is missing.
+
+// headerOperations handles syncing block headers for light mode.
+func (w *Worker) headerOperations() {
+	w.evHandler("worker: headerOperations: G started")
+	defer w.evHandler("worker: headerOperations: G completed")
+
+	ticker := time.NewTicker(headerSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !w.isShutdown() {
+				w.runHeadersOperation()
+			}
+		case <-w.shut:
+			w.evHandler("worker: headerOperations: received shut signal")
+			return
+		}
+	}
+}
+
+// runHeadersOperation syncs the block headers from the known peers.
+func (w *Worker) runHeadersOperation() {
+	w.evHandler("worker: runHeadersOperation: started")
+	defer w.evHandler("worker: runHeadersOperation: completed")
+
+	for _, peer := range w.state.RetrieveKnownPeers() {
+		if err := w.state.NetRequestPeerHeaders(peer); err != nil {
+			w.evHandler("worker: runHeadersOperation: requestPeerHeaders: %s: ERROR: %s", peer.Host, err)
+		}
+	}
+}
@@ -46,10 +46,13 @@ func (w *Worker) runMiningOperation() {
 	}
 
 	// After running a mining operation, check if a new operation should
-	// be signaled again.
+	// be signaled again. When none of the transactions could be picked the
+	// ones left in the mempool can't be mined yet, so signaling again would
+	// only spin until a new transaction arrives.
+	var noTransactions bool
 	defer func() {
 		length := w.state.QueryMempoolLength()
-		if length > 0 {
+		if !noTransactions && length > 0 {
 			w.evHandler("worker: runMiningOperation: MINING: signal new mining operation: Txs[%d]", length)
 			w.SignalStartMining()
 		}
@@ -101,6 +104,7 @@ func (w *Worker) runMiningOperation() {
 			switch {
 			case errors.Is(err, state.ErrNoTransactions):
 				w.evHandler("worker: runMiningOperation: MINING: WARNING: no transactions in mempool")
+				noTransactions = true
 			case ctx.Err() != nil:
 				w.evHandler("worker: runMiningOperation: MINING: CANCEL: complete")
 			default:
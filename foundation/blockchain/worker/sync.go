@@ -10,6 +10,19 @@ func (w *Worker) Sync() {
 	w.evHandler("worker: sync: started")
 	defer w.evHandler("worker: sync: completed")
 
+	// A node in light mode only needs the block headers.
+	if w.state.IsLight() {
+		w.runHeadersOperation()
+		return
+	}
+
+	// A node in snap sync mode starts from a peer's state snapshot when it
+	// has no blocks, then downloads the blocks from the snapshot forward.
+	if w.state.IsSnapSync() {
+		w.runSnapSyncOperation()
+		return
+	}
+
 	// for _, peer := range w.state.RetrieveKnownPeers() {
 
 	// 	// Retrieve the status of this peer.
@@ -44,3 +57,26 @@ func (w *Worker) Sync() {
 	// // Share with peers this node is available to participate in the network.
 	// w.state.NetSendNodeAvailableToPeers()
 }
+
+// runSnapSyncOperation installs a peer's state snapshot when this node has no
+// blocks and then syncs the remaining blocks from the same peer.
+func (w *Worker) runSnapSyncOperation() {
+	w.evHandler("worker: runSnapSyncOperation: started")
+	defer w.evHandler("worker: runSnapSyncOperation: completed")
+
+	for _, peer := range w.state.RetrieveKnownPeers() {
+		if w.state.RetrieveLatestBlock().Header.Number == 0 {
+			if err := w.state.NetSyncPeerSnapshot(peer); err != nil {
+				w.evHandler("worker: runSnapSyncOperation: syncPeerSnapshot: %s: ERROR: %s", peer.Host, err)
+				continue
+			}
+		}
+
+		if err := w.state.NetRequestPeerBlocks(peer); err != nil {
+			w.evHandler("worker: runSnapSyncOperation: requestPeerBlocks: %s: ERROR: %s", peer.Host, err)
+			continue
+		}
+
+		return
+	}
+}
@@ -44,11 +44,18 @@ func Run(st *state.State, evHandler state.EventHandler) {
 	// Update this node before starting any support G's.
 	w.Sync()
 
-	// Load the set of operations we need to run.
+	// Load the set of operations we need to run. A node in light mode
+	// syncs block headers instead of mining.
 	operations := []func(){
 		w.peerOperations,
 		w.miningOperations,
 	}
+	if st.IsLight() {
+		operations = []func(){
+			w.peerOperations,
+			w.headerOperations,
+		}
+	}
 
 	// Set waitgroup to match the number of G's we need for the set
 	// of operations we have.
@@ -0,0 +1,32 @@
+; Escrow: the account deploying the contract is the buyer and the value sent
+; with the deployment is held by the contract. The buyer releases the funds by
+; calling the contract with the seller's account in the first 32 bytes of
+; call data. Anyone else calling the contract is rejected.
+
+    CALLDATASIZE
+    ISZERO
+    PUSH @deploy
+    JUMPI
+
+    PUSH 0
+    SLOAD
+    CALLER
+    EQ
+    ISZERO
+    PUSH @reject
+    JUMPI           ; only the buyer can release the funds
+
+    BALANCE
+    PUSH 0
+    CALLDATALOAD
+    TRANSFER        ; send the balance to the seller
+    STOP
+
+reject:
+    REVERT
+
+deploy:
+    CALLER
+    PUSH 0
+    SSTORE          ; storage[0] = buyer
+    STOP
@@ -0,0 +1,33 @@
+; Voting: each account can vote once by calling the contract with the number
+; of the candidate in the first 32 bytes of call data. storage[candidate]
+; counts the votes and storage[caller] records the account voted.
+
+    CALLDATASIZE
+    ISZERO
+    PUSH @deploy
+    JUMPI           ; nothing to set up when deployed
+
+    CALLER
+    SLOAD
+    PUSH @voted
+    JUMPI           ; the caller already voted
+
+    PUSH 1
+    CALLER
+    SSTORE          ; storage[caller] = 1
+
+    PUSH 0
+    CALLDATALOAD    ; candidate
+    DUP1
+    SLOAD
+    PUSH 1
+    ADD
+    SWAP1
+    SSTORE          ; storage[candidate] += 1
+    STOP
+
+voted:
+    REVERT
+
+deploy:
+    STOP